@@ -3,34 +3,165 @@ package appconf
 // Version 程序版本号
 const Version string = "V1.3.1"
 
+// BuildCommit 构建时的代码提交ID
+// 构建时通过 -ldflags "-X note/appconf.BuildCommit=$(git rev-parse --short HEAD)" 设置
+var BuildCommit = "unknown"
+
 // Application 应用程序配置对象
 // 该对象用于持有配置文件出现的所有配置参数
+// 所有配置项均可通过环境变量（NOTE_ + 大写下划线路径，如 NOTE_DATABASE_DSN）及命令行参数（如 -database.dsn）覆盖，
+// 字符串配置项以"file:"开头时从该文件读取内容，用于密钥等敏感信息。
 type Application struct {
-	Database        Database `yaml:"database"`        // 数据库连接配置，不同的数据库驱动连接配置不一样，见数据库驱动
-	Port            int      `yaml:"port"`            // 端口
-	SyncPort        int      `yaml:"syncPort"`        // sync端口
-	LogKeepMaxDays  int      `yaml:"logKeepMaxDays"`  // 操作日志最大保存天数，注意若该值小于等于0则表示不删除。
-	NoteKeepMaxDays int      `yaml:"noteKeepMaxDays"` // 操作日志最大保存天数，注意若该值小于等于0则表示不删除
-	SSOBaseUrl      string   `yaml:"SSOBaseUrl"`      // 单点登录基础路径
-	Debug           bool     `yaml:"debug"`           // 调试模式
+	Database        Database      `yaml:"database"`        // 数据库连接配置，不同的数据库驱动连接配置不一样，见数据库驱动
+	DataDir         string        `yaml:"dataDir"`         // 数据根目录，笔记、头像、日志、根证书存储于该目录下，为空时使用程序运行目录
+	Port            int           `yaml:"port"`            // 端口
+	SyncPort        int           `yaml:"syncPort"`        // sync端口
+	SyncAuth        SyncAuth      `yaml:"syncAuth"`        // 用户同步服务认证配置
+	StatePolicies   []StatePolicy `yaml:"statePolicies"`   // 用户同步时员工状态对应的账号策略，未配置的状态账号正常可用
+	Scim            Scim          `yaml:"scim"`            // SCIM 2.0 用户供应配置，运行于sync端口
+	Successor       string        `yaml:"successor"`       // 离职交接默认接收人的工号或用户名，同步离职（状态6、SCIM禁用）时自动交接，为空表示仅禁用账号
+	MetricsPort     int           `yaml:"metricsPort"`     // 监控指标端口，提供 /metrics 接口，小于等于0表示不启用
+	LogKeepMaxDays  int           `yaml:"logKeepMaxDays"`  // 操作日志最大保存天数，注意若该值小于等于0则表示不删除。
+	NoteKeepMaxDays int           `yaml:"noteKeepMaxDays"` // 操作日志最大保存天数，注意若该值小于等于0则表示不删除
+	SSOBaseUrl      string        `yaml:"SSOBaseUrl"`      // 单点登录基础路径
+	SSOClientId     string        `yaml:"SSOClientId"`     // 单点登录客户端ID，为空表示不启用单点登录
+	SSOClientSecret string        `yaml:"SSOClientSecret"` // 单点登录客户端密钥，建议使用"file:"从文件读取
+	Debug           bool          `yaml:"debug"`           // 调试模式
+	LogFormat       string        `yaml:"logFormat"`       // 系统日志文件格式：console（默认）、json，控制台始终使用console格式
+	ShutdownTimeout int           `yaml:"shutdownTimeout"` // 优雅关闭超时时间（单位：秒），等待处理中的请求完成及操作日志写入，小于等于0时使用30秒
+	Alert           Alert         `yaml:"alert"`           // 审计告警配置
+	Mail            Mail          `yaml:"mail"`            // 邮件通知配置
+	TLS             TLS           `yaml:"tls"`             // HTTPS配置
+
+	File string `yaml:"-"` // 加载的配置文件路径
 }
 
 // Database 数据库配置
 type Database struct {
-	Type string // 数据库类型：mysql、dm
-	DSN  string // 连接地址
+	Type string `yaml:"type"` // 数据库类型：mysql、mariadb
+	DSN  string `yaml:"dsn"`  // 连接地址，包含密码时建议使用"file:"从文件读取
+}
+
+// SyncAuth 用户同步服务认证配置
+// 调用方使用共享密钥对请求进行HMAC-SM3签名，签名算法见 reuint.SignRequest。
+type SyncAuth struct {
+	Secret   string   `yaml:"secret"`   // 共享密钥，不少于16个字符，建议使用"file:"从文件读取
+	MaxSkew  int      `yaml:"maxSkew"`  // 请求时间戳允许的最大偏差（单位：秒），默认300
+	AllowIPs []string `yaml:"allowIPs"` // 允许访问的IP或网段（CIDR），为空表示不限制
+
+	// 可信反向代理的IP或网段（CIDR），仅来自可信代理的请求使用 X-Forwarded-For 判断来源IP，
+	// 为空表示不信任任何代理，始终使用连接的对端地址
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// StatePolicy 员工状态账号策略
+// 如：实习（3）账号自进入该状态起180天后过期，离职（6）禁用账号。
+type StatePolicy struct {
+	State      int  `yaml:"state"`      // 员工状态 1 - 正式 2 - 试用 3 - 实习 4 - 外聘 5 - 劳务派遣 6 - 离职 7 - 返聘
+	Disable    bool `yaml:"disable"`    // 是否禁用账号
+	ExpireDays int  `yaml:"expireDays"` // 账号有效天数，自进入该状态起计算，小于等于0表示不过期
+}
+
+// Scim SCIM 2.0 用户供应配置
+// 启用后用户同步服务提供 /scim/v2 接口，调用方使用 Authorization: Bearer <token> 认证。
+type Scim struct {
+	Token string `yaml:"token"` // 访问令牌，不少于32个字符，为空表示不启用，建议使用"file:"从文件读取
+}
+
+// TLS HTTPS配置
+// 同时设置证书与私钥时Web服务与用户同步服务启用HTTPS，监控指标服务始终使用HTTP。
+type TLS struct {
+	CertFile     string `yaml:"certFile"`     // 证书文件（PEM），可包含证书链
+	KeyFile      string `yaml:"keyFile"`      // 私钥文件（PEM）
+	ClientCAFile string `yaml:"clientCAFile"` // 用户同步服务客户端证书的CA证书文件（PEM），设置后同步服务要求并验证客户端证书
+	TLCP         TLCP   `yaml:"tlcp"`         // 国密TLCP配置
+}
+
+// Enabled 是否启用HTTPS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// TLCP 国密TLCP（GM/T 0024）配置
+// 使用SM2签名证书与加密证书双证书，在独立端口提供Web服务，与HTTPS配置互不影响。
+type TLCP struct {
+	Port         int    `yaml:"port"`         // 监听端口，小于等于0表示不启用
+	SignCertFile string `yaml:"signCertFile"` // SM2签名证书文件（PEM）
+	SignKeyFile  string `yaml:"signKeyFile"`  // SM2签名私钥文件（PEM）
+	EncCertFile  string `yaml:"encCertFile"`  // SM2加密证书文件（PEM）
+	EncKeyFile   string `yaml:"encKeyFile"`   // SM2加密私钥文件（PEM）
+}
+
+// Enabled 是否启用TLCP
+func (t TLCP) Enabled() bool {
+	return t.Port > 0
+}
+
+// Alert 审计告警配置
+type Alert struct {
+	Webhook string      `yaml:"webhook"` // 告警推送地址，为空时仅在站内告警
+	Rules   []AlertRule `yaml:"rules"`   // 告警规则
+}
+
+// AlertRule 审计告警规则
+// 操作日志的操作名称与规则匹配时计数，时间窗口内计数达到阈值时产生告警。
+type AlertRule struct {
+	Name       string `yaml:"name"`       // 规则名称
+	OpName     string `yaml:"opName"`     // 匹配的操作名称，如：删除笔记
+	Threshold  int    `yaml:"threshold"`  // 阈值，时间窗口内计数达到该值时告警，小于等于1表示每次匹配均告警
+	Window     int    `yaml:"window"`     // 时间窗口（单位：分钟）
+	PerUser    bool   `yaml:"perUser"`    // 是否按操作者分别计数
+	CountParam string `yaml:"countParam"` // 计数参数，为空时每条日志计数1，否则按操作参数中该字段以","分隔的元素个数计数，如：ids
+	Level      string `yaml:"level"`      // 告警级别：info、warn、critical
+}
+
+// Mail 邮件通知配置
+// 启用后分享、权限申请、分享到期提醒及变更摘要等通知同时发送至用户邮箱，用户可按事件关闭。
+type Mail struct {
+	Host     string `yaml:"host"`     // SMTP服务器地址，为空表示不启用邮件通知
+	Port     int    `yaml:"port"`     // SMTP服务器端口
+	Security string `yaml:"security"` // 连接加密方式：starttls（默认，服务器支持时启用）、tls、none
+	Username string `yaml:"username"` // 认证用户名，为空表示无需认证
+	Password string `yaml:"password"` // 认证口令，建议使用"file:"从文件读取
+	From     string `yaml:"from"`     // 发件人，如：笔记系统 <note@example.com>
+	BaseUrl  string `yaml:"baseUrl"`  // 系统访问地址，用于生成邮件中的链接，为空时不生成链接
+	Retries  int    `yaml:"retries"`  // 发送失败后的重试次数，间隔依次加倍（1、2、4…分钟）
+}
+
+// Enabled 是否启用邮件通知
+func (m Mail) Enabled() bool {
+	return m.Host != ""
 }
 
 // 无法找到配置文件时候的缺省配置
 var defaultConfig = Application{
 	Database: Database{
-		DSN:  "root:123qwe@tcp(127.0.0.1:3306)/note?charset=utf8mb4&parseTime=true",
+		// 不提供默认连接地址，防止默认配置文件中出现密码
+		DSN:  "",
 		Type: "mysql",
 	},
 	LogKeepMaxDays:  3 * 30, // 3月
 	NoteKeepMaxDays: 30,     // 1月
 	Port:            8011,
 	SyncPort:        8015,
+	SyncAuth:        SyncAuth{MaxSkew: 300},
+	StatePolicies:   []StatePolicy{{State: 6, Disable: true}},
+	MetricsPort:     8016,
 	SSOBaseUrl:      "http://nantemen.hzauth.com",
 	Debug:           true,
+	LogFormat:       "console",
+	ShutdownTimeout: 30,
+	Mail: Mail{
+		Port:     25,
+		Security: "starttls",
+		Retries:  3,
+	},
+	Alert: Alert{
+		Rules: []AlertRule{
+			{Name: "频繁删除笔记", OpName: "删除笔记", Threshold: 20, Window: 10, PerUser: true, Level: "warn"},
+			{Name: "用户锁定", OpName: "用户锁定", Threshold: 1, Level: "warn"},
+			{Name: "删除根证书", OpName: "删除根证书", Threshold: 1, Level: "critical"},
+			{Name: "批量删除用户", OpName: "删除用户", Threshold: 10, Window: 10, CountParam: "ids", Level: "critical"},
+		},
+	},
 }
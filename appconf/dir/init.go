@@ -8,31 +8,43 @@ import (
 
 var (
 	base        string // 程序运行目录
+	DataDir     string // 数据根目录
 	LogDir      string // 日志存储目录
+	ArchiveDir  string // 操作日志归档目录
 	NoteDir     string // 笔记文件存储目录
 	AvatarDir   string // 头像存储目录
 	UiDir       string // 前端文件存储目录
 	RootCertDir string // 根证书管理
 )
 
-func Init() {
-	base, _ := filepath.Abs(filepath.Dir(os.Args[0]))
-	LogDir = filepath.Join(base, "logs")
+// Init 初始化各级目录
+// dataRoot: 数据根目录，笔记、头像、日志、根证书存储于该目录下，为空时使用程序运行目录；前端文件始终位于程序运行目录。
+func Init(dataRoot string) error {
+	base, _ = filepath.Abs(filepath.Dir(os.Args[0]))
+	DataDir = base
+	if dataRoot != "" {
+		DataDir, _ = filepath.Abs(dataRoot)
+	}
+	LogDir = filepath.Join(DataDir, "logs")
+	ArchiveDir = filepath.Join(LogDir, "archive")
 	UiDir = filepath.Join(base, "ui")
-	AvatarDir = filepath.Join(base, "avatar")
-	NoteDir = filepath.Join(base, "notes")
-	RootCertDir = filepath.Join(base, "rootCerts")
+	AvatarDir = filepath.Join(DataDir, "avatar")
+	NoteDir = filepath.Join(DataDir, "notes")
+	RootCertDir = filepath.Join(DataDir, "rootCerts")
 
-	_ = os.MkdirAll(LogDir, os.ModePerm)
-	_ = os.MkdirAll(UiDir, os.ModePerm)
-	_ = os.MkdirAll(AvatarDir, os.ModePerm)
-	_ = os.MkdirAll(NoteDir, os.ModePerm)
-	_ = os.MkdirAll(RootCertDir, os.ModePerm)
+	for _, p := range []string{LogDir, ArchiveDir, UiDir, AvatarDir, NoteDir, RootCertDir} {
+		if err := os.MkdirAll(p, os.ModePerm); err != nil {
+			return err
+		}
+	}
 
 	log.Println("程序运行目录:", base)
+	log.Println("数据根目录:", DataDir)
 	log.Println("日志存储目录:", LogDir)
+	log.Println("操作日志归档目录:", ArchiveDir)
 	log.Println("前端文件存储目录:", UiDir)
 	log.Println("头像存储目录:", AvatarDir)
 	log.Println("笔记文件存储目录:", NoteDir)
 	log.Println("根证书目录:", RootCertDir)
+	return nil
 }
@@ -1,41 +1,315 @@
 package appconf
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"gopkg.in/yaml.v2"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
 )
 
-// Load 从配置文件中加在配置信息
-// 默认从可执行程序的相对目录中获取名为 application.yml 文件
-func Load() *Application {
+const (
+	EnvPrefix  = "NOTE_"       // 环境变量前缀
+	SecretFile = "file:"       // 从文件读取配置值的前缀
+	EnvConfig  = "NOTE_CONFIG" // 配置文件路径环境变量
+)
+
+// Load 加载配置信息
+// 默认从可执行程序的相对目录中获取名为 application.yml 文件，可通过 -config 参数或 NOTE_CONFIG 环境变量指定。
+// 配置优先级：命令行参数 > 环境变量 > 配置文件 > 缺省配置，加载后校验配置，配置错误时返回所有错误。
+// args: 命令行参数，不含程序名称
+func Load(args []string) (*Application, error) {
+	fs := newFlagSet()
+	configPath := fs.String("config", os.Getenv(EnvConfig), "配置文件路径，默认为程序运行目录下的 application.yml")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	p, err := configFile(*configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	res := defaultConfig
+	res.Alert.Rules = append([]AlertRule{}, defaultConfig.Alert.Rules...)
+	res.StatePolicies = append([]StatePolicy{}, defaultConfig.StatePolicies...)
+	bin, err := os.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("配置文件读取失败 %s: %s", p, err.Error())
+	}
+	if os.IsNotExist(err) {
+		// 没有配置文件的情况使用默认配置，并生成默认配置文件
+		// 注意空文件（如编辑中）不覆盖，防止热加载时破坏配置文件
+		b, _ := yaml.Marshal(&res)
+		_ = os.WriteFile(p, b, os.FileMode(0600))
+	} else if err = yaml.UnmarshalStrict(bin, &res); err != nil {
+		// 配置文件格式错误时不再使用默认配置，防止以错误的配置启动
+		return nil, fmt.Errorf("配置文件解析失败 %s: %s", p, err.Error())
+	}
+
+	var errs []string
+	// 环境变量覆盖
+	walk(reflect.ValueOf(&res).Elem(), "", func(path string, v reflect.Value) {
+		if raw, ok := os.LookupEnv(EnvName(path)); ok {
+			if err := setValue(v, raw); err != nil {
+				errs = append(errs, fmt.Sprintf("环境变量 %s: %s", EnvName(path), err.Error()))
+			}
+		}
+	})
+	// 命令行参数覆盖
+	set := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = f.Value.String()
+	})
+	walk(reflect.ValueOf(&res).Elem(), "", func(path string, v reflect.Value) {
+		if raw, ok := set[path]; ok {
+			if err := setValue(v, raw); err != nil {
+				errs = append(errs, fmt.Sprintf("命令行参数 -%s: %s", path, err.Error()))
+			}
+		}
+	})
+	// 从文件读取敏感配置
+	walk(reflect.ValueOf(&res).Elem(), "", func(path string, v reflect.Value) {
+		if v.Kind() != reflect.String || !strings.HasPrefix(v.String(), SecretFile) {
+			return
+		}
+		secret := strings.TrimPrefix(v.String(), SecretFile)
+		if !filepath.IsAbs(secret) {
+			secret = filepath.Join(filepath.Dir(p), secret)
+		}
+		b, err := os.ReadFile(secret)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: 读取文件失败 %s", path, err.Error()))
+			return
+		}
+		v.SetString(strings.TrimRight(string(b), "\r\n"))
+	})
+	if len(errs) > 0 {
+		return nil, joinErrors(errs)
+	}
+
+	// 相对路径均相对于配置文件所在目录
+	for _, item := range []*string{&res.DataDir, &res.TLS.CertFile, &res.TLS.KeyFile, &res.TLS.ClientCAFile,
+		&res.TLS.TLCP.SignCertFile, &res.TLS.TLCP.SignKeyFile, &res.TLS.TLCP.EncCertFile, &res.TLS.TLCP.EncKeyFile} {
+		if *item != "" && !filepath.IsAbs(*item) {
+			*item = filepath.Join(filepath.Dir(p), *item)
+		}
+	}
+	if err = res.Validate(); err != nil {
+		return nil, err
+	}
+	res.File = p
+	return &res, nil
+}
+
+// configFile 返回配置文件路径
+// 未指定时兼容两种格式的Yaml命名风格，均不存在时使用 application.yml
+func configFile(p string) (string, error) {
+	if p != "" {
+		return filepath.Abs(p)
+	}
 	base, _ := filepath.Abs(filepath.Dir(os.Args[0]))
+	p = filepath.Join(base, "application.yml")
+	if _, err := os.Stat(p); err == nil {
+		return p, nil
+	}
+	if _, err := os.Stat(filepath.Join(base, "application.yaml")); err == nil {
+		return filepath.Join(base, "application.yaml"), nil
+	}
+	return p, nil
+}
+
+// Validate 校验配置，返回所有配置错误
+func (a *Application) Validate() error {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
 
-	// 兼容两种格式的Yaml命名风格
-	p := filepath.Join(base, "application.yml")
-	bin, _ := os.ReadFile(p)
-	if len(bin) == 0 {
-		p = filepath.Join(base, "application.yaml")
-		bin, _ = os.ReadFile(p)
+	switch strings.ToLower(a.Database.Type) {
+	case "mysql", "mariadb":
+	default:
+		errs = append(errs, fmt.Sprintf("database.type: 未知的数据库类型 %q", a.Database.Type))
+	}
+	check(a.Database.DSN != "", "database.dsn: 不可为空，可通过环境变量 %s 设置", EnvName("database.dsn"))
+	check(a.Port > 0 && a.Port <= 65535, "port: 端口 %d 超出范围", a.Port)
+	check(a.SyncPort > 0 && a.SyncPort <= 65535, "syncPort: 端口 %d 超出范围", a.SyncPort)
+	check(len(a.SyncAuth.Secret) >= 16, "syncAuth.secret: 不少于16个字符，可通过环境变量 %s 设置", EnvName("syncAuth.secret"))
+	check(a.SyncAuth.MaxSkew >= 0, "syncAuth.maxSkew: 不可为负数")
+	for i, item := range a.SyncAuth.AllowIPs {
+		_, _, err := net.ParseCIDR(item)
+		check(err == nil || net.ParseIP(item) != nil, "syncAuth.allowIPs[%d]: 非法的IP或网段 %q", i, item)
+	}
+	for i, item := range a.SyncAuth.TrustedProxies {
+		_, _, err := net.ParseCIDR(item)
+		check(err == nil || net.ParseIP(item) != nil, "syncAuth.trustedProxies[%d]: 非法的IP或网段 %q", i, item)
+	}
+	check(a.Scim.Token == "" || len(a.Scim.Token) >= 32, "scim.token: 不少于32个字符")
+	states := map[int]bool{}
+	for i, p := range a.StatePolicies {
+		check(p.State >= 1 && p.State <= 7, "statePolicies[%d].state: 未知的员工状态 %d", i, p.State)
+		check(!states[p.State], "statePolicies[%d].state: 员工状态 %d 重复", i, p.State)
+		states[p.State] = true
 	}
-	if len(bin) == 0 {
-		// 没有加载到配置文件的情况使用默认配置
-		res := &defaultConfig
-		// 生成默认配置文件
-		b, _ := yaml.Marshal(res)
-		_ = os.WriteFile(p, b, os.FileMode(0666))
-		return res
+	check(a.MetricsPort <= 65535, "metricsPort: 端口 %d 超出范围", a.MetricsPort)
+	check(a.SyncPort != a.Port, "syncPort: 不可与 port 相同")
+	check(a.MetricsPort <= 0 || (a.MetricsPort != a.Port && a.MetricsPort != a.SyncPort), "metricsPort: 不可与 port、syncPort 相同")
+	check(a.LogFormat == "" || a.LogFormat == "console" || a.LogFormat == "json", "logFormat: 仅支持 console、json")
+	check(a.ShutdownTimeout >= 0, "shutdownTimeout: 不可为负数")
+	check(a.SSOBaseUrl == "" || validURL(a.SSOBaseUrl), "SSOBaseUrl: 非法的地址 %q", a.SSOBaseUrl)
+	check(a.SSOClientId == "" || a.SSOClientSecret != "", "SSOClientSecret: 设置 SSOClientId 时不可为空")
+	check(a.SSOClientId == "" || a.SSOBaseUrl != "", "SSOBaseUrl: 设置 SSOClientId 时不可为空")
+	check((a.TLS.CertFile == "") == (a.TLS.KeyFile == ""), "tls: certFile 与 keyFile 须同时设置")
+	check(a.TLS.ClientCAFile == "" || a.TLS.Enabled(), "tls.clientCAFile: 需先设置 certFile 与 keyFile 启用HTTPS")
+	for _, f := range []string{a.TLS.CertFile, a.TLS.KeyFile, a.TLS.ClientCAFile} {
+		if f != "" {
+			_, err := os.Stat(f)
+			check(err == nil, "tls: 文件 %s 不可读取", f)
+		}
 	}
-	// 复制一份默认配置，防止出现空文件的导致的配置参数为空
-	var res = Application{}
-	err := yaml.Unmarshal(bin, &res)
-	if res.Port <= 0 {
-		res.Port = 80
+	if tlcp := a.TLS.TLCP; tlcp.Enabled() {
+		check(tlcp.Port <= 65535, "tls.tlcp.port: 端口 %d 超出范围", tlcp.Port)
+		check(tlcp.Port != a.Port && tlcp.Port != a.SyncPort && tlcp.Port != a.MetricsPort,
+			"tls.tlcp.port: 不可与 port、syncPort、metricsPort 相同")
+		for _, f := range []string{tlcp.SignCertFile, tlcp.SignKeyFile, tlcp.EncCertFile, tlcp.EncKeyFile} {
+			_, err := os.Stat(f)
+			check(f != "" && err == nil, "tls.tlcp: 签名证书、签名私钥、加密证书、加密私钥须同时设置且可读取 %q", f)
+		}
+	}
+	check(a.Alert.Webhook == "" || validURL(a.Alert.Webhook), "alert.webhook: 非法的地址 %q", a.Alert.Webhook)
+	for i, rule := range a.Alert.Rules {
+		check(rule.Name != "", "alert.rules[%d].name: 不可为空", i)
+		check(rule.OpName != "", "alert.rules[%d].opName: 不可为空", i)
+		check(rule.Threshold <= 1 || rule.Window > 0, "alert.rules[%d].window: 阈值大于1时时间窗口必须大于0", i)
+		check(rule.Level == "" || rule.Level == "info" || rule.Level == "warn" || rule.Level == "critical",
+			"alert.rules[%d].level: 仅支持 info、warn、critical", i)
+	}
+	if a.Mail.Enabled() {
+		check(a.Mail.Port > 0 && a.Mail.Port <= 65535, "mail.port: 端口 %d 超出范围", a.Mail.Port)
+		check(a.Mail.Security == "" || a.Mail.Security == "starttls" || a.Mail.Security == "tls" || a.Mail.Security == "none",
+			"mail.security: 仅支持 starttls、tls、none")
+		_, err := mail.ParseAddress(a.Mail.From)
+		check(err == nil, "mail.from: 非法的发件人 %q", a.Mail.From)
+		check(a.Mail.Retries >= 0, "mail.retries: 不可为负数")
+		check(a.Mail.BaseUrl == "" || validURL(a.Mail.BaseUrl), "mail.baseUrl: 非法的地址 %q", a.Mail.BaseUrl)
 	}
 
-	if err != nil {
-		// 没有加载到配置文件的情况使用默认配置
-		return &defaultConfig
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+	return nil
+}
+
+// Warnings 返回不影响启动但可能需要处理的配置问题，如旧版本升级后需补充的配置项
+func (a *Application) Warnings() []string {
+	var res []string
+	if a.SSOBaseUrl != "" && a.SSOClientId == "" {
+		res = append(res, fmt.Sprintf("单点登录未启用：程序不再内置单点登录客户端ID及密钥，如需使用请设置 SSOClientId、SSOClientSecret（环境变量 %s、%s）",
+			EnvName("SSOClientId"), EnvName("SSOClientSecret")))
+	}
+	return res
+}
+
+// EnvName 返回配置项对应的环境变量名称
+// path: 配置项路径，如 database.dsn、logKeepMaxDays、SSOBaseUrl
+func EnvName(path string) string {
+	var parts []string
+	for _, name := range strings.Split(path, ".") {
+		parts = append(parts, snake(name))
+	}
+	return EnvPrefix + strings.Join(parts, "_")
+}
+
+// snake 驼峰命名转换为大写下划线命名，如 SSOBaseUrl -> SSO_BASE_URL
+func snake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToUpper(r))
 	}
-	return &res
+	return b.String()
+}
+
+// walk 遍历配置对象中可覆盖的配置项（字符串、整数、布尔），忽略切片等复杂类型
+func walk(v reflect.Value, prefix string, fn func(path string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		fv := v.Field(i)
+		switch fv.Kind() {
+		case reflect.Struct:
+			walk(fv, name, fn)
+		case reflect.String, reflect.Int, reflect.Bool:
+			fn(name, fv)
+		}
+	}
+}
+
+// newFlagSet 创建命令行参数，每个配置项对应一个参数，参数名称为配置项路径
+func newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("note", flag.ContinueOnError)
+	var sample Application
+	walk(reflect.ValueOf(&sample).Elem(), "", func(path string, _ reflect.Value) {
+		fs.String(path, "", fmt.Sprintf("覆盖配置项 %s，对应环境变量 %s", path, EnvName(path)))
+	})
+	return fs
+}
+
+// setValue 将字符串转换为配置项的类型并赋值
+func setValue(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%q 不是整数", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%q 不是布尔值", raw)
+		}
+		v.SetBool(b)
+	}
+	return nil
+}
+
+// validURL 判断是否为合法的HTTP地址
+func validURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// joinErrors 合并多个配置错误
+func joinErrors(errs []string) error {
+	return errors.New("配置错误:\n  - " + strings.Join(errs, "\n  - "))
 }
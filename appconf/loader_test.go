@@ -3,6 +3,9 @@ package appconf
 import (
 	"fmt"
 	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -36,3 +39,101 @@ usr:
 
 	fmt.Printf("%s\n", expect)
 }
+
+func TestEnvName(t *testing.T) {
+	cases := map[string]string{
+		"database.dsn":   "NOTE_DATABASE_DSN",
+		"logKeepMaxDays": "NOTE_LOG_KEEP_MAX_DAYS",
+		"SSOBaseUrl":     "NOTE_SSO_BASE_URL",
+		"SSOClientId":    "NOTE_SSO_CLIENT_ID",
+		"alert.webhook":  "NOTE_ALERT_WEBHOOK",
+	}
+	for path, expect := range cases {
+		if actual := EnvName(path); actual != expect {
+			t.Fatalf("EnvName(%s) = %s, want %s", path, actual, expect)
+		}
+	}
+}
+
+func TestLoadOverride(t *testing.T) {
+	root := t.TempDir()
+	p := filepath.Join(root, "application.yml")
+	_ = os.WriteFile(p, []byte("database:\n  dsn: file:dsn.secret\nport: 8011\nsyncPort: 8015\nsyncAuth:\n  allowIPs: [10.0.0.0/8]\n"), 0600)
+	_ = os.WriteFile(filepath.Join(root, "dsn.secret"), []byte("root:pwd@tcp(127.0.0.1:3306)/note\n"), 0600)
+	t.Setenv("NOTE_PORT", "9000")
+	t.Setenv("NOTE_SYNC_AUTH_SECRET", "0123456789abcdef")
+	t.Setenv("NOTE_SYNC_PORT", "9001")
+
+	cfg, err := Load([]string{"-config", p, "-syncPort", "9002", "-dataDir", "data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.DSN != "root:pwd@tcp(127.0.0.1:3306)/note" {
+		t.Fatalf("DSN = %s, want read from file", cfg.Database.DSN)
+	}
+	if cfg.Port != 9000 {
+		t.Fatalf("Port = %d, want env override 9000", cfg.Port)
+	}
+	if cfg.SyncPort != 9002 {
+		t.Fatalf("SyncPort = %d, want flag override 9002", cfg.SyncPort)
+	}
+	if cfg.DataDir != filepath.Join(root, "data") {
+		t.Fatalf("DataDir = %s, want relative to config file", cfg.DataDir)
+	}
+	if len(cfg.Alert.Rules) == 0 {
+		t.Fatal("default alert rules should be kept")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	root := t.TempDir()
+	p := filepath.Join(root, "application.yml")
+
+	// 未知的配置项
+	_ = os.WriteFile(p, []byte("database:\n  dsn: root@/note\nprot: 8011\n"), 0600)
+	if _, err := Load([]string{"-config", p}); err == nil {
+		t.Fatal("unknown field should be rejected")
+	}
+
+	// 校验错误合并返回
+	_ = os.WriteFile(p, []byte("port: 70000\nlogFormat: xml\ntls:\n  certFile: cert.pem\n"), 0600)
+	_, err := Load([]string{"-config", p})
+	if err == nil {
+		t.Fatal("invalid config should be rejected")
+	}
+	for _, item := range []string{"database.dsn", "port", "logFormat", "keyFile", "syncAuth.secret"} {
+		if !strings.Contains(err.Error(), item) {
+			t.Fatalf("error should contain %s: %s", item, err.Error())
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	old := defaultConfig
+	cur := defaultConfig
+	cur.Port = 9000
+	cur.LogKeepMaxDays = 1
+	cur.Alert.Rules = []AlertRule{{Name: "test", OpName: "test"}}
+
+	changed := Diff(&old, &cur)
+	expect := []string{"port", "logKeepMaxDays", "alert.rules"}
+	if strings.Join(changed, ",") != strings.Join(expect, ",") {
+		t.Fatalf("Diff() = %v, want %v", changed, expect)
+	}
+	if IsReloadable("port") || !IsReloadable("alert.rules") || !IsReloadable("logKeepMaxDays") {
+		t.Fatal("IsReloadable() not match")
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	cfg := defaultConfig
+	cfg.SSOBaseUrl = "http://sso.example.com"
+	if w := cfg.Warnings(); len(w) != 1 || !strings.Contains(w[0], "SSOClientId") {
+		t.Fatalf("Warnings() = %v, want SSOClientId warning", w)
+	}
+	cfg.SSOClientId = "client"
+	cfg.SSOClientSecret = "secret"
+	if w := cfg.Warnings(); len(w) != 0 {
+		t.Fatalf("Warnings() = %v, want none", w)
+	}
+}
@@ -0,0 +1,61 @@
+package appconf
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Reloadable 支持热加载的配置项，修改后无需重启即可生效
+// 其余配置项（数据库、数据根目录、端口、日志格式、关闭超时）修改后需重启程序。
+var Reloadable = []string{
+	"logKeepMaxDays",
+	"noteKeepMaxDays",
+	"debug",
+	"SSOBaseUrl",
+	"SSOClientId",
+	"SSOClientSecret",
+	"alert",
+	"successor",
+	"mail",
+}
+
+// Diff 比较两份配置，返回发生变化的配置项路径
+// 告警规则等复杂类型整体比较，路径如 alert.rules。
+func Diff(old, cur *Application) []string {
+	var res []string
+	var diff func(a, b reflect.Value, prefix string)
+	diff = func(a, b reflect.Value, prefix string) {
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = strings.ToLower(t.Field(i).Name)
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			if a.Field(i).Kind() == reflect.Struct {
+				diff(a.Field(i), b.Field(i), name)
+				continue
+			}
+			if !reflect.DeepEqual(a.Field(i).Interface(), b.Field(i).Interface()) {
+				res = append(res, name)
+			}
+		}
+	}
+	diff(reflect.ValueOf(old).Elem(), reflect.ValueOf(cur).Elem(), "")
+	return res
+}
+
+// IsReloadable 判断配置项是否支持热加载
+func IsReloadable(path string) bool {
+	for _, item := range Reloadable {
+		if path == item || strings.HasPrefix(path, item+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,486 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/notify"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint/jwt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// 笔记权限名称
+var roleNames = map[int]string{1: "查看", 2: "编辑"}
+
+// NewAccessRequestController 创建笔记权限申请控制器
+func NewAccessRequestController(router gin.IRouter) *AccessRequestController {
+	res := &AccessRequestController{}
+	r := router.Group("/accessRequest")
+	// 申请笔记权限
+	r.POST("/create", User, res.create)
+	// 获取待处理的权限申请
+	r.GET("/list", User, res.list)
+	// 获取我的权限申请
+	r.GET("/mine", User, res.mine)
+	// 同意权限申请
+	r.POST("/approve", User, res.approve)
+	// 拒绝权限申请
+	r.POST("/deny", User, res.deny)
+	return res
+}
+
+// AccessRequestController 笔记权限申请控制器
+// 无权限查看笔记或仅可查看的用户向笔记拥有者申请权限，拥有者同意后按分享笔记处理。
+type AccessRequestController struct {
+}
+
+/**
+@api {POST} /api/accessRequest/create 申请笔记权限
+@apiDescription 向笔记拥有者申请查看或编辑权限，获取笔记内容返回无权限或仅可查看时使用。
+
+申请后笔记拥有者（团队空间的笔记为用户组拥有者）收到站内通知；
+存在待处理的申请时修改该申请的权限及说明。
+@apiName AccessRequestCreate
+@apiGroup AccessRequest
+
+@apiPermission 用户
+
+@apiParam {Integer} noteId 笔记ID。
+@apiParam {Integer{1,2}} role 申请的权限
+<ul>
+	<li>1 - 可查看 </li>
+	<li>2 - 可编辑 </li>
+</ul>
+@apiParam {String{..512}} [message] 申请说明。
+
+@apiParamExample {json} 请求示例
+{
+	"noteId": 12,
+	"role": 2,
+	"message": "需要补充测试章节"
+}
+
+@apiSuccess {Integer} id 申请ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+5
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+已拥有该权限
+*/
+
+// create 申请笔记权限
+func (c *AccessRequestController) create(ctx *gin.Context) {
+	var info dto.AccessRequestCreateDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "申请笔记权限", map[string]interface{}{
+		"noteId": info.NoteId,
+		"role":   info.Role,
+	})
+	if err != nil || info.NoteId <= 0 || (info.Role != 1 && info.Role != 2) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	if utf8.RuneCountInString(info.Message) > 512 {
+		ErrIllegal(ctx, "申请说明过长")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	var note entity.Note
+	err = repo.DBDao.Select("id, title").First(&note, "id = ? AND is_delete = 0", info.NoteId).Error
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "笔记不存在")
+		return
+	} else if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	role, err := repo.NoteMemberRepo.Check(claims.Sub, info.NoteId)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if role == 0 || role >= info.Role {
+		ErrIllegal(ctx, "已拥有该权限")
+		return
+	}
+
+	// 存在待处理的申请时修改该申请
+	req := entity.AccessRequest{NoteId: info.NoteId, UserId: claims.Sub}
+	err = repo.DBDao.Where("note_id = ? AND user_id = ? AND status = 0", info.NoteId, claims.Sub).
+		Assign(map[string]interface{}{"role": info.Role, "message": info.Message}).FirstOrCreate(&req).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	owners, err := repo.NoteRepo.Owners(info.NoteId)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	content := fmt.Sprintf("%s 申请笔记《%s》的%s权限", operatorName(claims), note.Title, roleNames[info.Role])
+	if info.Message != "" {
+		content += "：" + info.Message
+	}
+	sendNotify(ctx, owners, notify.TypeAccessRequest, "权限申请", content, info.NoteId)
+
+	ctx.JSON(200, req.ID)
+}
+
+/**
+@api {GET} /api/accessRequest/list 获取待处理的权限申请
+@apiDescription 获取用户拥有的笔记及管理的团队空间笔记收到的权限申请，按申请时间倒序。
+@apiName AccessRequestList
+@apiGroup AccessRequest
+
+@apiPermission 用户
+
+@apiParam {Integer} [status=0] 状态，255表示全部
+<ul>
+	<li>0 - 待处理 </li>
+	<li>1 - 已同意 </li>
+	<li>2 - 已拒绝 </li>
+</ul>
+@apiParam {Integer} [page=1] 页码。
+@apiParam {Integer} [limit=20] 每页数量。
+
+@apiParamExample 请求示例
+GET /api/accessRequest/list?status=0&page=1&limit=20
+
+@apiSuccess {AccessRequest[]} records 查询结果列表。
+@apiSuccess {Integer} total 记录总数。
+@apiSuccess {Integer} size 每页显示条数。
+@apiSuccess {Integer} current 当前页。
+@apiSuccess {Integer} pages 总页数。
+@apiSuccess (AccessRequest) {Integer} id 申请ID。
+@apiSuccess (AccessRequest) {String} createdAt 申请时间。
+@apiSuccess (AccessRequest) {Integer} noteId 笔记ID。
+@apiSuccess (AccessRequest) {String} title 笔记名称。
+@apiSuccess (AccessRequest) {Integer} userId 申请者ID。
+@apiSuccess (AccessRequest) {String} name 申请者姓名。
+@apiSuccess (AccessRequest) {Integer} role 申请的权限，同意后为授予的权限。
+@apiSuccess (AccessRequest) {String} message 申请说明。
+@apiSuccess (AccessRequest) {Integer} status 状态。
+@apiSuccess (AccessRequest) {String} handledAt 处理时间。
+@apiSuccess (AccessRequest) {String} reply 处理意见。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"records": [{
+		"id": 5,
+		"createdAt": "2026-10-19 10:00:00",
+		"noteId": 12,
+		"title": "接口设计",
+		"userId": 31,
+		"name": "墨小菊",
+		"role": 2,
+		"message": "需要补充测试章节",
+		"status": 0,
+		"handledAt": null,
+		"reply": ""
+	}],
+	"total": 1,
+	"size": 20,
+	"current": 1,
+	"pages": 1
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// list 获取待处理的权限申请
+func (c *AccessRequestController) list(ctx *gin.Context) {
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	owned := repo.DBDao.Model(&entity.NoteMember{}).Select("note_id").Where("user_id = ? AND role = 0", claims.Sub)
+	managed := repo.DBDao.Model(&entity.GroupMember{}).Select("belong").Where("user_id = ? AND role = 0", claims.Sub)
+	c.query(ctx, "0", "access_requests.note_id IN (?) OR notes.group_id IN (?)", owned, managed)
+}
+
+/**
+@api {GET} /api/accessRequest/mine 获取我的权限申请
+@apiDescription 获取用户提交的权限申请，按申请时间倒序。
+@apiName AccessRequestMine
+@apiGroup AccessRequest
+
+@apiPermission 用户
+
+@apiParam {Integer} [status=255] 状态，255表示全部，取值同获取待处理的权限申请。
+@apiParam {Integer} [page=1] 页码。
+@apiParam {Integer} [limit=20] 每页数量。
+
+@apiParamExample 请求示例
+GET /api/accessRequest/mine?page=1&limit=20
+
+@apiSuccess {Object} page 分页结果，字段见获取待处理的权限申请。
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// mine 获取我的权限申请
+func (c *AccessRequestController) mine(ctx *gin.Context) {
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	c.query(ctx, "255", "access_requests.user_id = ?", claims.Sub)
+}
+
+// query 分页查询权限申请
+// defaultStatus: 未指定状态时查询的状态
+// cond: 申请范围条件
+func (c *AccessRequestController) query(ctx *gin.Context, defaultStatus string, cond string, args ...interface{}) {
+	status, _ := strconv.Atoi(ctx.DefaultQuery("status", defaultStatus))
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	query, tx := repo.NewPageQueryFnc(repo.DBDao, &entity.AccessRequest{}, page, limit, func(db *gorm.DB) *gorm.DB {
+		db = db.Table("access_requests").
+			Select("access_requests.id, access_requests.created_at, access_requests.note_id, notes.title, "+
+				"access_requests.user_id, users.`name`, access_requests.role, access_requests.message, "+
+				"access_requests.status, access_requests.handled_at, access_requests.reply").
+			Joins("INNER JOIN notes ON notes.id = access_requests.note_id").
+			Joins("LEFT JOIN users ON users.id = access_requests.user_id").
+			Where(cond, args...).
+			Order("access_requests.id desc")
+		if status != 255 {
+			db = db.Where("access_requests.status = ?", status)
+		}
+		return db
+	})
+	records := []dto.AccessRequestListDto{}
+	if err = tx.Find(&records).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	query.Records = records
+	ctx.JSON(200, query)
+}
+
+/**
+@api {POST} /api/accessRequest/approve 同意权限申请
+@apiDescription 同意权限申请，按分享笔记给申请者处理，仅笔记拥有者可操作。
+
+申请者已被分享时修改其权限及到期时间，处理结果通过站内通知告知申请者。
+@apiName AccessRequestApprove
+@apiGroup AccessRequest
+
+@apiPermission 用户
+
+@apiParam {Integer} id 申请ID。
+@apiParam {Integer{0,1,2}} [role=0] 授予的权限，为0时授予申请的权限。
+@apiParam {String} [expiresAt] 到期时间，格式：2006-01-02 15:04:05，为空表示不过期。
+
+@apiParamExample {json} 请求示例
+{
+	"id": 5,
+	"expiresAt": "2026-10-26 18:00:00"
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+申请已处理
+*/
+
+// approve 同意权限申请
+func (c *AccessRequestController) approve(ctx *gin.Context) {
+	var info dto.AccessRequestApproveDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "同意权限申请", map[string]interface{}{
+		"id":        info.Id,
+		"role":      info.Role,
+		"expiresAt": info.ExpiresAt,
+	})
+	if err != nil || info.Id <= 0 || info.Role < 0 || info.Role > 2 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	req, ok := c.pending(ctx, info.Id)
+	if !ok {
+		return
+	}
+	if info.Role == 0 {
+		info.Role = req.Role
+	}
+	// 与分享笔记相同的处理，包括拥有者权限校验
+	share := &dto.NoteShareDto{
+		Id:        req.UserId,
+		NoteId:    req.NoteId,
+		ShareType: "user",
+		Role:      info.Role,
+		ExpiresAt: info.ExpiresAt,
+	}
+	_, err = shareNote(claims.Sub, share)
+	if illegal, ok := err.(illegalErr); ok {
+		ErrIllegal(ctx, string(illegal))
+		return
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	if !c.handle(ctx, req, claims.Sub, 1, info.Role, "") {
+		return
+	}
+	content := fmt.Sprintf("你对笔记《%s》的%s权限申请已同意", noteTitle(req.NoteId), roleNames[info.Role])
+	if info.ExpiresAt != "" {
+		content += fmt.Sprintf("，权限将于 %s 到期", info.ExpiresAt)
+	}
+	sendNotify(ctx, []int{req.UserId}, notify.TypeAccessApproved, "权限申请已同意", content, req.NoteId)
+	emitNoteShared(ctx, share)
+}
+
+/**
+@api {POST} /api/accessRequest/deny 拒绝权限申请
+@apiDescription 拒绝权限申请，仅笔记拥有者可操作，处理结果通过站内通知告知申请者。
+@apiName AccessRequestDeny
+@apiGroup AccessRequest
+
+@apiPermission 用户
+
+@apiParam {Integer} id 申请ID。
+@apiParam {String{..512}} [reply] 处理意见。
+
+@apiParamExample {json} 请求示例
+{
+	"id": 5,
+	"reply": "请联系项目负责人"
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+无权限
+*/
+
+// deny 拒绝权限申请
+func (c *AccessRequestController) deny(ctx *gin.Context) {
+	var info dto.AccessRequestDenyDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "拒绝权限申请", map[string]interface{}{
+		"id":    info.Id,
+		"reply": info.Reply,
+	})
+	if err != nil || info.Id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	if utf8.RuneCountInString(info.Reply) > 512 {
+		ErrIllegal(ctx, "处理意见过长")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	req, ok := c.pending(ctx, info.Id)
+	if !ok {
+		return
+	}
+	role, err := repo.NoteMemberRepo.Check(claims.Sub, req.NoteId)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if role != 0 {
+		ErrIllegal(ctx, "无权限")
+		return
+	}
+
+	if !c.handle(ctx, req, claims.Sub, 2, req.Role, info.Reply) {
+		return
+	}
+	content := fmt.Sprintf("你对笔记《%s》的%s权限申请已被拒绝", noteTitle(req.NoteId), roleNames[req.Role])
+	if info.Reply != "" {
+		content += "：" + info.Reply
+	}
+	sendNotify(ctx, []int{req.UserId}, notify.TypeAccessDenied, "权限申请已拒绝", content, req.NoteId)
+}
+
+// pending 获取待处理的权限申请
+// return: 申请，申请不存在或已处理时响应错误并返回false
+func (c *AccessRequestController) pending(ctx *gin.Context, id int) (*entity.AccessRequest, bool) {
+	var req entity.AccessRequest
+	err := repo.DBDao.First(&req, id).Error
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "申请不存在")
+		return nil, false
+	} else if err != nil {
+		ErrSys(ctx, err)
+		return nil, false
+	}
+	if req.Status != 0 {
+		ErrIllegal(ctx, "申请已处理")
+		return nil, false
+	}
+	return &req, true
+}
+
+// handle 记录权限申请的处理结果
+// status: 1 - 已同意 2 - 已拒绝
+// role: 授予的权限
+func (c *AccessRequestController) handle(ctx *gin.Context, req *entity.AccessRequest, handlerId, status, role int, reply string) bool {
+	err := repo.DBDao.Model(req).Updates(map[string]interface{}{
+		"status":     status,
+		"role":       role,
+		"handler_id": handlerId,
+		"handled_at": time.Now(),
+		"reply":      reply,
+	}).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return false
+	}
+	return true
+}
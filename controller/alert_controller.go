@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"note/controller/dto"
+	"note/logg/applog"
+	"note/repo"
+	"note/repo/entity"
+)
+
+// NewAlertController 创建审计告警控制器
+func NewAlertController(router gin.IRouter) *AlertController {
+	res := &AlertController{}
+	r := router.Group("/alert")
+	// 告警列表
+	r.GET("/list", Audit, res.list)
+	// 标记已读
+	r.POST("/read", Audit, res.read)
+	// 未读数量
+	r.GET("/unread", Audit, res.unread)
+	return res
+}
+
+// AlertController 审计告警控制器
+type AlertController struct {
+}
+
+/**
+@api {GET} /api/alert/list 告警列表
+@apiDescription 查询审计告警收件箱，支持分页查询，按告警时间倒序排列。
+@apiName AlertList
+@apiGroup Alert
+
+@apiPermission 审计员
+
+@apiParam {String=info,warn,critical} [level] 告警级别，为空表示所有。
+@apiParam {Integer=0,1,255} [isRead=255] 是否已读
+<ul>
+	<li>0 - 未读</li>
+	<li>1 - 已读</li>
+	<li>255 - 所有</li>
+</ul>
+@apiParam {Integer} [page=1] 分页查询页码，表示第几页，默认 1。
+@apiParam {Integer} [limit=20] 单页多少数据，默认 20。
+
+@apiParamExample {get} 请求示例
+GET /api/alert/list?isRead=0&page=1&limit=20
+
+@apiSuccess {Alert[]} records 查询结果列表。
+@apiSuccess {Integer} total 记录总数。
+@apiSuccess {Integer} size 每页显示条数，默认 20。
+@apiSuccess {Integer} current 当前页。
+@apiSuccess {Integer} pages 总页数。
+
+@apiSuccess (Alert) {Integer} id 告警ID。
+@apiSuccess (Alert) {String} createdAt 告警时间。
+@apiSuccess (Alert) {String} ruleName 触发的规则名称。
+@apiSuccess (Alert) {String} level 告警级别。
+@apiSuccess (Alert) {Integer} opType 操作者类型。
+@apiSuccess (Alert) {Integer} opId 操作者ID。
+@apiSuccess (Alert) {String} opName 操作名称。
+@apiSuccess (Alert) {String} content 告警内容。
+@apiSuccess (Alert) {Integer} isRead 是否已读。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+	{
+		"records": [
+			{
+				"id": 1,
+				"createdAt": "2024-02-01 10:21:03",
+				"ruleName": "频繁删除笔记",
+				"level": "warn",
+				"opType": 2,
+				"opId": 12,
+				"opName": "删除笔记",
+				"content": "10分钟内「删除笔记」操作计数20，达到阈值20",
+				"isRead": 0
+			}
+		],
+		"total": 1,
+		"size": 20,
+		"current": 1,
+		"pages": 1
+	}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+权限错误
+*/
+
+// list 告警列表
+func (c *AlertController) list(ctx *gin.Context) {
+	var param dto.AlertSearchDto
+
+	// 设置默认值
+	param.IsRead = 255
+	param.Page = 1
+	param.Limit = 20
+
+	if ctx.ShouldBindQuery(&param) != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	query, tx := repo.NewPageQueryFnc(repo.DBDao, &entity.Alert{}, param.Page, param.Limit, func(db *gorm.DB) *gorm.DB {
+		if param.Level != "" {
+			db = db.Where("level = ?", param.Level)
+		}
+		if param.IsRead != 255 {
+			db = db.Where("is_read = ?", param.IsRead)
+		}
+		return db.Order("created_at desc")
+	})
+	alerts := []entity.Alert{}
+	if err := tx.Find(&alerts).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	query.Records = alerts
+	ctx.JSON(200, query)
+}
+
+/**
+@api {POST} /api/alert/read 标记已读
+@apiDescription 将告警标记为已读，告警ID列表为空时将全部告警标记为已读。
+@apiName AlertRead
+@apiGroup Alert
+
+@apiPermission 审计员
+
+@apiParam {Integer[]} [ids] 告警ID列表。
+
+@apiParamExample {json} 请求示例
+{
+	"ids": [1, 2]
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// read 标记已读
+func (c *AlertController) read(ctx *gin.Context) {
+	var info dto.AlertReadDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "告警标记已读", map[string]interface{}{
+		"ids": info.Ids,
+	})
+	if err != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	db := repo.DBDao.Model(&entity.Alert{}).Where("is_read = 0")
+	if len(info.Ids) > 0 {
+		db = db.Where("id in ?", info.Ids)
+	}
+	if err = db.Update("is_read", 1).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+}
+
+/**
+@api {GET} /api/alert/unread 未读数量
+@apiDescription 查询未读告警数量。
+@apiName AlertUnread
+@apiGroup Alert
+
+@apiPermission 审计员
+
+@apiParamExample {get} 请求示例
+GET /api/alert/unread
+
+@apiSuccess {Integer} body 未读告警数量。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+3
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// unread 未读数量
+func (c *AlertController) unread(ctx *gin.Context) {
+	var count int64
+	if err := repo.DBDao.Model(&entity.Alert{}).Where("is_read = 0").Count(&count).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, count)
+}
@@ -12,6 +12,7 @@ import (
 	"gorm.io/gorm"
 	"log"
 	"note/controller/dto"
+	"note/logg/applog"
 	"note/repo"
 	"note/repo/entity"
 	"note/reuint"
@@ -36,7 +37,7 @@ func NewLoginController(r gin.IRouter) *LoginController {
 	// 验证登录token
 	r.GET("/check", res.check)
 	// 临时接口 --- 同步用户数据至文件夹表
-	r.GET("/sync", res.sync)
+	r.GET("/sync", Admin, res.sync)
 
 	res.userCache = cache.New(cache.NoExpiration, 10*time.Minute)
 
@@ -62,6 +63,12 @@ func (c *LoginController) pwdAttempts(userName string) {
 	if usrTry >= 5 {
 		// 错误尝试达到5次，设置10分钟后清除缓存
 		c.userCache.Set(userName, usrTry, 10*time.Minute)
+		if usrTry == 5 {
+			// 记录用户锁定日志
+			applog.Anonymous("用户锁定", map[string]interface{}{
+				"username": userName,
+			})
+		}
 	} else {
 		// 将缓存中的userName对应值更新
 		c.userCache.Set(userName, usrTry, cache.NoExpiration)
@@ -128,6 +135,8 @@ func (c *LoginController) login(ctx *gin.Context) {
 
 	// 用户ID
 	var userSub int
+	// token过期时间
+	var tokenExp int64
 	err := ctx.BindJSON(&info)
 	if err != nil {
 		ErrIllegal(ctx, "参数非法，无法解析")
@@ -160,8 +169,13 @@ func (c *LoginController) login(ctx *gin.Context) {
 			c.pwdAttempts(info.Username)
 			return
 		}
+		if usr.Expired() {
+			ErrIllegal(ctx, "账号已过期，请联系管理员")
+			return
+		}
 		userType = "user"
 		userSub = usr.ID
+		tokenExp = userTokenExp(usr)
 		reqInfo.Username = usr.Username
 		reqInfo.Name = usr.Name
 	}
@@ -182,11 +196,11 @@ func (c *LoginController) login(ctx *gin.Context) {
 	}
 
 	c.userCache.Delete(info.Username)
-	claims := jwt.Claims{Type: userType, Sub: userSub, Exp: time.Now().Add(10 * time.Hour).UnixMilli()}
+	claims := jwt.Claims{Type: userType, Sub: userSub, Exp: tokenExp}
 	token := tokenManager.GenToken(&claims)
 	reqInfo.Transform(&claims)
 	// 设置头部 Cookies 有效时间为10小时
-	ctx.SetCookie("token", token, 10*3600, "", "", false, true)
+	tokenManager.SetToken(ctx, token, 10*3600)
 	ctx.JSON(200, reqInfo)
 }
 
@@ -210,7 +224,7 @@ HTTP/1.1 200 OK
 
 // logout 登出
 func (c *LoginController) logout(ctx *gin.Context) {
-	ctx.SetCookie("token", "", -1, "", "", false, true)
+	tokenManager.SetToken(ctx, "", -1)
 }
 
 /**
@@ -381,7 +395,7 @@ func (c *LoginController) entityAuth(ctx *gin.Context) {
 	token := tokenManager.GenToken(&claims)
 	reqInfo.Transform(&claims, &info)
 	// 设置头部 Cookies 有效时间为10小时
-	ctx.SetCookie("token", token, 10*3600, "", "", false, true)
+	tokenManager.SetToken(ctx, token, 10*3600)
 	ctx.JSON(200, reqInfo)
 }
 
@@ -560,6 +574,10 @@ func (c *LoginController) check(ctx *gin.Context) {
 			ErrSys(ctx, err)
 			return
 		}
+		if usr.Expired() {
+			ErrAuth(ctx)
+			return
+		}
 		res.Username = usr.Username
 		res.Name = usr.Name
 	} else if claims.Type == "admin" || claims.Type == "audit" {
@@ -659,3 +677,13 @@ func (c *LoginController) sync(ctx *gin.Context) {
 	}
 
 }
+
+// userTokenExp 用户token的过期时间，签发后10小时过期，且不晚于账号过期时间
+// 账号过期后token随之失效，无需等待token过期。
+func userTokenExp(user *entity.User) int64 {
+	exp := time.Now().Add(10 * time.Hour)
+	if user.ExpiredAt != nil && user.ExpiredAt.Before(exp) {
+		exp = *user.ExpiredAt
+	}
+	return exp.UnixMilli()
+}
@@ -1,26 +1,42 @@
 package controller
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"net/http"
+	"note/appconf"
 	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
 	"note/repo"
 	"note/repo/entity"
 	"note/reuint"
 	"strconv"
+	"time"
 )
 
+// ayncBatchMax 批量同步单次最大用户数
+const ayncBatchMax = 5000
+
 // AyncController 同步控制器
 type AyncController struct {
+	policies map[int]appconf.StatePolicy // 员工状态对应的账号策略
 }
 
 // NewAyncController 创建用户控制器
-func NewAyncController(router gin.IRouter) *AyncController {
-	res := &AyncController{}
+// policies: 员工状态对应的账号策略
+func NewAyncController(router gin.IRouter, policies []appconf.StatePolicy) *AyncController {
+	res := &AyncController{policies: map[int]appconf.StatePolicy{}}
+	for _, p := range policies {
+		res.policies[p.State] = p
+	}
 	r := router.Group("/user")
 	// 用户同步
 	r.POST("/aync", res.aync)
+	// 批量用户同步
+	r.POST("/ayncBatch", res.ayncBatch)
 	return res
 }
 
@@ -34,7 +50,18 @@ func NewAyncController(router gin.IRouter) *AyncController {
 当员工管理系统中的用户发生变更时（如：创建、数据更新），员工管理系统
 将会调用该接口对外推送发生变更的用户信息，所有推送消息工位为唯一的用户ID。
 
+该接口需要使用共享密钥（配置项 syncAuth.secret）对请求进行HMAC-SM3签名，
+并可通过配置项 syncAuth.allowIPs 限制调用方IP。签名方法如下：
+<pre>
+待签名字符串 = 请求方法 + "\n" + 请求路径 + "\n" + 时间戳 + "\n" + 随机数 + "\n" + Hex(SM3(请求体))
+签名 = Hex(HMAC-SM3(共享密钥, 待签名字符串))
+</pre>
+如：POST\n/api/user/aync\n1760832000\n8f3a2c1d\n{请求体SM3 Hex}
+
 @apiHeader {String} Content-Type application/json
+@apiHeader {String} X-Sync-Timestamp 请求时间戳（Unix秒），与服务器时间偏差不超过 syncAuth.maxSkew（默认300秒）。
+@apiHeader {String} X-Sync-Nonce 随机数，不超过64个字符，有效期内不可重复使用。
+@apiHeader {String} X-Sync-Signature 请求签名Hex。
 
 @apiParam {Integer} jobNumber 工号，必选无论什么情况都不能为空
 @apiParam {Integer=1,2,3,4,5,6,7} state 用户状态。
@@ -47,9 +74,19 @@ func NewAyncController(router gin.IRouter) *AyncController {
  <li>6 - 离职</li>
  <li>7 - 返聘</li>
 </ul>
+各状态对应的账号策略由配置项 statePolicies 设置，如：离职禁用账号、实习账号180天后过期，未配置的状态账号正常可用。
+用户变更为离职（状态6）时，若配置了默认接收人（配置项 successor）将自动离职交接（见 /api/user/offboard），
+否则（含默认接收人不存在或已被删除）仅禁用账号并吊销会话。
 @apiParam {String} [name] 姓名。
 @apiParam {String} [phone] 手机号。
 @apiParam {String} [email] 邮箱。
+@apiParam {Object} [department] 所属部门，不存在时创建，存在时更新名称及上级部门，为空时不修改用户所属部门。
+@apiParam {String} department.code 部门编号。
+@apiParam {String} [department.name] 部门名称。
+@apiParam {String} [department.parentCode] 上级部门编号，为空表示顶级部门。
+@apiParam {String} [title] 职务。
+@apiParam {Integer} [manager] 直属上级工号。
+@apiParam {String} [expiredAt] 账号过期日期，格式：2006-01-02，为空时按员工状态的账号策略（配置项 statePolicies）设置。
 
 @apiParamExample {json} 用户创建或更新
 {
@@ -57,7 +94,10 @@ func NewAyncController(router gin.IRouter) *AyncController {
      "state": 1,
      "name": "张三",
      "phone": "13875648756",
-     "email": "123456@mail.com"
+     "email": "123456@mail.com",
+     "department": {"code": "D0102", "name": "研发一部", "parentCode": "D01"},
+     "title": "工程师",
+     "manager": 20001
 }
 @apiParamExample {json} 用户禁用（和上面一个样例没有区别，是状态6的特殊情况）
 {
@@ -70,8 +110,24 @@ func NewAyncController(router gin.IRouter) *AyncController {
 @apiSuccessExample {http} 成功响应
 HTTP/1.1 200 OK
 
+15
+
 @apiErrorExample 失败响应
-HTTP/1.1 500
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+
+@apiErrorExample 签名错误
+HTTP/1.1 401 Unauthorized
+
+签名错误
+
+@apiErrorExample 来源IP不允许
+HTTP/1.1 403 Forbidden
+
+来源IP不允许
+
+@apiErrorExample 系统错误
 HTTP/1.1 500
 
 系统内部错误
@@ -81,56 +137,272 @@ HTTP/1.1 500
 func (c *AyncController) aync(ctx *gin.Context) {
 	ayncUser := new(dto.AyncUserDto)
 
-	if err := ctx.BindJSON(ayncUser); err != nil {
+	if err := ctx.ShouldBindJSON(ayncUser); err != nil {
 		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	var applied *ayncApplied
+	err := repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		var err error
+		applied, err = c.apply(tx, ayncUser)
+		return err
+	})
+	var illegal illegalErr
+	if errors.As(err, &illegal) {
+		ErrIllegal(ctx, illegal.Error())
+		return
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	applied.done()
+	ctx.JSON(http.StatusOK, applied.user.ID)
+}
+
+/**
+@api {post} /api/user/ayncBatch 批量用户同步
+
+@apiGroup User
+@apiName UserAyncBatch
+
+@apiDescription 该接口由员工管理系统调用，用于批量推送发生变更的用户信息。
+单个用户的同步规则、请求签名方法与 /api/user/aync 相同。
+所有用户在同一事务中按顺序同步，单个用户同步失败时仅撤销该用户的变更，不影响其他用户，
+响应中按请求顺序返回每个用户的同步结果。单次最多同步5000个用户。
+
+@apiHeader {String} Content-Type application/json
+@apiHeader {String} X-Sync-Timestamp 请求时间戳（Unix秒）。
+@apiHeader {String} X-Sync-Nonce 随机数。
+@apiHeader {String} X-Sync-Signature 请求签名Hex。
+
+@apiParam {Object[]} body 用户列表，字段同 /api/user/aync。
+
+@apiParamExample {json} 请求示例
+[
+    {"jobNumber": 21011, "state": 1, "name": "张三", "department": {"code": "D0102", "name": "研发一部", "parentCode": "D01"}, "title": "工程师", "manager": 20001},
+    {"jobNumber": 21012, "state": 6, "name": "李四"},
+    {"jobNumber": 0, "state": 1, "name": "王五"}
+]
+
+@apiSuccess {Object[]} body 同步结果，顺序与请求一致。
+@apiSuccess {Integer} body.jobNumber 工号。
+@apiSuccess {Integer} body.id 用户ID，失败时为0。
+@apiSuccess {String} body.error 错误信息，为空表示成功。
+
+@apiSuccessExample {json} 成功响应
+HTTP/1.1 200 OK
+
+[
+    {"jobNumber": 21011, "id": 15, "error": ""},
+    {"jobNumber": 21012, "id": 16, "error": ""},
+    {"jobNumber": 0, "id": 0, "error": "工号不能为空"}
+]
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+单次最多同步5000个用户
+
+@apiErrorExample 系统错误
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// ayncBatch 批量用户同步
+func (c *AyncController) ayncBatch(ctx *gin.Context) {
+	var items []dto.AyncUserDto
+	if err := ctx.ShouldBindJSON(&items); err != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	if len(items) > ayncBatchMax {
+		ErrIllegal(ctx, "单次最多同步5000个用户")
+		return
+	}
+
+	results := make([]dto.AyncResultDto, len(items))
+	var synced []*ayncApplied
+	failed := 0
+	err := repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		for i := range items {
+			results[i].JobNumber = items[i].JobNumber
+			if err := tx.SavePoint("aync").Error; err != nil {
+				return err
+			}
+			applied, err := c.apply(tx, &items[i])
+			if err == nil {
+				results[i].Id = applied.user.ID
+				synced = append(synced, applied)
+				continue
+			}
+			// 仅撤销该用户的变更
+			if rerr := tx.RollbackTo("aync").Error; rerr != nil {
+				return rerr
+			}
+			failed++
+			var illegal illegalErr
+			if errors.As(err, &illegal) {
+				results[i].Error = illegal.Error()
+			} else {
+				middle.Logger(ctx).Warn("用户同步失败", zap.Int("jobNumber", items[i].JobNumber), zap.Error(err))
+				results[i].Error = "系统内部错误"
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	applog.Anonymous("批量用户同步", map[string]int{"total": len(items), "failed": failed})
+	for _, applied := range synced {
+		applied.done()
+	}
+	ctx.JSON(http.StatusOK, results)
+}
+
+// ayncApplied 单个用户的同步结果
+// 吊销会话、记录离职交接及事件推送须在事务提交成功后执行，见 done。
+type ayncApplied struct {
+	user   *entity.User
+	revoke bool                   // 是否需要吊销会话，账号被禁用或过期时间提前时为true
+	report *dto.OffboardReportDto // 离职交接报告，未交接时为nil
+}
+
+// done 事务提交成功后吊销会话、记录离职交接并推送用户同步事件
+func (a *ayncApplied) done() {
+	if a.revoke {
+		revokeSessions(a.user.ID)
+	}
+	logOffboard(a.report)
+	emitUserSynced(a.user, "aync")
+}
+
+// apply 同步单个用户，同步所属部门并按员工状态设置账号策略
+// 同步数据非法时返回 illegalErr 错误。
+// tx: 事务
+// item: 用户同步数据
+func (c *AyncController) apply(tx *gorm.DB, item *dto.AyncUserDto) (*ayncApplied, error) {
+	if item.JobNumber <= 0 {
+		return nil, illegalErr("工号不能为空")
+	}
+	if item.State < 1 || item.State > 7 {
+		return nil, illegalErr("未知的用户状态")
+	}
+	var expiredAt *time.Time
+	if item.ExpiredAt != "" {
+		t, err := time.ParseInLocation("2006-01-02", item.ExpiredAt, time.Local)
+		if err != nil {
+			return nil, illegalErr("账号过期日期格式错误")
+		}
+		expiredAt = &t
+	}
+	if item.Department != nil && item.Department.Code == "" {
+		return nil, illegalErr("部门编号不能为空")
 	}
 
 	var user entity.User
-	err := repo.DBDao.First(&user, "openid = ? ", ayncUser.JobNumber).Error
+	err := tx.First(&user, "openid = ? ", item.JobNumber).Error
 
 	// 若 查找不到 则创建用户
 	if err == gorm.ErrRecordNotFound {
 		defaultPwd := "Gm123qwe"
 		pwd, salt, err := reuint.GenPasswordSalt(defaultPwd)
 		if err != nil {
-			ErrSys(ctx, err)
-			return
+			return nil, err
 		}
 		// 密码和盐值
 		user.Password = entity.Pwd(pwd)
 		user.Salt = salt
 	} else if err != nil {
-		ErrSys(ctx, err)
-		return
+		return nil, err
 	}
 
-	// 状态设置
-	if ayncUser.State == 6 {
+	// 部门同步
+	if item.Department != nil {
+		if err = syncDepartment(tx, item.Department); err != nil {
+			return nil, err
+		}
+		user.DepartmentCode = item.Department.Code
+	}
+
+	// 账号策略，状态变化时重新计算过期时间
+	prevState, prevDelete, prevExpiredAt := user.State, user.IsDelete, user.ExpiredAt
+	policy := c.policies[item.State]
+	if policy.Disable {
 		user.IsDelete = 1
 	} else {
 		user.IsDelete = 0
 	}
+	if expiredAt != nil {
+		user.ExpiredAt = expiredAt
+	} else if policy.ExpireDays <= 0 {
+		user.ExpiredAt = nil
+	} else if user.State != item.State || user.ExpiredAt == nil {
+		t := time.Now().AddDate(0, 0, policy.ExpireDays)
+		user.ExpiredAt = &t
+	}
+	user.State = item.State
 
-	openId := strconv.Itoa(ayncUser.JobNumber)
+	openId := strconv.Itoa(item.JobNumber)
 	user.Openid = openId
 	user.Username = openId
-	user.Name = ayncUser.Name
-	user.Phone = ayncUser.Phone
-	user.Email = ayncUser.Email
+	user.Name = item.Name
+	user.Phone = item.Phone
+	user.Email = item.Email
+	user.Title = item.Title
+	user.Manager = ""
+	if item.Manager > 0 {
+		user.Manager = strconv.Itoa(item.Manager)
+	}
 
 	// 姓名转化为拼音首字母
-	if len(ayncUser.Name) > 0 {
-		str, err := reuint.PinyinConversion(ayncUser.Name)
+	if len(item.Name) > 0 {
+		str, err := reuint.PinyinConversion(item.Name)
 		if err != nil {
-			ErrSys(ctx, err)
-			return
+			return nil, err
 		}
 		user.NamePy = str
 	}
 
-	if err := repo.DBDao.Save(&user).Error; err != nil {
-		ErrSys(ctx, err)
-		return
+	if err = tx.Save(&user).Error; err != nil {
+		return nil, err
+	}
+
+	// 离职时自动交接，禁用账号的用户在事务提交后吊销会话
+	res := &ayncApplied{user: &user}
+	if user.ID != 0 && item.State == 6 && prevState != 6 && prevDelete == 0 {
+		if res.report, err = autoOffboard(tx, user.ID, "aync"); err != nil {
+			return nil, err
+		}
+		user.IsDelete = 1
+	}
+	// 过期时间提前时已签发的token可能晚于新的过期时间失效，同样吊销会话
+	expireEarlier := user.ExpiredAt != nil && (prevExpiredAt == nil || user.ExpiredAt.Before(*prevExpiredAt))
+	res.revoke = (user.IsDelete == 1 && prevDelete == 0) || expireEarlier
+	return res, nil
+}
+
+// syncDepartment 同步部门，不存在时创建，存在时更新名称及上级部门
+func syncDepartment(tx *gorm.DB, item *dto.AyncDepartmentDto) error {
+	var dept entity.Department
+	err := tx.First(&dept, "code = ?", item.Code).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return err
+	}
+	if err == nil && (item.Name == "" || dept.Name == item.Name) && dept.ParentCode == item.ParentCode {
+		return nil
+	}
+	if item.Code == item.ParentCode {
+		return illegalErr("上级部门不能为部门本身")
+	}
+	dept.Code = item.Code
+	if item.Name != "" {
+		dept.Name = item.Name
 	}
-	ctx.JSON(http.StatusOK, user.ID)
+	dept.ParentCode = item.ParentCode
+	return tx.Save(&dept).Error
 }
@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"encoding/json"
+	"note/repo/entity"
+	"time"
+)
+
+// AccessRequestCreateDto 申请笔记权限
+type AccessRequestCreateDto struct {
+	NoteId  int    `json:"noteId"`  // 笔记ID
+	Role    int    `json:"role"`    // 申请的权限 1 - 可查看 2 - 可编辑
+	Message string `json:"message"` // 申请说明
+}
+
+// AccessRequestApproveDto 同意权限申请
+type AccessRequestApproveDto struct {
+	Id        int    `json:"id"`        // 申请ID
+	Role      int    `json:"role"`      // 授予的权限，为0时授予申请的权限
+	ExpiresAt string `json:"expiresAt"` // 到期时间，格式：2006-01-02 15:04:05，为空表示不过期
+}
+
+// AccessRequestDenyDto 拒绝权限申请
+type AccessRequestDenyDto struct {
+	Id    int    `json:"id"`    // 申请ID
+	Reply string `json:"reply"` // 处理意见
+}
+
+// AccessRequestListDto 权限申请列表
+type AccessRequestListDto struct {
+	ID        int        `json:"id"`        // 申请ID
+	CreatedAt time.Time  `json:"createdAt"` // 申请时间
+	NoteId    int        `json:"noteId"`    // 笔记ID
+	Title     string     `json:"title"`     // 笔记名称
+	UserId    int        `json:"userId"`    // 申请者ID
+	Name      string     `json:"name"`      // 申请者姓名
+	Role      int        `json:"role"`      // 申请的权限，同意后为授予的权限
+	Message   string     `json:"message"`   // 申请说明
+	Status    int        `json:"status"`    // 状态 0 - 待处理 1 - 已同意 2 - 已拒绝
+	HandledAt *time.Time `json:"handledAt"` // 处理时间
+	Reply     string     `json:"reply"`     // 处理意见
+}
+
+func (c *AccessRequestListDto) MarshalJSON() ([]byte, error) {
+	type Alias AccessRequestListDto
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt entity.DateTime  `json:"createdAt"`
+		HandledAt *entity.DateTime `json:"handledAt"`
+	}{
+		(*Alias)(c),
+		entity.DateTime(c.CreatedAt),
+		(*entity.DateTime)(c.HandledAt),
+	})
+}
@@ -0,0 +1,14 @@
+package dto
+
+// AlertSearchDto 审计告警搜索
+type AlertSearchDto struct {
+	Level  string `form:"level" json:"level"`   // 告警级别，为空表示所有
+	IsRead int    `form:"isRead" json:"isRead"` // 是否已读 0 - 未读；1 - 已读；255 - 所有
+	Page   int    `form:"page" json:"page"`     // 页码 1 起
+	Limit  int    `form:"limit" json:"limit"`   // 页容量，默认20
+}
+
+// AlertReadDto 告警标记已读
+type AlertReadDto struct {
+	Ids []int `json:"ids"` // 告警ID列表，为空表示全部标记已读
+}
@@ -18,3 +18,35 @@ type FolderRemoveDto struct {
 	ID       int `json:"id"`       // 文件夹ID
 	ParentId int `json:"parentId"` // 移动到的文件夹ID
 }
+
+// FolderShareDto 文件夹分享
+type FolderShareDto struct {
+	Id        int    `json:"id"`        // 被分享者ID
+	FolderId  int    `json:"folderId"`  // 文件夹ID
+	ShareType string `json:"shareType"` // 被分享者类型：user、group
+	Role      int    `json:"role"`      // 权限 1 - 可查看 2 - 可编辑
+}
+
+// FolderUnShareDto 取消文件夹分享
+type FolderUnShareDto struct {
+	Id        int    `json:"id"`        // 被分享者ID
+	FolderId  int    `json:"folderId"`  // 文件夹ID
+	ShareType string `json:"shareType"` // 被分享者类型：user、group
+}
+
+// FolderMemberDto 文件夹分享对象
+type FolderMemberDto struct {
+	Id        int    `json:"id"`        // 用户ID或用户组ID
+	Name      string `json:"name"`      // 用户姓名或用户组名称
+	ShareType string `json:"shareType"` // 被分享者类型：user、group
+	Role      int    `json:"role"`      // 权限 1 - 可查看 2 - 可编辑
+}
+
+// FolderSharedDto 分享给用户的文件夹
+type FolderSharedDto struct {
+	ID       int    `json:"id"`       // 文件夹ID
+	Name     string `json:"name"`     // 文件夹名称
+	UserId   int    `json:"userId"`   // 文件夹拥有者ID
+	Username string `json:"username"` // 文件夹拥有者姓名
+	Role     int    `json:"role"`     // 权限 1 - 可查看 2 - 可编辑，分享给多个用户组时取最高权限
+}
@@ -23,6 +23,7 @@ type NoteInfoDto struct {
 	IsDelete   int       `json:"isDelete"`   // 是否删除
 	FolderId   int       `json:"folderId"`   // 文件夹Id
 	FolderName string    `json:"folderName"` // 文件夹名称 （包含父文件夹信息 以/分隔）
+	GroupId    int       `json:"groupId"`    // 团队空间所属用户组ID，0表示个人笔记，团队空间的笔记 userName 为用户组名称
 }
 
 func (c *NoteInfoDto) MarshalJSON() ([]byte, error) {
@@ -5,6 +5,7 @@ type NoteShareDto struct {
 	NoteId    int    `json:"noteId"`
 	ShareType string `json:"shareType"`
 	Role      int    `json:"role"`
+	ExpiresAt string `json:"expiresAt"` // 到期时间，格式：2006-01-02 15:04:05，为空表示不过期
 }
 
 type NoteUnShareDto struct {
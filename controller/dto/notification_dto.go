@@ -0,0 +1,15 @@
+package dto
+
+// NotificationReadDto 标记通知已读
+type NotificationReadDto struct {
+	Ids []int `json:"ids"` // 通知ID列表
+	All bool  `json:"all"` // 是否标记全部通知已读，为true时忽略ids
+}
+
+// MailPreferenceDto 邮件通知偏好，各项取值 0 - 不发送 1 - 发送
+type MailPreferenceDto struct {
+	ShareGranted  int `json:"shareGranted"`  // 笔记分享给我时是否发送邮件
+	AccessRequest int `json:"accessRequest"` // 收到笔记权限申请时是否发送邮件
+	ShareExpiring int `json:"shareExpiring"` // 分享即将到期时是否发送邮件
+	Digest        int `json:"digest"`        // 是否发送关注笔记的变更摘要邮件
+}
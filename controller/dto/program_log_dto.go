@@ -0,0 +1,17 @@
+package dto
+
+// ProgramLogQueryDto 系统日志查询
+type ProgramLogQueryDto struct {
+	Start   int64  `form:"start" json:"start"`     // 开始时间，Unix毫秒时间戳
+	End     int64  `form:"end" json:"end"`         // 截止时间，Unix毫秒时间戳
+	Level   string `form:"level" json:"level"`     // 最低日志级别 debug、info、warn、error
+	Keyword string `form:"keyword" json:"keyword"` // 消息包含的内容
+	Caller  string `form:"caller" json:"caller"`   // 调用位置包含的内容
+	Limit   int    `form:"limit" json:"limit"`     // 最大返回条数，默认200，最大1000
+}
+
+// LogLevelDto 系统日志级别
+type LogLevelDto struct {
+	Package string `json:"package"` // 包路径，为空表示调整全局级别，如 note/controller
+	Level   string `json:"level"`   // 日志级别 debug、info、warn、error，包级别为空时表示删除该包的覆盖
+}
@@ -0,0 +1,112 @@
+package dto
+
+import "encoding/json"
+
+// SCIM 2.0 资源及消息的 Schema URI
+const (
+	ScimSchemaUser       = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup      = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaEnterprise = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+	ScimSchemaList       = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatch      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError      = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSchemaSPConfig   = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ScimSchemaResource   = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+// ScimMeta 资源元数据
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`           // 资源类型：User、Group
+	Created      string `json:"created,omitempty"`      // 创建时间，RFC3339格式
+	LastModified string `json:"lastModified,omitempty"` // 最后修改时间，RFC3339格式
+	Location     string `json:"location,omitempty"`     // 资源地址
+}
+
+// ScimName 用户姓名
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`  // 完整姓名
+	FamilyName string `json:"familyName,omitempty"` // 姓
+	GivenName  string `json:"givenName,omitempty"`  // 名
+}
+
+// ScimMultiValue 多值属性，如邮箱、手机号
+type ScimMultiValue struct {
+	Value   string `json:"value"`             // 值
+	Type    string `json:"type,omitempty"`    // 类型，如 work
+	Primary bool   `json:"primary,omitempty"` // 是否为主要值
+}
+
+// ScimManager 直属上级
+type ScimManager struct {
+	Value string `json:"value,omitempty"` // 直属上级工号
+}
+
+// ScimEnterprise 企业用户扩展
+type ScimEnterprise struct {
+	Department string       `json:"department,omitempty"` // 部门编号
+	Manager    *ScimManager `json:"manager,omitempty"`    // 直属上级
+}
+
+// ScimUser SCIM用户资源
+type ScimUser struct {
+	Schemas      []string         `json:"schemas"`
+	Id           string           `json:"id,omitempty"`           // 用户ID
+	ExternalId   string           `json:"externalId,omitempty"`   // 外部ID，对应工号（openid）
+	UserName     string           `json:"userName"`               // 用户名
+	Name         *ScimName        `json:"name,omitempty"`         // 姓名
+	DisplayName  string           `json:"displayName,omitempty"`  // 显示名称，同姓名
+	Title        string           `json:"title,omitempty"`        // 职务
+	Active       *bool            `json:"active,omitempty"`       // 是否可用，false 表示禁用
+	Password     string           `json:"password,omitempty"`     // 口令，仅写入
+	Emails       []ScimMultiValue `json:"emails,omitempty"`       // 邮箱，仅使用第一个
+	PhoneNumbers []ScimMultiValue `json:"phoneNumbers,omitempty"` // 手机号，仅使用第一个
+	Groups       []ScimMember     `json:"groups,omitempty"`       // 所属用户组，只读
+	Enterprise   *ScimEnterprise  `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta         *ScimMeta        `json:"meta,omitempty"`
+}
+
+// ScimMember 用户组成员
+type ScimMember struct {
+	Value   string `json:"value"`             // 用户ID或用户组ID
+	Display string `json:"display,omitempty"` // 显示名称
+	Ref     string `json:"$ref,omitempty"`    // 资源地址
+}
+
+// ScimGroup SCIM用户组资源
+type ScimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id,omitempty"`      // 用户组ID
+	DisplayName string       `json:"displayName"`       // 用户组名称
+	Members     []ScimMember `json:"members,omitempty"` // 成员
+	Meta        *ScimMeta    `json:"meta,omitempty"`
+}
+
+// ScimListResponse 查询结果
+type ScimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"` // 符合条件的资源总数
+	StartIndex   int         `json:"startIndex"`   // 起始序号，从1开始
+	ItemsPerPage int         `json:"itemsPerPage"` // 本页资源数
+	Resources    interface{} `json:"Resources"`    // 资源列表
+}
+
+// ScimPatchDto 资源部分修改请求
+type ScimPatchDto struct {
+	Schemas    []string          `json:"schemas"`
+	Operations []ScimPatchOpItem `json:"Operations"`
+}
+
+// ScimPatchOpItem 修改操作
+type ScimPatchOpItem struct {
+	Op    string          `json:"op"`    // 操作类型（不区分大小写）：add、replace、remove
+	Path  string          `json:"path"`  // 属性路径，为空时 value 为属性对象
+	Value json.RawMessage `json:"value"` // 属性值
+}
+
+// ScimErrorDto 错误响应
+type ScimErrorDto struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`             // HTTP状态码
+	ScimType string   `json:"scimType,omitempty"` // 错误类型，如 invalidFilter、uniqueness
+	Detail   string   `json:"detail,omitempty"`   // 错误详情
+}
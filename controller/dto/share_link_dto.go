@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"encoding/json"
+	"note/repo/entity"
+	"time"
+)
+
+// ShareLinkCreateDto 创建公开分享链接
+type ShareLinkCreateDto struct {
+	NoteId      int    `json:"noteId"`      // 笔记ID
+	ExpiredAt   string `json:"expiredAt"`   // 过期时间，格式：2006-01-02 15:04:05，为空表示不过期
+	Password    string `json:"password"`    // 访问密码，为空表示无需密码
+	MaxViews    int    `json:"maxViews"`    // 最大访问次数，0表示不限制
+	AllowAssets bool   `json:"allowAssets"` // 是否允许下载笔记资源
+}
+
+// ShareLinkRevokeDto 撤销公开分享链接
+type ShareLinkRevokeDto struct {
+	Id int `json:"id"` // 链接ID
+}
+
+// PublicNoteReqDto 匿名访问公开分享的笔记
+type PublicNoteReqDto struct {
+	Token    string `json:"token"`    // 链接令牌
+	Password string `json:"password"` // 访问密码
+}
+
+// PublicNoteDto 公开分享的笔记
+type PublicNoteDto struct {
+	Title       string    `json:"title"`       // 笔记名称
+	UpdatedAt   time.Time `json:"updatedAt"`   // 更新时间
+	Content     string    `json:"content"`     // 笔记内容，允许下载资源时资源地址替换为公开资源下载地址
+	AllowAssets bool      `json:"allowAssets"` // 是否允许下载笔记资源
+	Ticket      string    `json:"ticket"`      // 资源下载凭证，允许下载资源时有效
+}
+
+func (c *PublicNoteDto) MarshalJSON() ([]byte, error) {
+	type Alias PublicNoteDto
+	return json.Marshal(&struct {
+		*Alias
+		UpdatedAt entity.DateTime `json:"updatedAt"`
+	}{
+		(*Alias)(c),
+		entity.DateTime(c.UpdatedAt),
+	})
+}
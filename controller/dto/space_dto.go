@@ -0,0 +1,16 @@
+package dto
+
+// SpaceCreateDto 创建团队空间
+type SpaceCreateDto struct {
+	GroupId int    `json:"groupId"` // 用户组ID
+	Name    string `json:"name"`    // 团队空间名称，即根文件夹名称
+}
+
+// SpaceDto 团队空间
+type SpaceDto struct {
+	GroupId   int    `json:"groupId"`   // 所属用户组ID，笔记列表的 space 参数
+	GroupName string `json:"groupName"` // 用户组名称
+	FolderId  int    `json:"folderId"`  // 根文件夹ID
+	Name      string `json:"name"`      // 团队空间名称
+	Role      int    `json:"role"`      // 用户在用户组中的角色 0 - 拥有者（管理） 1 - 普通用户（查看） 2 - 维护（编辑）
+}
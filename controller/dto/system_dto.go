@@ -0,0 +1,17 @@
+package dto
+
+import "note/reuint"
+
+// SystemInfoDto 系统运行信息
+type SystemInfoDto struct {
+	SystemVersion string            `json:"systemVersion"` // 系统版本号
+	BuildCommit   string            `json:"buildCommit"`   // 构建时的代码提交ID
+	GoVersion     string            `json:"goVersion"`     // Go版本
+	StartedAt     string            `json:"startedAt"`     // 启动时间，格式"YYYY-MM-DD HH:mm:ss"
+	Uptime        int64             `json:"uptime"`        // 运行时长（单位：秒）
+	DBVersion     string            `json:"dbVersion"`     // 数据库版本号
+	Disk          *reuint.DiskUsage `json:"disk"`          // 笔记存储区所在磁盘使用情况，获取失败时为null
+	Users         int64             `json:"users"`         // 用户数量
+	Notes         int64             `json:"notes"`         // 笔记数量（不含已删除）
+	Assets        int64             `json:"assets"`        // 笔记资源文件数量
+}
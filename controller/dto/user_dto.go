@@ -1,6 +1,10 @@
 package dto
 
-import "note/repo/entity"
+import (
+	"encoding/json"
+	"note/repo/entity"
+	"time"
+)
 
 // UserCreateDto create接口接收前端数据
 type UserCreateDto struct {
@@ -33,9 +37,21 @@ func (nameListDto *NameListDto) Transform(usr *entity.User) *NameListDto {
 
 // UserListDto 接口将以下数据返回给前端
 type UserListDto struct {
-	ID   int    `json:"id"`   // 用户ID
-	Name string `json:"name"` // 用户姓名
-	Role int    `json:"role"`
+	ID        int        `json:"id"`   // 用户ID
+	Name      string     `json:"name"` // 用户姓名
+	Role      int        `json:"role"`
+	ExpiresAt *time.Time `json:"expiresAt"` // 分享到期时间，为空表示不过期
+}
+
+func (c *UserListDto) MarshalJSON() ([]byte, error) {
+	type Alias UserListDto
+	return json.Marshal(&struct {
+		*Alias
+		ExpiresAt *entity.DateTime `json:"expiresAt"`
+	}{
+		(*Alias)(c),
+		(*entity.DateTime)(c.ExpiresAt),
+	})
 }
 
 // UserInfoDto 接口将以下数据返回给前端
@@ -51,9 +67,48 @@ type UserInfoDto struct {
 
 // AyncUserDto 用户同步DTO
 type AyncUserDto struct {
+	JobNumber  int                `json:"jobNumber"`  // 工号
+	State      int                `json:"state"`      // 用户状态 1 - 正式 2 - 试用 3 - 实习 4 - 外聘 5 - 劳务派遣 6 - 离职 7 - 返聘
+	Name       string             `json:"name"`       // 姓名
+	Phone      string             `json:"phone"`      // 手机号
+	Email      string             `json:"email"`      // 邮箱
+	Department *AyncDepartmentDto `json:"department"` // 所属部门，为空时不修改
+	Title      string             `json:"title"`      // 职务
+	Manager    int                `json:"manager"`    // 直属上级工号，0表示无
+	ExpiredAt  string             `json:"expiredAt"`  // 账号过期日期，格式：2006-01-02，为空时按员工状态的账号策略设置
+}
+
+// AyncDepartmentDto 部门同步DTO
+type AyncDepartmentDto struct {
+	Code       string `json:"code"`       // 部门编号
+	Name       string `json:"name"`       // 部门名称
+	ParentCode string `json:"parentCode"` // 上级部门编号，为空表示顶级部门
+}
+
+// AyncResultDto 批量用户同步结果
+type AyncResultDto struct {
 	JobNumber int    `json:"jobNumber"` // 工号
-	State     int    `json:"state"`     // 用户状态 6 - 离职
-	Name      string `json:"name"`      // 姓名
-	Phone     string `json:"phone"`     // 手机号
-	Email     string `json:"email"`     // 邮箱
+	Id        int    `json:"id"`        // 用户ID，失败时为0
+	Error     string `json:"error"`     // 错误信息，为空表示成功
+}
+
+// OffboardDto 离职交接请求
+type OffboardDto struct {
+	UserId      int `json:"userId"`      // 离职用户ID
+	SuccessorId int `json:"successorId"` // 接收人用户ID，为0时使用配置的默认接收人
+}
+
+// OffboardReportDto 离职交接报告
+type OffboardReportDto struct {
+	UserId            int    `json:"userId"`            // 离职用户ID
+	Username          string `json:"username"`          // 离职用户名
+	SuccessorId       int    `json:"successorId"`       // 接收人用户ID
+	SuccessorName     string `json:"successorName"`     // 接收人用户名
+	FolderId          int    `json:"folderId"`          // 接收人的交接文件夹ID
+	Notes             int    `json:"notes"`             // 转移的笔记数量
+	Folders           int    `json:"folders"`           // 转移的文件夹数量
+	SharesRemoved     int    `json:"sharesRemoved"`     // 取消的分享给该用户的笔记及文件夹数量
+	Groups            int    `json:"groups"`            // 退出的用户组数量
+	GroupsTransferred int    `json:"groupsTransferred"` // 转交拥有者的用户组数量
+	Trigger           string `json:"trigger"`           // 触发方式：manual - 管理员、aync - 用户同步、scim - SCIM
 }
@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"encoding/json"
+	"note/repo/entity"
+	"time"
+)
+
 // UserGroupCreateDto 用户组创建DTO
 type UserGroupCreateDto struct {
 	Name        string `json:"name"` // 用户组名称
@@ -31,8 +37,21 @@ type MemberListDTO struct {
 
 // GroupListDto 接口将以下数据返回给前端
 type GroupListDto struct {
-	ID   int    `json:"id"`   // 组ID
-	Name string `json:"name"` // 组名
+	ID        int        `json:"id"`        // 组ID
+	Name      string     `json:"name"`      // 组名
+	Role      int        `json:"role"`      // 分享给用户组的权限 1 - 可查看 2 - 可编辑
+	ExpiresAt *time.Time `json:"expiresAt"` // 分享到期时间，为空表示不过期
+}
+
+func (c *GroupListDto) MarshalJSON() ([]byte, error) {
+	type Alias GroupListDto
+	return json.Marshal(&struct {
+		*Alias
+		ExpiresAt *entity.DateTime `json:"expiresAt"`
+	}{
+		(*Alias)(c),
+		(*entity.DateTime)(c.ExpiresAt),
+	})
 }
 
 // GroupRenameDto 用户组重命名DTO
@@ -0,0 +1,40 @@
+package dto
+
+import (
+	"encoding/json"
+	"note/repo/entity"
+	"time"
+)
+
+// WatchCreateDto 关注笔记或文件夹，笔记ID与文件夹ID仅可指定其一
+type WatchCreateDto struct {
+	NoteId   int `json:"noteId"`   // 笔记ID
+	FolderId int `json:"folderId"` // 文件夹ID
+}
+
+// WatchListDto 关注列表
+type WatchListDto struct {
+	ID        int       `json:"id"`        // 关注ID
+	CreatedAt time.Time `json:"createdAt"` // 关注时间
+	NoteId    int       `json:"noteId"`    // 笔记ID，0表示关注文件夹
+	FolderId  int       `json:"folderId"`  // 文件夹ID，0表示关注笔记
+	Name      string    `json:"name"`      // 笔记或文件夹名称
+}
+
+func (c *WatchListDto) MarshalJSON() ([]byte, error) {
+	type Alias WatchListDto
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt entity.DateTime `json:"createdAt"`
+	}{
+		(*Alias)(c),
+		entity.DateTime(c.CreatedAt),
+	})
+}
+
+// WatchSettingDto 变更摘要设置
+type WatchSettingDto struct {
+	Frequency string `json:"frequency"` // 摘要频率 hourly - 每小时 daily - 每天
+	InApp     int    `json:"inApp"`     // 是否发送站内通知摘要 0 - 不发送 1 - 发送
+	Email     int    `json:"email"`     // 是否发送摘要邮件 0 - 不发送 1 - 发送
+}
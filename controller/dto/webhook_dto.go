@@ -0,0 +1,30 @@
+package dto
+
+// WebhookCreateDto 创建事件推送订阅
+type WebhookCreateDto struct {
+	Name   string   `json:"name"`   // 名称
+	Url    string   `json:"url"`    // 推送地址
+	Events []string `json:"events"` // 订阅的事件，支持通配符，如：note.*
+	Secret string   `json:"secret"` // 签名密钥，为空时自动生成
+}
+
+// WebhookUpdateDto 修改事件推送订阅
+type WebhookUpdateDto struct {
+	Id      int      `json:"id"`      // 订阅ID
+	Name    string   `json:"name"`    // 名称
+	Url     string   `json:"url"`     // 推送地址
+	Events  []string `json:"events"`  // 订阅的事件
+	Enabled int      `json:"enabled"` // 是否启用 0 - 停用 1 - 启用
+	Secret  string   `json:"secret"`  // 新的签名密钥，为空表示不修改
+}
+
+// WebhookIdDto 事件推送订阅或推送记录ID
+type WebhookIdDto struct {
+	Id int `json:"id"` // ID
+}
+
+// WebhookSecretDto 事件推送订阅的签名密钥，仅在创建及修改密钥时返回
+type WebhookSecretDto struct {
+	Id     int    `json:"id"`     // 订阅ID
+	Secret string `json:"secret"` // 签名密钥
+}
@@ -5,13 +5,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"net/http"
+	"note/controller/middle"
 	"runtime"
 )
 
 // ErrSys 内部错误
 func ErrSys(c *gin.Context, err error) {
 	// 打印日志文件
-	zap.L().Error("系统内部错误",
+	middle.Logger(c).Error("系统内部错误",
 		zap.String("errTyp", "Inn"),
 		zap.Error(err),
 		zap.String("caller", caller()))
@@ -23,7 +24,7 @@ func ErrSys(c *gin.Context, err error) {
 // ErrIllegal 参数错误
 func ErrIllegal(c *gin.Context, hit string) {
 	// 打印日志
-	zap.L().Info("参数错误",
+	middle.Logger(c).Info("参数错误",
 		zap.String("errTyp", "Illegal"),
 		zap.String("desp", hit),
 		zap.String("caller", caller()))
@@ -36,7 +37,7 @@ func ErrIllegal(c *gin.Context, hit string) {
 // ErrIllegalE 参数错误
 func ErrIllegalE(c *gin.Context, err error) {
 	// 打印日志
-	zap.L().Info("参数错误",
+	middle.Logger(c).Info("参数错误",
 		zap.String("errTyp", "Illegal"),
 		zap.Error(err),
 		zap.String("caller", caller()))
@@ -60,7 +61,7 @@ func ErrForbidden(c *gin.Context, msg string) {
 // ErrNormal 普通错误，非系统错误由于运行时内部错误错误导致的错误，或不知道该如何处理的错误
 func ErrNormal(c *gin.Context, hit string, err error) {
 	// 打印日志文件
-	zap.L().Warn("异常",
+	middle.Logger(c).Warn("异常",
 		zap.String("errTyp", "Normal"),
 		zap.String("desp", hit),
 		zap.Error(err),
@@ -71,6 +72,13 @@ func ErrNormal(c *gin.Context, hit string, err error) {
 	_, _ = c.Writer.WriteString(hit)
 }
 
+// illegalErr 请求数据非法，区别于系统错误
+type illegalErr string
+
+func (e illegalErr) Error() string {
+	return string(e)
+}
+
 // 打印调用信息
 func caller() string {
 	pc, file, line, _ := runtime.Caller(2)
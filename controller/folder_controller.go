@@ -9,6 +9,7 @@ import (
 	"note/repo"
 	"note/repo/entity"
 	"note/reuint/jwt"
+	"note/webhook"
 	"strconv"
 )
 
@@ -26,6 +27,14 @@ func NewFolderController(router gin.IRouter) *FolderController {
 	r.POST("/rename", User, res.rename)
 	// 移动文件夹
 	r.POST("/remove", User, res.remove)
+	// 分享文件夹
+	r.POST("/share", User, res.share)
+	// 取消分享文件夹
+	r.POST("/cancel", User, res.cancel)
+	// 获取文件夹的分享对象
+	r.GET("/members", User, res.members)
+	// 获取分享给我的文件夹
+	r.GET("/shared", User, res.shared)
 	return res
 }
 
@@ -82,6 +91,38 @@ func (c *FolderController) create(ctx *gin.Context) {
 	// 获取创建文件夹的用户id
 	claims := claimsValue.(*jwt.Claims)
 
+	// 创建文件夹
+	folder := entity.Folder{
+		UserId:   claims.Sub,
+		Name:     folderDto.Name,
+		ParentId: folderDto.ParentId,
+	}
+	// 可在自己的文件夹或可编辑的团队空间中创建子文件夹，团队空间中的文件夹属于用户组
+	if folderDto.ParentId > 0 {
+		parent, err := repo.FolderRepo.GetById(folderDto.ParentId)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			ErrSys(ctx, err)
+			return
+		}
+		if parent == nil || (parent.GroupId == 0 && parent.UserId != claims.Sub) {
+			ErrIllegal(ctx, "父文件夹不存在")
+			return
+		}
+		if parent.GroupId != 0 {
+			role, err := repo.FolderRepo.Role(claims.Sub, parent.ID)
+			if err != nil {
+				ErrSys(ctx, err)
+				return
+			}
+			if role != 0 && role != 2 {
+				ErrIllegal(ctx, "无权限")
+				return
+			}
+			folder.UserId = 0
+			folder.GroupId = parent.GroupId
+		}
+	}
+
 	// 查询文件夹是否已存在
 	exist, err := repo.NewFolderRepository().Exist(claims.Sub, folderDto.Name, folderDto.ParentId)
 	if err != nil {
@@ -93,24 +134,25 @@ func (c *FolderController) create(ctx *gin.Context) {
 		return
 	}
 
-	// 创建文件夹
-	folder := entity.Folder{
-		UserId:   claims.Sub,
-		Name:     folderDto.Name,
-		ParentId: folderDto.ParentId,
-	}
 	err = repo.DBDao.Create(&folder).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
 	}
 
+	emitEvent(ctx, &webhook.Event{
+		Name:     webhook.EventFolderCreated,
+		FolderId: folder.ID,
+		Data:     map[string]interface{}{"id": folder.ID, "name": folder.Name, "parentId": folder.ParentId, "groupId": folder.GroupId},
+	})
 	ctx.JSON(200, folder.ID)
 }
 
 /**
 @api {GET} /api/folder/list 获取文件夹列表
 @apiDescription 获取文件夹列表
+
+id为空时返回用户的所有文件夹，否则返回该文件夹的子文件夹，分享给用户的文件夹同样可查看其子文件夹。
 @apiName FolderList
 @apiGroup Folder
 
@@ -165,7 +207,24 @@ func (c *FolderController) list(ctx *gin.Context) {
 			ErrIllegal(ctx, "参数解析错误")
 			return
 		}
-		err = repo.DBDao.Where("user_id = ? AND parent_id = ?", claims.Sub, folderId).Find(&folders).Error
+		// 共享文件夹及团队空间可查看其子文件夹
+		userId := claims.Sub
+		if folderId > 0 {
+			role, err := repo.FolderRepo.Role(claims.Sub, folderId)
+			if err != nil {
+				ErrSys(ctx, err)
+				return
+			}
+			if role >= 0 {
+				folder, err := repo.FolderRepo.GetById(folderId)
+				if err != nil {
+					ErrSys(ctx, err)
+					return
+				}
+				userId = folder.UserId
+			}
+		}
+		err = repo.DBDao.Where("user_id = ? AND parent_id = ?", userId, folderId).Find(&folders).Error
 		if err != nil {
 			ErrSys(ctx, err)
 			return
@@ -272,6 +331,36 @@ func (c *FolderController) delete(ctx *gin.Context) {
 			if dbErr != nil {
 				return dbErr
 			}
+			ids := make([]int, len(folders))
+			for i := range folders {
+				ids[i] = folders[i].ID
+			}
+			// 删除文件夹的分享授权
+			dbErr = tx.Where("folder_id IN ?", ids).Delete(&entity.FolderGrant{}).Error
+			if dbErr != nil {
+				return dbErr
+			}
+			// 取消对文件夹的关注
+			dbErr = tx.Where("folder_id IN ?", ids).Delete(&entity.Watch{}).Error
+			if dbErr != nil {
+				return dbErr
+			}
+			// 删除团队空间中的笔记
+			if folder.GroupId != 0 {
+				dbErr = tx.Model(&entity.Note{}).
+					Where("id IN (?)", tx.Model(&entity.NoteMember{}).Select("note_id").Where("role = 0 AND folder_id IN ?", ids)).
+					Where("group_id = ?", folder.GroupId).
+					Update("is_delete", 1).Error
+				if dbErr != nil {
+					return dbErr
+				}
+			}
+			// 其他用户在共享文件夹中创建的笔记移出文件夹
+			dbErr = tx.Model(&entity.NoteMember{}).Where("role = 0 AND folder_id IN ? AND user_id <> ?", ids, claims.Sub).
+				Update("folder_id", 0).Error
+			if dbErr != nil {
+				return dbErr
+			}
 		}
 
 		if len(noteMembers) != 0 {
@@ -302,6 +391,13 @@ func (c *FolderController) delete(ctx *gin.Context) {
 		return
 	}
 
+	// 文件夹已删除，仅拥有者及团队空间成员可接收
+	emitEvent(ctx, &webhook.Event{
+		Name:    webhook.EventFolderDeleted,
+		GroupId: folder.GroupId,
+		Users:   []int{claims.Sub},
+		Data:    map[string]interface{}{"id": folder.ID, "name": folder.Name, "groupId": folder.GroupId, "notes": len(notes)},
+	})
 	ctx.Status(200)
 }
 
@@ -376,6 +472,7 @@ func (c *FolderController) rename(ctx *gin.Context) {
 	}
 
 	// 文件夹重命名
+	oldName := folder.Name
 	folder.Name = info.Name
 
 	err = repo.DBDao.Save(&folder).Error
@@ -383,6 +480,11 @@ func (c *FolderController) rename(ctx *gin.Context) {
 		ErrSys(ctx, err)
 		return
 	}
+	emitEvent(ctx, &webhook.Event{
+		Name:     webhook.EventFolderRenamed,
+		FolderId: folder.ID,
+		Data:     map[string]interface{}{"id": folder.ID, "name": folder.Name, "oldName": oldName},
+	})
 
 	ctx.Status(200)
 
@@ -439,11 +541,16 @@ func (c *FolderController) remove(ctx *gin.Context) {
 
 	// 获取目标文件夹信息
 	if info.ParentId != 0 {
-		_, err = repo.FolderRepo.GetFolder(info.ParentId, claims.Sub)
+		target, err := repo.FolderRepo.GetFolder(info.ParentId, claims.Sub)
 		if err != nil {
 			ErrIllegal(ctx, "目标文件夹不存在")
 			return
 		}
+		// 文件夹不能在个人文件夹与团队空间之间移动
+		if target.GroupId != folder.GroupId {
+			ErrIllegal(ctx, "无法移动至其他团队空间或个人文件夹")
+			return
+		}
 		// 若移动的目的地址为该文件夹或该文件夹的子文件夹
 		folders := repo.FolderRepo.GetSubFolders(info.ID)
 		folders = append(folders, *folder)
@@ -455,6 +562,12 @@ func (c *FolderController) remove(ctx *gin.Context) {
 		}
 	}
 
+	// 团队空间只有一个根文件夹
+	if info.ParentId == 0 && folder.GroupId != 0 && folder.ParentId != 0 {
+		ErrIllegal(ctx, "团队空间的文件夹无法移动至根目录")
+		return
+	}
+
 	// 若未发生移动
 	if info.ParentId == folder.ParentId {
 		ctx.Status(200)
@@ -473,7 +586,8 @@ func (c *FolderController) remove(ctx *gin.Context) {
 		return
 	}
 
-	// 文件夹重命名
+	// 文件夹移动
+	oldParentId := folder.ParentId
 	folder.ParentId = info.ParentId
 
 	err = repo.DBDao.Save(&folder).Error
@@ -481,6 +595,356 @@ func (c *FolderController) remove(ctx *gin.Context) {
 		ErrSys(ctx, err)
 		return
 	}
+	emitEvent(ctx, &webhook.Event{
+		Name:     webhook.EventFolderMoved,
+		FolderId: folder.ID,
+		Data:     map[string]interface{}{"id": folder.ID, "name": folder.Name, "parentId": folder.ParentId, "oldParentId": oldParentId},
+	})
 
 	ctx.Status(200)
 }
+
+/**
+@api {POST} /api/folder/share 分享文件夹
+@apiDescription 分享文件夹
+
+文件夹下的笔记及子文件夹继承分享的权限，之后放入该文件夹的笔记自动对被分享者可见；
+笔记单独分享给用户或用户组时以笔记的分享权限为准。重复分享时修改权限，仅文件夹拥有者可分享。
+
+@apiName FolderShare
+@apiGroup Folder
+
+@apiPermission 用户
+
+@apiParam {Integer} id 		 被分享者ID
+@apiParam {Integer} folderId 文件夹ID
+@apiParam {String{"user","group"}} shareType 被分享者类型
+@apiParam {Integer{1,2}} role 权限
+<ul>
+	<li>1 - 可查看 </li>
+	<li>2 - 可编辑 </li>
+</ul>
+
+@apiParamExample {json} 请求示例
+{
+	"id": 2,
+	"folderId": 5,
+	"shareType": "group",
+	"role": 2
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// share 分享文件夹
+func (c *FolderController) share(ctx *gin.Context) {
+	var info dto.FolderShareDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "分享文件夹", map[string]interface{}{
+		"id":       info.Id,
+		"folderId": info.FolderId,
+		"type":     info.ShareType,
+		"role":     info.Role,
+	})
+	if err != nil || info.Id <= 0 || info.FolderId <= 0 || (info.Role != 1 && info.Role != 2) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	grant := entity.FolderGrant{FolderId: info.FolderId}
+	if !c.checkGrant(ctx, claims.Sub, info.Id, info.FolderId, info.ShareType, &grant) {
+		return
+	}
+
+	// 判断被分享者是否存在
+	var exist bool
+	if info.ShareType == "user" {
+		exist, err = repo.UserRepo.Exist(info.Id)
+	} else {
+		exist, err = repo.UserGroupRepo.ExistByID(info.Id)
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if !exist {
+		ErrIllegal(ctx, "被分享的用户或用户组不存在")
+		return
+	}
+
+	err = repo.DBDao.Where("folder_id = ? AND user_id = ? AND group_id = ?", grant.FolderId, grant.UserId, grant.GroupId).
+		Assign(entity.FolderGrant{Role: info.Role}).FirstOrCreate(&grant).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	emitFolderShare(ctx, webhook.EventFolderShared, info.FolderId, info.ShareType, info.Id, info.Role)
+}
+
+/**
+@api {POST} /api/folder/cancel 取消分享文件夹
+@apiDescription 取消分享文件夹，仅文件夹拥有者可取消。
+
+@apiName FolderCancel
+@apiGroup Folder
+
+@apiPermission 用户
+
+@apiParam {Integer} id 		 被分享者ID
+@apiParam {Integer} folderId 文件夹ID
+@apiParam {String{"user","group"}} shareType 被分享者类型
+
+@apiParamExample {json} 请求示例
+{
+	"id": 2,
+	"folderId": 5,
+	"shareType": "group"
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+未被分享
+*/
+
+// cancel 取消分享文件夹
+func (c *FolderController) cancel(ctx *gin.Context) {
+	var info dto.FolderUnShareDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "取消分享文件夹", map[string]interface{}{
+		"id":       info.Id,
+		"folderId": info.FolderId,
+		"type":     info.ShareType,
+	})
+	if err != nil || info.Id <= 0 || info.FolderId <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	grant := entity.FolderGrant{FolderId: info.FolderId}
+	if !c.checkGrant(ctx, claims.Sub, info.Id, info.FolderId, info.ShareType, &grant) {
+		return
+	}
+
+	res := repo.DBDao.Where("folder_id = ? AND user_id = ? AND group_id = ?", grant.FolderId, grant.UserId, grant.GroupId).
+		Delete(&entity.FolderGrant{})
+	if res.Error != nil {
+		ErrSys(ctx, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		ErrIllegal(ctx, "未被分享")
+		return
+	}
+	emitFolderShare(ctx, webhook.EventFolderUnshared, info.FolderId, info.ShareType, info.Id, 0)
+}
+
+// emitFolderShare 发布文件夹分享或取消分享事件，被分享的用户或用户组成员同样可接收
+// role: 分享的权限，取消分享时为0
+func emitFolderShare(ctx *gin.Context, name string, folderId int, shareType string, targetId, role int) {
+	e := &webhook.Event{
+		Name:     name,
+		FolderId: folderId,
+		Data:     map[string]interface{}{"folderId": folderId, "shareType": shareType, "targetId": targetId, "role": role},
+	}
+	if shareType == "group" {
+		e.GroupId = targetId
+	} else {
+		e.Users = []int{targetId}
+	}
+	emitEvent(ctx, e)
+}
+
+// checkGrant 检查文件夹分享参数，并设置授权对象
+// 文件夹不属于该用户、分享对象类型错误或分享给自己时返回错误响应。
+// return: 是否通过检查
+func (c *FolderController) checkGrant(ctx *gin.Context, userId, id, folderId int, shareType string, grant *entity.FolderGrant) bool {
+	_, err := repo.FolderRepo.GetFolder(folderId, userId)
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "文件夹不存在")
+		return false
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return false
+	}
+	switch shareType {
+	case "user":
+		if id == userId {
+			ErrIllegal(ctx, "无法对自己进行分享")
+			return false
+		}
+		grant.UserId = id
+	case "group":
+		grant.GroupId = id
+	default:
+		ErrIllegal(ctx, "未知类型")
+		return false
+	}
+	return true
+}
+
+/**
+@api {GET} /api/folder/members 获取文件夹的分享对象
+@apiDescription 获取文件夹分享的用户及用户组，仅文件夹拥有者可查看。
+@apiName FolderMembers
+@apiGroup Folder
+
+@apiPermission 用户
+
+@apiParam {Integer} id 文件夹ID。
+
+@apiParamExample 请求示例
+GET /api/folder/members?id=5
+
+@apiSuccess {Member[]} members 分享对象列表。
+@apiSuccess (Member) {Integer} id 用户ID或用户组ID。
+@apiSuccess (Member) {String} name 用户姓名或用户组名称。
+@apiSuccess (Member) {String} shareType 被分享者类型：user、group。
+@apiSuccess (Member) {Integer} role 权限。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+[
+    {
+        "id": 118,
+        "name": "墨小菊",
+        "shareType": "user",
+        "role": 2
+    },
+    {
+        "id": 1,
+        "name": "研发部",
+        "shareType": "group",
+        "role": 1
+    }
+]
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+文件夹不存在
+*/
+
+// members 获取文件夹的分享对象
+func (c *FolderController) members(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Query("id"))
+	if id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	_, err := repo.FolderRepo.GetFolder(id, claims.Sub)
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "文件夹不存在")
+		return
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	users := []dto.FolderMemberDto{}
+	err = repo.DBDao.Table("folder_grants").
+		Select("users.id AS id , users.`name` , 'user' AS share_type , folder_grants.role").
+		Joins("INNER JOIN users ON users.id = folder_grants.user_id").
+		Where("folder_grants.folder_id = ?", id).Find(&users).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	groups := []dto.FolderMemberDto{}
+	err = repo.DBDao.Table("folder_grants").
+		Select("user_groups.id AS id , user_groups.`name` , 'group' AS share_type , folder_grants.role").
+		Joins("INNER JOIN user_groups ON user_groups.id = folder_grants.group_id").
+		Where("folder_grants.folder_id = ?", id).Find(&groups).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	ctx.JSON(200, append(users, groups...))
+}
+
+/**
+@api {GET} /api/folder/shared 获取分享给我的文件夹
+@apiDescription 获取分享给用户或用户所在用户组的文件夹，子文件夹通过 /api/folder/list 查看，
+文件夹中的笔记通过 /api/note/noteList 的 folder 参数查询。
+@apiName FolderShared
+@apiGroup Folder
+
+@apiPermission 用户
+
+@apiSuccess {Folder[]} folders 文件夹列表。
+@apiSuccess (Folder) {Integer} id 文件夹ID。
+@apiSuccess (Folder) {String} name 文件夹名称。
+@apiSuccess (Folder) {Integer} userId 文件夹拥有者ID。
+@apiSuccess (Folder) {String} username 文件夹拥有者姓名。
+@apiSuccess (Folder) {Integer} role 权限，分享给多个用户组时取最高权限。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+[
+    {
+        "id": 5,
+        "name": "周报",
+        "userId": 30,
+        "username": "墨小菊",
+        "role": 2
+    }
+]
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// shared 获取分享给我的文件夹
+func (c *FolderController) shared(ctx *gin.Context) {
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	groups := repo.DBDao.Model(&entity.GroupMember{}).Select("belong").Where("user_id = ?", claims.Sub)
+	folders := []dto.FolderSharedDto{}
+	err := repo.DBDao.Table("folder_grants").
+		Select("folders.id AS id , folders.`name` , folders.user_id , users.`name` AS username , MAX(folder_grants.role) AS role").
+		Joins("INNER JOIN folders ON folders.id = folder_grants.folder_id").
+		Joins("LEFT JOIN users ON users.id = folders.user_id").
+		Where("folder_grants.user_id = ? OR folder_grants.group_id IN (?)", claims.Sub, groups).
+		Where("folders.user_id <> ?", claims.Sub).
+		Group("folders.id, folders.`name`, folders.user_id, users.`name`").
+		Find(&folders).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	ctx.JSON(200, folders)
+}
@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"note/appconf/dir"
+	"note/controller/middle"
+	"note/repo"
+	"note/reuint"
+	"os"
+	"time"
+)
+
+// NewHealthController 创建健康检查控制器
+// 健康检查接口位于根路径下，供负载均衡、容器编排等探针匿名访问。
+func NewHealthController(router gin.IRouter) *HealthController {
+	res := &HealthController{}
+	// 存活检查
+	router.GET("/healthz", res.healthz)
+	// 就绪检查
+	router.GET("/readyz", res.readyz)
+	return res
+}
+
+// HealthController 健康检查控制器
+type HealthController struct {
+}
+
+/**
+@api {GET} /healthz 存活检查
+@apiDescription 进程存活即返回成功，不检查依赖。
+@apiName Healthz
+@apiGroup System
+
+@apiPermission 匿名
+
+@apiParamExample 请求示例
+GET /healthz
+
+@apiSuccess {String} status 状态，固定为"ok"。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+	{
+		"status": "ok"
+	}
+*/
+
+// healthz 存活检查
+func (c *HealthController) healthz(ctx *gin.Context) {
+	ctx.JSON(200, gin.H{"status": "ok"})
+}
+
+/**
+@api {GET} /readyz 就绪检查
+@apiDescription 检查数据库连接、存储目录是否可写、根证书池是否加载，全部通过时返回200，否则返回503。
+@apiName Readyz
+@apiGroup System
+
+@apiPermission 匿名
+
+@apiParamExample 请求示例
+GET /readyz
+
+@apiSuccess {String} status 状态，"ok"表示就绪，"fail"表示未就绪。
+@apiSuccess {Object} checks 各项检查结果，"ok"或"fail"，失败原因仅记录于系统日志。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+	{
+		"status": "ok",
+		"checks": {
+			"database": "ok",
+			"storage": "ok",
+			"rootCerts": "ok"
+		}
+	}
+
+@apiErrorExample 失败响应
+HTTP/1.1 503 Service Unavailable
+
+	{
+		"status": "fail",
+		"checks": {
+			"database": "fail",
+			"storage": "ok",
+			"rootCerts": "ok"
+		}
+	}
+*/
+
+// readyz 就绪检查
+func (c *HealthController) readyz(ctx *gin.Context) {
+	checks := map[string]error{
+		"database":  checkDatabase(ctx.Request.Context()),
+		"storage":   checkStorage(),
+		"rootCerts": checkRootCerts(),
+	}
+	status := "ok"
+	res := map[string]string{}
+	for name, err := range checks {
+		if err != nil {
+			// 接口可匿名访问，失败原因可能包含数据库地址、文件路径，仅记录于系统日志
+			middle.Logger(ctx).Warn("就绪检查失败", zap.String("check", name), zap.Error(err))
+			status = "fail"
+			res[name] = "fail"
+		} else {
+			res[name] = "ok"
+		}
+	}
+	code := http.StatusOK
+	if status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	ctx.JSON(code, gin.H{"status": status, "checks": res})
+}
+
+// checkDatabase 检查数据库连接
+func checkDatabase(ctx context.Context) error {
+	if repo.DBDao == nil {
+		return errors.New("数据库未初始化")
+	}
+	db, err := repo.DBDao.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
+// checkStorage 检查存储目录是否可写
+func checkStorage() error {
+	for _, p := range []string{dir.NoteDir, dir.AvatarDir, dir.LogDir, dir.ArchiveDir, dir.RootCertDir} {
+		file, err := os.CreateTemp(p, ".readyz-*")
+		if err != nil {
+			return err
+		}
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}
+	return nil
+}
+
+// checkRootCerts 检查根证书池是否加载
+func checkRootCerts() error {
+	if reuint.CertPool == nil {
+		return errors.New("根证书池未加载")
+	}
+	return nil
+}
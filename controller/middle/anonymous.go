@@ -19,7 +19,8 @@ func Anonymous(ctx *gin.Context) {
 		return
 	}
 	switch dest {
-	case "/api/login", "/api/system/version", "/api/random", "/api/entityAuth", "/api/certBinding", "/api/redirect", "/api/sync":
+	case "/healthz", "/readyz", "/api/login", "/api/system/version", "/api/random", "/api/entityAuth", "/api/certBinding", "/api/redirect",
+		"/api/public/note", "/api/public/assert":
 		ctx.Set(FlagAnonymous, true)
 		return
 	}
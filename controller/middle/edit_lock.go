@@ -61,3 +61,8 @@ func (c *EditLock) Query(noteId string) LockInfo {
 func (c *EditLock) Unlock(noteId string) {
 	c.editCache.Delete(noteId)
 }
+
+// Count 当前持有的编辑锁数量（包含已过期但尚未清理的锁）
+func (c *EditLock) Count() int {
+	return c.editCache.ItemCount()
+}
@@ -16,16 +16,18 @@ func Recovery() gin.HandlerFunc {
 		caller := fmt.Sprintf("%s %s %d", runtime.FuncForPC(pc).Name(), file, line)
 
 		var err error
-		if ee, ok := err.(error); ok {
+		if ee, ok := e.(error); ok {
 			err = ee
 		} else {
 			err = fmt.Errorf("未知类型错误发生: %+v", e)
 		}
 
 		// 打印日志文件
-		zap.L().Error("系统内部错误",
+		Logger(c).Error("系统内部错误",
 			zap.String("errTyp", "Inn"),
 			zap.Error(err),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
 			zap.String("caller", caller))
 		c.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -0,0 +1,89 @@
+package middle
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"note/reuint/jwt"
+	"time"
+)
+
+const (
+	HeaderRequestId = "X-Request-ID" // 请求ID请求头
+	FlagRequestId   = "RequestId"    // 请求ID
+	FlagLogger      = "Logger"       // 请求范围的日志记录器
+)
+
+// RequestId 请求ID及访问日志拦截器
+// 沿用请求头中的请求ID或生成新的请求ID，并在请求上下文中设置携带请求ID的日志记录器，
+// 请求结束后记录访问日志。该拦截器应位于所有拦截器之前。
+func RequestId(ctx *gin.Context) {
+	id := ctx.GetHeader(HeaderRequestId)
+	if !validRequestId(id) {
+		id = newRequestId()
+	}
+	logger := zap.L().With(zap.String("requestId", id))
+	ctx.Set(FlagRequestId, id)
+	ctx.Set(FlagLogger, logger)
+	ctx.Header(HeaderRequestId, id)
+
+	start := time.Now()
+	ctx.Next()
+
+	user := ""
+	if v, ok := ctx.Get(FlagClaims); ok {
+		if claims, ok := v.(*jwt.Claims); ok {
+			user = fmt.Sprintf("%s:%d", claims.Type, claims.Sub)
+		}
+	}
+	status := ctx.Writer.Status()
+	fields := []zap.Field{
+		zap.String("method", ctx.Request.Method),
+		zap.String("path", ctx.Request.URL.Path),
+		zap.Int("status", status),
+		zap.Duration("latency", time.Since(start)),
+		zap.String("user", user),
+		zap.Int("bytes", ctx.Writer.Size()),
+		zap.String("ip", ctx.ClientIP()),
+	}
+	if status >= 500 {
+		logger.Warn("访问", fields...)
+	} else {
+		logger.Info("访问", fields...)
+	}
+}
+
+// Logger 获取请求范围的日志记录器，日志携带请求ID
+// 未经过 RequestId 拦截器时返回全局日志记录器。
+func Logger(ctx *gin.Context) *zap.Logger {
+	if ctx != nil {
+		if v, ok := ctx.Get(FlagLogger); ok {
+			if logger, ok := v.(*zap.Logger); ok {
+				return logger
+			}
+		}
+	}
+	return zap.L()
+}
+
+// validRequestId 校验外部传入的请求ID，防止日志注入
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestId 生成随机请求ID
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
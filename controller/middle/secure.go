@@ -0,0 +1,25 @@
+package middle
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// secureConnKey 加密连接标记的上下文键
+type secureConnKey struct{}
+
+// SecureConn 标记连接已加密，用作 http.Server.ConnContext
+// 用于 Request.TLS 为空的加密连接，如TLCP连接。
+func SecureConn(ctx context.Context, _ net.Conn) context.Context {
+	return context.WithValue(ctx, secureConnKey{}, true)
+}
+
+// IsSecure 请求是否通过HTTPS或TLCP等加密连接传输
+func IsSecure(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	secure, _ := r.Context().Value(secureConnKey{}).(bool)
+	return secure
+}
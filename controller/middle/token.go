@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -15,14 +16,18 @@ type TokenManager struct {
 	key    []byte // 当前HMAC密钥
 	oldKey []byte // 过去HMAC密钥
 	ticker *time.Ticker
+
+	revokedMu sync.RWMutex
+	revoked   map[int]int64 // 已吊销会话的用户，key：用户ID，value：吊销时间（Unix毫秒），此前签发的token均无效
 }
 
 // NewTokenFilter 新建token过滤器
 func NewTokenFilter() *TokenManager {
 	res := &TokenManager{
-		key:    make([]byte, 32),
-		oldKey: make([]byte, 32),
-		ticker: time.NewTicker(time.Hour * 12),
+		key:     make([]byte, 32),
+		oldKey:  make([]byte, 32),
+		ticker:  time.NewTicker(time.Hour * 12),
+		revoked: map[int]int64{},
 	}
 	_, _ = rand.Reader.Read(res.key)
 	// 12小时更新一次密钥
@@ -58,17 +63,57 @@ func (t *TokenManager) Filter(ctx *gin.Context) {
 		claims, err2 = jwt.Verify(t.oldKey, token)
 		if err2 != nil {
 			// 清除头里失效的token
-			ctx.SetCookie("token", "", -1, "", "", false, true)
+			t.SetToken(ctx, "", -1)
 			ctx.AbortWithStatus(http.StatusUnauthorized)
 			_, _ = ctx.Writer.WriteString(err.Error())
 			return
 		}
 	}
+	// 用户会话已被吊销
+	if claims.Type == "user" && t.Revoked(claims) {
+		t.SetToken(ctx, "", -1)
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
 	ctx.Set(FlagClaims, claims)
 	return
 }
 
-// GenToken 生成新的token
+// Revoke 吊销用户当前所有会话，此前签发的token均失效
+// userId: 用户ID
+func (t *TokenManager) Revoke(userId int) {
+	now := time.Now()
+	t.revokedMu.Lock()
+	defer t.revokedMu.Unlock()
+	t.revoked[userId] = now.UnixMilli()
+	// token有效期为10小时，超过1天的吊销记录已无意义
+	for id, at := range t.revoked {
+		if now.Sub(time.UnixMilli(at)) > 24*time.Hour {
+			delete(t.revoked, id)
+		}
+	}
+}
+
+// Revoked 判断token是否在用户会话吊销前签发
+func (t *TokenManager) Revoked(claims *jwt.Claims) bool {
+	t.revokedMu.RLock()
+	defer t.revokedMu.RUnlock()
+	at, ok := t.revoked[claims.Sub]
+	return ok && claims.Iat <= at
+}
+
+// SetToken 设置token Cookie
+// Cookie 禁止脚本访问并设置 SameSite=Lax，请求通过HTTPS或TLCP传输时设置Secure标志，见 IsSecure。
+// maxAge: 有效时间（单位：秒），小于0表示删除
+func (t *TokenManager) SetToken(ctx *gin.Context, token string, maxAge int) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie("token", token, maxAge, "/", "", IsSecure(ctx.Request), true)
+}
+
+// GenToken 生成新的token，未设置签发时间时使用当前时间
 func (t *TokenManager) GenToken(claims *jwt.Claims) string {
+	if claims.Iat == 0 {
+		claims.Iat = time.Now().UnixMilli()
+	}
 	return jwt.New(t.key, claims)
 }
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 	"io"
 	"math/rand"
@@ -11,10 +12,12 @@ import (
 	"note/controller/dto"
 	"note/controller/middle"
 	"note/logg/applog"
+	"note/notify"
 	repo "note/repo"
 	"note/repo/entity"
 	"note/reuint"
 	"note/reuint/jwt"
+	"note/webhook"
 	"os"
 	"path"
 	"path/filepath"
@@ -120,8 +123,31 @@ func (c *NoteController) create(ctx *gin.Context) {
 	// 获取用户信息
 	claimsValue, _ := ctx.Get(middle.FlagClaims)
 	claims := claimsValue.(*jwt.Claims)
-	// 若该用户笔记名已存在，则生成随机数在笔记名后
-	err = repo.DBDao.First(&entity.Note{}, "title = ? AND user_id = ? ", noteCreateDto.Title, claims.Sub).Error
+	// 笔记拥有者，团队空间中创建的笔记属于团队空间
+	note.UserId = claims.Sub
+	// 可在自己的文件夹或可编辑的共享文件夹、团队空间中创建笔记
+	if noteCreateDto.FolderId > 0 {
+		folderRole, err := repo.FolderRepo.Role(claims.Sub, noteCreateDto.FolderId)
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		if folderRole != 0 && folderRole != 2 {
+			ErrIllegal(ctx, "无权限在该文件夹中创建笔记")
+			return
+		}
+		folder, err := repo.FolderRepo.GetById(noteCreateDto.FolderId)
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		if folder.GroupId != 0 {
+			note.UserId = 0
+			note.GroupId = folder.GroupId
+		}
+	}
+	// 若该用户（团队空间）笔记名已存在，则生成随机数在笔记名后
+	err = repo.DBDao.First(&entity.Note{}, "title = ? AND user_id = ? AND group_id = ?", noteCreateDto.Title, note.UserId, note.GroupId).Error
 	if err == gorm.ErrRecordNotFound {
 		note.Title = noteCreateDto.Title
 	} else if err != nil {
@@ -141,7 +167,6 @@ func (c *NoteController) create(ctx *gin.Context) {
 	}
 
 	// 笔记记录 赋值
-	note.UserId = claims.Sub
 	note.TitlePy = str
 	note.Priority = noteCreateDto.Priority
 	note.IsDelete = 0
@@ -180,7 +205,7 @@ func (c *NoteController) create(ctx *gin.Context) {
 		// 生成笔记成员记录
 		noteMember := &entity.NoteMember{}
 		noteMember.Role = 0
-		noteMember.UserId = claims.Sub
+		noteMember.UserId = note.UserId
 		noteMember.NoteId = note.ID
 		noteMember.FolderId = noteCreateDto.FolderId
 		dberr = tx.Create(&noteMember).Error
@@ -195,6 +220,11 @@ func (c *NoteController) create(ctx *gin.Context) {
 		return
 	}
 
+	emitEvent(ctx, &webhook.Event{
+		Name:   webhook.EventNoteCreated,
+		NoteId: note.ID,
+		Data:   map[string]interface{}{"id": note.ID, "title": note.Title, "folderId": noteCreateDto.FolderId, "groupId": note.GroupId},
+	})
 	ctx.JSON(200, note.ID)
 }
 
@@ -271,33 +301,49 @@ func (c *NoteController) info(ctx *gin.Context) {
 	info.ID = note.ID
 
 	// 判断是否拥有笔记权限
-	err = repo.DBDao.First(&noteMember, "user_id = ? AND note_id = ?", claims.Sub, id).Error
-	if err == gorm.ErrRecordNotFound {
+	info.Role, err = repo.NoteMemberRepo.Check(claims.Sub, id)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if info.Role == -1 {
 		ErrIllegal(ctx, "未拥有该笔记权限")
 		return
 	}
+	// 通过用户组获得权限时无笔记成员记录
+	err = repo.DBDao.Where("user_id = ? AND note_id = ?", claims.Sub, id).Limit(1).Find(&noteMember).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
 	}
 	info.NoteGroup = noteMember.NoteGroup
-	info.Role = noteMember.Role
 	info.Remark = noteMember.Remark
 	info.Title = note.Title
 	info.IsDelete = note.IsDelete
 	info.FolderId = noteMember.FolderId
 
-	// 获取笔记拥有者信息
-	err = repo.DBDao.First(&user, "id = ? AND is_delete = 0 ", note.UserId).Error
-	if err == gorm.ErrRecordNotFound {
-		ErrIllegal(ctx, "用户不存在")
-		return
-	}
-	if err != nil {
-		ErrSys(ctx, err)
-		return
+	// 获取笔记拥有者信息，团队空间的笔记为用户组名称
+	if note.GroupId != 0 {
+		var group entity.UserGroup
+		err = repo.DBDao.Select("name").Where("id = ?", note.GroupId).Limit(1).Find(&group).Error
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		info.UserName = group.Name
+		info.GroupId = note.GroupId
+	} else {
+		err = repo.DBDao.First(&user, "id = ? AND is_delete = 0 ", note.UserId).Error
+		if err == gorm.ErrRecordNotFound {
+			ErrIllegal(ctx, "用户不存在")
+			return
+		}
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		info.UserName = user.Name
 	}
-	info.UserName = user.Name
 
 	// 获取文件夹信息
 	info.FolderName, err = repo.FolderRepo.GetFolderFullPath(noteMember.FolderId, claims.Sub, "")
@@ -323,6 +369,7 @@ func (c *NoteController) info(ctx *gin.Context) {
 @apiParam {Integer} page 页数
 @apiParam {Integer} isDelete 是否删除
 @apiParam {Integer} group 用户组ID
+@apiParam {Integer} space 团队空间所属用户组ID，查询该团队空间中的笔记
 @apiParam {Integer} folder 文件夹ID
 
 @apiParamExample {http} 请求示例
@@ -389,6 +436,8 @@ func (c *NoteController) noteList(ctx *gin.Context) {
 	folder, _ := strconv.Atoi(ctx.DefaultQuery("folder", "0"))
 	// 用户组ID
 	group, _ := strconv.Atoi(ctx.DefaultQuery("group", "0"))
+	// 团队空间（用户组ID）
+	space, _ := strconv.Atoi(ctx.DefaultQuery("space", "0"))
 	// 是否删除
 	isDelete, _ := strconv.Atoi(ctx.DefaultQuery("isDelete", "0"))
 	// 页面
@@ -407,54 +456,72 @@ func (c *NoteController) noteList(ctx *gin.Context) {
 	claimsValue, _ := ctx.Get(middle.FlagClaims)
 	claims := claimsValue.(*jwt.Claims)
 
+	// 用户可访问的笔记，包含直接分享、用户组授权以及文件夹继承的权限
+	var access *gorm.DB
+	if claims.Type == "user" {
+		access, err = repo.NoteMemberRepo.Accessible(repo.DBDao, claims.Sub)
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+	}
+
 	query, tx := repo.NewPageQueryFnc(repo.DBDao, &entity.NoteMember{}, page, limit, func(db *gorm.DB) *gorm.DB {
-		// SELECT note_members.id AS id , notes.updated_at , notes.title , note_members.remark, users.`name` AS username,
-		//	notes.tags ,note_members.role   FROM note_members
-		//	LEFT JOIN notes ON notes.id = note_members.note_id
-		//	RIGHT JOIN users on users.id = note_members.user_id
-		db = db.Table("note_members").
-			Select("notes.id AS id ,notes.updated_at,notes.title,note_members.remark,users.`name` AS username, note_members.role , note_members.folder_id").
-			Joins("LEFT JOIN notes ON notes.id = note_members.note_id").Joins("RIGHT JOIN users on users.id = note_members.user_id")
+		// 管理员查询所有已删除的笔记
+		if claims.Type == "admin" {
+			// SELECT note_members.id AS id , notes.updated_at , notes.title , note_members.remark, users.`name` AS username,
+			//	notes.tags ,note_members.role   FROM note_members
+			//	LEFT JOIN notes ON notes.id = note_members.note_id
+			//	RIGHT JOIN users on users.id = note_members.user_id
+			return db.Table("note_members").
+				Select("notes.id AS id ,notes.updated_at,notes.title,note_members.remark,users.`name` AS username, note_members.role , note_members.folder_id").
+				Joins("LEFT JOIN notes ON notes.id = note_members.note_id").Joins("RIGHT JOIN users on users.id = note_members.user_id").
+				Where("note_members.role = 0 AND notes.is_delete = 1").
+				Order("updated_at desc")
+		}
+
+		db = db.Table("(?) AS access", access).
+			Select("notes.id AS id ,notes.updated_at,notes.title,access.remark,users.`name` AS username, access.role , access.folder_id").
+			Joins("INNER JOIN notes ON notes.id = access.note_id").Joins("LEFT JOIN users on users.id = ?", claims.Sub)
 
 		// 前端数据展示排序
 		db = db.Order("updated_at desc")
 
-		// 用户类型
-		if claims.Type == "user" {
-			db = db.Where("note_members.user_id = ? ", claims.Sub)
-		} else if claims.Type == "admin" {
-			db = db.Where("note_members.role = 0 AND notes.is_delete = 1")
-			return db
-		}
-
 		// 关键字查询 - 标题、标题拼音
 		if keyword != "" {
 			db = db.Where("notes.title like ? OR notes.title_py like ?", fmt.Sprintf("%%%s%%", keyword), fmt.Sprintf("%%%s%%", keyword))
 		}
 
-		// 是否删除
+		// 是否删除，包含用户管理的团队空间中删除的笔记
 		if isDelete != 0 {
-			db = db.Where("note_members.user_id = ? AND notes.user_id = ? AND notes.is_delete = ?", claims.Sub, claims.Sub, isDelete)
+			managed := repo.DBDao.Model(&entity.GroupMember{}).Select("belong").Where("user_id = ? AND role = 0", claims.Sub)
+			db = db.Where("(notes.user_id = ? OR notes.group_id IN (?)) AND notes.is_delete = ?", claims.Sub, managed, isDelete)
 			return db
 		} else {
 			db = db.Where("notes.is_delete = 0")
 		}
 
-		// 用户组
+		// 团队空间
+		if space != 0 {
+			db = db.Where("notes.group_id = ?", space)
+		}
+
+		// 用户组，分享给该用户组的笔记
 		if group != 0 {
-			db = db.Where("note_members.group_id = ?", group)
+			db = db.Where("access.note_id IN (?)", repo.DBDao.Model(&entity.NoteGroupGrant{}).Select("note_id").Where("group_id = ?", group))
 		}
 
 		// 用户权限
 		if role == 254 {
-			db = db.Where("note_members.role = 1 OR note_members.role = 2")
+			db = db.Where("access.role = 1 OR access.role = 2")
 		} else if role != 255 {
-			db = db.Where("note_members.role = ? ", role)
+			db = db.Where("access.role = ? ", role)
 		}
 
-		// 文件夹
+		// 文件夹，包含用户放入该文件夹的笔记以及共享文件夹中他人的笔记
 		if folder != 0 {
-			db = db.Where("note_members.folder_id = ?", folder)
+			db = db.Where("access.folder_id = ? OR access.note_id IN (?)", folder,
+				repo.DBDao.Model(&entity.NoteMember{}).Select("note_id").Where("role = 0 AND folder_id = ?", folder))
 		}
 
 		return db
@@ -619,17 +686,13 @@ func (c *NoteController) contentPost(ctx *gin.Context) {
 
 	}
 
-	// 打开一个存在的文件，将原来的内容覆盖掉
+	// 覆盖一个存在的文件
 	filename := filepath.Join(dir.NoteDir, id, note.Filename)
-	// O_WRONLY: 只写, O_TRUNC: 清空文件
-	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, 0666)
-	if err != nil {
+	if _, err = os.Stat(filename); err != nil {
 		ErrIllegal(ctx, "文件打开错误")
 		return
 	}
 
-	defer file.Close()
-
 	// 防止删除文件本身
 	fileContent := fmt.Sprintf("(%s &file=%s)", content, note.Filename)
 
@@ -642,16 +705,11 @@ func (c *NoteController) contentPost(ctx *gin.Context) {
 		}
 	}
 
-	// 带缓冲区的*Writer
-	writer := bufio.NewWriter(file)
-	_, err = writer.WriteString(content)
-	if err != nil {
-		ErrSys(ctx, err)
-		return
-	}
+	// 变更记录的比较基准
+	baseline := changeBaseline(note.ID, filename, autoSave)
 
-	// 将缓冲区中的内容写入到文件里
-	err = writer.Flush()
+	// 先写入临时文件再替换，防止写入中断（如服务关闭）导致笔记内容被截断
+	err = reuint.WriteFileAtomic(filename, []byte(content), 0666)
 	if err != nil {
 		ErrSys(ctx, err)
 		return
@@ -685,12 +743,23 @@ func (c *NoteController) contentPost(ctx *gin.Context) {
 		return
 	}
 
+	// 仅手动保存时记录变更及推送，自动保存过于频繁
+	if !autoSave {
+		recordChange(ctx, note.ID, claims.Sub, baseline, content)
+		emitEvent(ctx, &webhook.Event{
+			Name:   webhook.EventNoteUpdated,
+			NoteId: note.ID,
+			Data:   map[string]interface{}{"id": note.ID, "title": title},
+		})
+	}
 }
 
 /**
 @api {Get} /api/note/content 获取文档内容
 @apiDescription 获取文档内容
 
+无权限时可通过 /api/accessRequest/create 向笔记拥有者申请权限。
+
 @apiName NoteContentGET
 @apiGroup Note
 
@@ -903,13 +972,21 @@ func (c *NoteController) assertGet(ctx *gin.Context) {
 		id = id[:len(id)-1]
 	}
 
-	filename := ctx.Query("file")
+	serveAssert(ctx, id, ctx.Query("file"))
+}
 
+// serveAssert 下载笔记资源文件
+// id: 笔记ID
+// filename: 资源文件名称
+func serveAssert(ctx *gin.Context, id string, filename string) {
 	// 文件路径
-	filePath := filepath.Join(dir.NoteDir, id, filename)
+	noteDir := filepath.Join(dir.NoteDir, id)
+	filePath := filepath.Join(noteDir, filename)
 
-	// 防止用户通过 ../../ 的方式下载到操作系统内的重要文件
-	if !strings.HasPrefix(filePath, filepath.Join(dir.NoteDir, id)) {
+	// 防止用户通过 ../ 的方式下载到其他笔记或操作系统内的重要文件，
+	// 仅比较路径前缀时 ../12/ 可访问笔记1以外的笔记12
+	rel, err := filepath.Rel(noteDir, filePath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
 		ErrIllegal(ctx, "文件路径错误")
 		return
 	}
@@ -998,6 +1075,11 @@ func (c *NoteController) lock(ctx *gin.Context) {
 	// 若无人拥有该锁或拥有该锁的用户为申请者本身
 	if v.UserId == middle.NoLock || v.UserId == lockDto.UserId {
 		editLock.Lock(ctx, lockDto.Id, lockDto.UserId)
+		// 接管用户在别处打开的编辑，别处的页面无法再保存
+		if v.UserId == lockDto.UserId && v.Exp != editLock.Query(lockDto.Id).Exp {
+			content := fmt.Sprintf("笔记《%s》已在别处打开编辑，此前打开的页面无法保存", noteTitle(id))
+			sendNotify(ctx, []int{lockDto.UserId}, notify.TypeLockTaken, "编辑锁接管", content, id)
+		}
 		ctx.JSON(200, "获取到锁")
 	} else {
 		repo.DBDao.Where("id", v.UserId).Find(&user)
@@ -1283,6 +1365,13 @@ func (c *NoteController) delete(ctx *gin.Context) {
 		ErrSys(ctx, err)
 		return
 	}
+	clearBaseline(noteId)
+	notifyEditors(ctx, claims, noteId, notify.TypeNoteDeleted, "笔记删除", "删除")
+	emitEvent(ctx, &webhook.Event{
+		Name:   webhook.EventNoteDeleted,
+		NoteId: noteId,
+		Data:   map[string]interface{}{"id": noteId, "title": noteTitle(noteId)},
+	})
 
 }
 
@@ -1346,6 +1435,12 @@ func (c *NoteController) restore(ctx *gin.Context) {
 		ErrSys(ctx, err)
 		return
 	}
+	notifyEditors(ctx, claims, noteId, notify.TypeNoteRestored, "笔记恢复", "恢复")
+	emitEvent(ctx, &webhook.Event{
+		Name:   webhook.EventNoteRestored,
+		NoteId: noteId,
+		Data:   map[string]interface{}{"id": noteId, "title": noteTitle(noteId)},
+	})
 
 }
 
@@ -1388,8 +1483,52 @@ func (c *NoteController) group(ctx *gin.Context) {
 		ErrIllegal(ctx, "参数解析错误")
 		return
 	}
+	// 可保存至自己的文件夹或可编辑的共享文件夹
+	target := &entity.Folder{}
+	if groupId > 0 {
+		folderRole, err := repo.FolderRepo.Role(claims.Sub, groupId)
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		if folderRole != 0 && folderRole != 2 {
+			ErrIllegal(ctx, "无权限")
+			return
+		}
+		if target, err = repo.FolderRepo.GetById(groupId); err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+	}
+	var note entity.Note
+	err := repo.DBDao.Select("group_id").Where("id = ?", noteId).Limit(1).Find(&note).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	// 团队空间的笔记仅能在该团队空间中移动，由可编辑的成员移动
+	if note.GroupId != 0 || target.GroupId != 0 {
+		if note.GroupId != target.GroupId {
+			ErrIllegal(ctx, "笔记无法在团队空间与其他文件夹之间移动")
+			return
+		}
+		role, err := repo.NoteMemberRepo.Check(claims.Sub, noteId)
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		if role != 0 && role != 2 {
+			ErrIllegal(ctx, "无权限")
+			return
+		}
+		err = repo.DBDao.Model(&entity.NoteMember{}).Where("note_id = ? AND role = 0", noteId).Update("folder_id", groupId).Error
+		if err != nil {
+			ErrSys(ctx, err)
+		}
+		return
+	}
 	// 修改笔记成员组 文件ID
-	err := repo.DBDao.Where("user_id = ? AND note_id = ?", claims.Sub, noteId).Find(&entity.NoteMember{}).Update("folder_id", groupId).Error
+	err = repo.DBDao.Where("user_id = ? AND note_id = ?", claims.Sub, noteId).Find(&entity.NoteMember{}).Update("folder_id", groupId).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
@@ -1493,3 +1632,18 @@ func (c *NoteController) rename(ctx *gin.Context) {
 		}
 	}
 }
+
+// notifyEditors 通知可编辑笔记的用户，不含操作者本人
+// action: 操作名称，如：删除
+func notifyEditors(ctx *gin.Context, claims *jwt.Claims, noteId int, typ, title, action string) {
+	editors, err := repo.NoteMemberRepo.Editors(noteId)
+	if err != nil {
+		middle.Logger(ctx).Warn("查询笔记编辑者失败", zap.Int("noteId", noteId), zap.Error(err))
+		return
+	}
+	if claims.Type == "user" {
+		editors = without(editors, claims.Sub)
+	}
+	content := fmt.Sprintf("%s %s了笔记《%s》", operatorName(claims), action, noteTitle(noteId))
+	sendNotify(ctx, editors, typ, title, content, noteId)
+}
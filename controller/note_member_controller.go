@@ -1,15 +1,19 @@
 package controller
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"go.uber.org/zap"
 	"note/controller/dto"
 	"note/controller/middle"
 	"note/logg/applog"
+	"note/notify"
 	"note/repo"
 	"note/repo/entity"
 	"note/reuint/jwt"
+	"note/webhook"
 	"strconv"
+	"time"
 )
 
 // NewNoteMemberController 创建笔记成员控制器
@@ -34,6 +38,12 @@ type NoteMemberController struct {
 /**
 @api {POST} /api/noteMember/share 分享笔记
 @apiDescription 分享笔记
+
+分享给用户组时用户组成员获得该权限，用户加入或退出用户组后权限随之变化；
+用户同时被直接分享或属于多个用户组时取最高权限。重复分享时修改权限及到期时间。
+
+被分享的用户或用户组成员收到站内通知。
+设置到期时间的分享到期后自动取消，到期前24小时向笔记拥有者发送站内通知提醒。
 @apiName NoteMemberShare
 @apiGroup NoteMember
 
@@ -55,6 +65,7 @@ type NoteMemberController struct {
 	<li>2 - 可编辑 </li>
 
 </ul>
+@apiParam {String} [expiresAt] 到期时间，格式：2006-01-02 15:04:05，为空表示不过期
 
 @apiParamExample {json} 请求示例
 
@@ -62,7 +73,8 @@ type NoteMemberController struct {
 	    "id": 2,
 		"noteId":3,
 		"shareType":"user",
-		"role":1
+		"role":1,
+		"expiresAt":"2026-10-26 18:00:00"
 	}
 
 @apiSuccessExample 成功响应
@@ -77,16 +89,16 @@ HTTP/1.1 400 Bad Request
 // share 分享笔记
 func (c *NoteMemberController) share(ctx *gin.Context) {
 	var info dto.NoteShareDto
-	var reqInfo entity.NoteMember
 
 	err := ctx.BindJSON(&info)
 
 	// 记录日志
 	applog.L(ctx, "分享笔记", map[string]interface{}{
-		"id":     info.Id,
-		"noteId": info.NoteId,
-		"type":   info.ShareType,
-		"role":   info.Role,
+		"id":        info.Id,
+		"noteId":    info.NoteId,
+		"type":      info.ShareType,
+		"role":      info.Role,
+		"expiresAt": info.ExpiresAt,
 	})
 	if err != nil || info.Id <= 0 || info.NoteId <= 0 || info.Role <= 0 || info.ShareType == "" {
 		ErrIllegal(ctx, "参数方法，无法解析")
@@ -96,103 +108,134 @@ func (c *NoteMemberController) share(ctx *gin.Context) {
 	// 获取用户信息
 	claimsValue, _ := ctx.Get(middle.FlagClaims)
 	claims := claimsValue.(*jwt.Claims)
-	// 判断用户是否拥有分享权限
-	userRole, err := repo.NoteMemberRepo.Check(claims.Sub, info.NoteId)
+	exist, err := shareNote(claims.Sub, &info)
+	if illegal, ok := err.(illegalErr); ok {
+		ErrIllegal(ctx, string(illegal))
+		return
+	}
 	if err != nil {
 		ErrSys(ctx, err)
 		return
 	}
+
+	// 通知被分享的用户或用户组成员
+	recipients := []int{info.Id}
+	if info.ShareType == "group" {
+		recipients, err = repo.UserGroupRepo.MemberIds(info.Id)
+		if err != nil {
+			middle.Logger(ctx).Warn("查询用户组成员失败", zap.Int("groupId", info.Id), zap.Error(err))
+		}
+	}
+	content := fmt.Sprintf("%s 将笔记《%s》分享给你，权限：可%s", operatorName(claims), noteTitle(info.NoteId), roleNames[info.Role])
+	if info.ShareType == "group" {
+		content = fmt.Sprintf("%s 将笔记《%s》分享给用户组「%s」，权限：可%s",
+			operatorName(claims), noteTitle(info.NoteId), groupName(info.Id), roleNames[info.Role])
+	}
+	typ, title := notify.TypeShareGranted, "笔记分享"
+	if exist {
+		typ, title = notify.TypeShareChanged, "笔记分享权限变更"
+	}
+	if info.ExpiresAt != "" {
+		content += fmt.Sprintf("，将于 %s 到期", info.ExpiresAt)
+	}
+	sendNotify(ctx, without(recipients, claims.Sub), typ, title, content, info.NoteId)
+	emitNoteShared(ctx, &info)
+}
+
+// emitNoteShared 发布笔记分享事件
+func emitNoteShared(ctx *gin.Context, info *dto.NoteShareDto) {
+	e := &webhook.Event{
+		Name:   webhook.EventNoteShared,
+		NoteId: info.NoteId,
+		Data: map[string]interface{}{
+			"noteId":    info.NoteId,
+			"title":     noteTitle(info.NoteId),
+			"shareType": info.ShareType,
+			"targetId":  info.Id,
+			"role":      info.Role,
+			"expiresAt": info.ExpiresAt,
+		},
+	}
+	if info.ShareType == "group" {
+		e.GroupId = info.Id
+	} else {
+		e.Users = []int{info.Id}
+	}
+	emitEvent(ctx, e)
+}
+
+// shareNote 分享笔记，重复分享时修改权限及到期时间
+// 仅笔记拥有者可分享，分享参数非法时返回 illegalErr 错误。
+// userId: 分享者ID
+// return: 是否为修改已有的分享
+func shareNote(userId int, info *dto.NoteShareDto) (bool, error) {
+	var expiresAt *time.Time
+	if info.ExpiresAt != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", info.ExpiresAt, time.Local)
+		if err != nil {
+			return false, illegalErr("到期时间格式错误")
+		}
+		if t.Before(time.Now()) {
+			return false, illegalErr("到期时间早于当前时间")
+		}
+		expiresAt = &t
+	}
+
+	// 判断用户是否拥有分享权限
+	userRole, err := repo.NoteMemberRepo.Check(userId, info.NoteId)
+	if err != nil {
+		return false, err
+	}
 	// 若用户非该笔记的拥有者，无法分享
 	if userRole != 0 {
-		ErrIllegal(ctx, "无权限")
-		return
+		return false, illegalErr("无权限")
 	}
 
 	// 若用户对自己进行分享，则提示错误
-	if info.Id == claims.Sub {
-		ErrIllegal(ctx, "无法对自己进行分享")
-		return
+	if info.Id == userId {
+		return false, illegalErr("无法对自己进行分享")
 	}
 
 	// 判断是 修改分享权限 还是 新增分享
 	exist, err := repo.NoteMemberRepo.Exist(info.Id, info.NoteId, info.ShareType)
 	if err != nil {
-		ErrSys(ctx, err)
-		return
+		return false, err
 	}
 
 	// 若分享对象为用户
 	if info.ShareType == "user" {
-		// 若用户已存在 则修改其权限
+		// 若用户已存在 则修改其权限及到期时间
 		if exist {
-			err = repo.DBDao.First(&entity.NoteMember{}, "user_id = ? AND note_id = ?", info.Id, info.NoteId).Update("role", info.Role).Error
-			if err != nil {
-				ErrSys(ctx, err)
-				return
-			}
-		} else if !exist { // 不存在则创建记录
-			reqInfo.UserId = info.Id
-			reqInfo.Role = info.Role
-			reqInfo.NoteId = info.NoteId
-			err = repo.DBDao.Create(&reqInfo).Error
-			if err != nil {
-				ErrSys(ctx, err)
-				return
-			}
-		}
-
-	} else if info.ShareType == "group" {
-		// 事务处理
-		err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
-			var users []dto.GroupRoleDto
-			// 查询用户组用户
-			err = tx.Model(&entity.GroupMember{}).Select("user_id,role").Where("belong", info.Id).Find(&users).Error
-			if err != nil {
-				return err
-			}
-			for _, user := range users {
-				// 判断用户是否已被分享
-				exist, err = repo.NoteMemberRepo.Exist(user.UserId, info.NoteId, "user")
-				if err != nil {
-					return err
-				}
-				if exist {
-					// 若用户已存在 则修改其用户组
-					err = tx.First(&entity.NoteMember{}, "user_id = ? AND note_id = ?", user.UserId, info.NoteId).Update("group_id", info.Id).Error
-					if err != nil {
-						return err
-					}
-				} else if !exist {
-					// 不存在则创建记录
-					var tmp entity.NoteMember
-					tmp.GroupId = info.Id
-					tmp.UserId = user.UserId
-					tmp.NoteId = info.NoteId
-					// 若被分享的用户是该用户组的管理员，则赋予其编辑权限
-					if user.Role == 0 {
-						tmp.Role = 2
-					} else {
-						tmp.Role = user.Role
-					}
-					err = repo.DBDao.Create(&tmp).Error
-					if err != nil {
-						return err
-					}
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			ErrSys(ctx, err)
-			return
+			return true, repo.DBDao.Model(&entity.NoteMember{}).Where("user_id = ? AND note_id = ?", info.Id, info.NoteId).
+				Updates(map[string]interface{}{"role": info.Role, "expires_at": expiresAt, "reminded": 0}).Error
 		}
+		// 不存在则创建记录
+		return false, repo.DBDao.Create(&entity.NoteMember{
+			UserId:    info.Id,
+			Role:      info.Role,
+			NoteId:    info.NoteId,
+			ExpiresAt: expiresAt,
+		}).Error
 	}
 
+	found, err := repo.UserGroupRepo.ExistByID(info.Id)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		return false, illegalErr("用户组不存在")
+	}
+	// 用户组成员的权限在访问时根据授权计算，无需为每个成员创建记录
+	grant := entity.NoteGroupGrant{NoteId: info.NoteId, GroupId: info.Id}
+	return exist, repo.DBDao.Where("note_id = ? AND group_id = ?", info.NoteId, info.Id).
+		Assign(map[string]interface{}{"role": info.Role, "expires_at": expiresAt, "reminded": 0}).FirstOrCreate(&grant).Error
 }
 
 /**
 @api {POST} /api/noteMember/cancel 取消分享
 @apiDescription 取消分享
+
+取消用户组分享不影响用户通过直接分享或其他用户组获得的权限。
 @apiName NoteMemberCancel
 @apiGroup NoteMember
 
@@ -278,20 +321,12 @@ func (c *NoteMemberController) cancel(ctx *gin.Context) {
 			return
 		}
 	} else if info.ShareType == "group" {
-		// 将用户本身 用户组 设置为空
-		err = repo.DBDao.First(&entity.NoteMember{}, "group_id = ? AND note_id = ? AND role = 0", info.Id, info.NoteId).Update("group_id", 0).Error
+		// 删除用户组授权，用户通过其他用户组或直接分享获得的权限不受影响
+		err = repo.DBDao.Where("group_id", info.Id).Where("note_id", info.NoteId).Delete(&entity.NoteGroupGrant{}).Error
 		if err != nil {
 			ErrSys(ctx, err)
 			return
 		}
-
-		// 删除其他成员
-		err = repo.DBDao.Where("group_id", info.Id).Where("note_id", info.NoteId).Where("role != 0 ").Delete(&entity.NoteMember{}).Error
-		if err != nil {
-			ErrSys(ctx, err)
-			return
-		}
-
 	}
 }
 
@@ -313,6 +348,7 @@ DELETE /api/noteMember/allUser?id=12
 @apiSuccess (User) {Integer} id 用户ID。
 @apiSuccess (User) {String} name 用户姓名。
 @apiSuccess (User) {Integer} role 用户权限。
+@apiSuccess (User) {String} expiresAt 到期时间，为空表示不过期。
 
 @apiSuccessExample 成功响应
 HTTP/1.1 200 OK
@@ -321,7 +357,8 @@ HTTP/1.1 200 OK
     {
         "id": 118,
 		"name":"墨小菊",
-		"role":2
+		"role":2,
+		"expiresAt":"2026-10-26 18:00:00"
     }
 ]
 
@@ -357,10 +394,11 @@ func (c *NoteMemberController) allUser(ctx *gin.Context) {
 	userList := []dto.UserListDto{}
 
 	err = repo.DBDao.Table("note_members").
-		Select("note_members.user_id AS id , role ,users.`name` ").
+		Select("note_members.user_id AS id , role ,users.`name`, note_members.expires_at ").
 		Joins("LEFT JOIN users on users.id = note_members.user_id").
 		//Where("note_members.group_id = 0").
-		Where("note_members.role != 0 ").Where("note_members.note_id", id).Find(&userList).Error
+		Where("note_members.role != 0 ").Where("note_members.note_id", id).
+		Where("note_members.expires_at IS NULL OR note_members.expires_at > ?", time.Now()).Find(&userList).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
@@ -387,6 +425,8 @@ DELETE /api/noteMember/allGroup?id=12
 @apiSuccess {Group[]} group 用户列表。
 @apiSuccess (Group) {Integer} id 组ID。
 @apiSuccess (Group) {String} name 组名。
+@apiSuccess (Group) {Integer} role 用户组成员权限。
+@apiSuccess (Group) {String} expiresAt 到期时间，为空表示不过期。
 
 @apiSuccessExample 成功响应
 HTTP/1.1 200 OK
@@ -395,6 +435,8 @@ HTTP/1.1 200 OK
     {
         "id": 1,
 		"name":"研发部",
+		"role":1,
+		"expiresAt":null
     }
 ]
 
@@ -429,9 +471,10 @@ func (c *NoteMemberController) allGroup(ctx *gin.Context) {
 
 	groupList := []dto.GroupListDto{}
 
-	err = repo.DBDao.Table("note_members").
-		Select("DISTINCT user_groups.id AS id , user_groups.`name` ").
-		Joins("LEFT JOIN user_groups on user_groups.id = note_members.group_id").Where("note_members.note_id", id).Where("note_members.group_id != 0 ").Find(&groupList).Error
+	err = repo.DBDao.Table("note_group_grants").
+		Select("user_groups.id AS id , user_groups.`name` , note_group_grants.role, note_group_grants.expires_at").
+		Joins("INNER JOIN user_groups on user_groups.id = note_group_grants.group_id").Where("note_group_grants.note_id", id).
+		Where("note_group_grants.expires_at IS NULL OR note_group_grants.expires_at > ?", time.Now()).Find(&groupList).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
@@ -0,0 +1,478 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/mail"
+	"note/metrics"
+	"note/notify"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint/jwt"
+	"strconv"
+	"time"
+)
+
+// 通知推送连接的心跳间隔，防止代理关闭空闲连接
+const notificationHeartbeat = 30 * time.Second
+
+// NewNotificationController 创建站内通知控制器
+func NewNotificationController(router gin.IRouter) *NotificationController {
+	res := &NotificationController{}
+	r := router.Group("/notification")
+	// 获取通知列表
+	r.GET("/list", User, res.list)
+	// 获取未读通知数量
+	r.GET("/unread", User, res.unread)
+	// 标记通知已读
+	r.POST("/read", User, res.read)
+	// 实时推送通知
+	r.GET("/stream", User, res.stream)
+	// 获取邮件通知偏好
+	r.GET("/mailPreference", User, res.mailPreference)
+	// 设置邮件通知偏好
+	r.POST("/mailPreference", User, res.setMailPreference)
+	metrics.GaugeFunc("notification_streams", "当前通知实时推送连接数量", func() float64 {
+		return float64(notify.Subscribers())
+	})
+	return res
+}
+
+// NotificationController 站内通知控制器
+// 通知由分享、用户组成员变更、笔记删除及恢复、编辑锁接管等操作产生，见 notify 包；
+// 分享、权限申请等重要通知按用户的偏好同时发送邮件，见 mail 包。
+type NotificationController struct {
+}
+
+/**
+@api {GET} /api/notification/list 获取通知列表
+@apiDescription 获取当前用户的站内通知，按时间倒序。
+@apiName NotificationList
+@apiGroup Notification
+
+@apiPermission 用户
+
+@apiParam {Integer} [page=1] 页码。
+@apiParam {Integer} [limit=20] 每页数量。
+@apiParam {Integer=0,1} [unread=0] 是否仅查询未读通知。
+
+@apiParamExample 请求示例
+GET /api/notification/list?page=1&limit=20&unread=1
+
+@apiSuccess {Notification[]} records 查询结果列表。
+@apiSuccess {Integer} total 记录总数。
+@apiSuccess {Integer} size 每页显示条数。
+@apiSuccess {Integer} current 当前页。
+@apiSuccess {Integer} pages 总页数。
+@apiSuccess (Notification) {Integer} id 通知ID。
+@apiSuccess (Notification) {String} createdAt 通知时间。
+@apiSuccess (Notification) {String} type 通知类型
+<ul>
+	<li>share_granted - 笔记分享给用户或所在用户组</li>
+	<li>share_changed - 笔记分享的权限被修改</li>
+	<li>share_expiring - 分享即将到期</li>
+	<li>access_request - 收到笔记权限申请</li>
+	<li>access_approved - 权限申请已同意</li>
+	<li>access_denied - 权限申请已拒绝</li>
+	<li>group_added - 被加入用户组</li>
+	<li>group_role_changed - 用户组中的角色被修改</li>
+	<li>group_removed - 被移出用户组</li>
+	<li>note_deleted - 可编辑的笔记被删除</li>
+	<li>note_restored - 可编辑的笔记被恢复</li>
+	<li>lock_taken - 笔记编辑锁被接管</li>
+	<li>digest - 关注的笔记或文件夹的变更摘要，见 /api/watch/setting</li>
+</ul>
+@apiSuccess (Notification) {String} title 标题。
+@apiSuccess (Notification) {String} content 内容。
+@apiSuccess (Notification) {Integer} noteId 关联笔记ID，0表示无。
+@apiSuccess (Notification) {Integer} isRead 是否已读 0 - 未读 1 - 已读。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"records": [{
+		"id": 21,
+		"createdAt": "2026-10-19 10:00:00",
+		"userId": 31,
+		"type": "share_granted",
+		"title": "笔记分享",
+		"content": "王沁涛 将笔记《接口设计》分享给你，权限：可编辑",
+		"noteId": 12,
+		"isRead": 0
+	}],
+	"total": 1,
+	"size": 20,
+	"current": 1,
+	"pages": 1
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// list 获取通知列表
+func (c *NotificationController) list(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	unread := ctx.Query("unread") == "1"
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	query, tx := repo.NewPageQueryFnc(repo.DBDao, &entity.Notification{}, page, limit, func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", claims.Sub).Order("id desc")
+		if unread {
+			db = db.Where("is_read = 0")
+		}
+		return db
+	})
+	records := []entity.Notification{}
+	if err = tx.Find(&records).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	query.Records = records
+	ctx.JSON(200, query)
+}
+
+/**
+@api {GET} /api/notification/unread 获取未读通知数量
+@apiDescription 获取当前用户的未读通知数量。
+@apiName NotificationUnread
+@apiGroup Notification
+
+@apiPermission 用户
+
+@apiSuccess {Integer} count 未读通知数量。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+3
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// unread 获取未读通知数量
+func (c *NotificationController) unread(ctx *gin.Context) {
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	count, err := unreadCount(claims.Sub)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, count)
+}
+
+/**
+@api {POST} /api/notification/read 标记通知已读
+@apiDescription 标记当前用户的通知已读。
+@apiName NotificationRead
+@apiGroup Notification
+
+@apiPermission 用户
+
+@apiParam {Integer[]} [ids] 通知ID列表。
+@apiParam {Boolean} [all=false] 是否标记全部通知已读，为true时忽略ids。
+
+@apiParamExample {json} 请求示例
+{
+	"ids": [21, 22]
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// read 标记通知已读
+func (c *NotificationController) read(ctx *gin.Context) {
+	var info dto.NotificationReadDto
+	err := ctx.BindJSON(&info)
+	if err != nil || (!info.All && len(info.Ids) == 0) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	db := repo.DBDao.Model(&entity.Notification{}).Where("user_id = ? AND is_read = 0", claims.Sub)
+	if !info.All {
+		db = db.Where("id IN ?", info.Ids)
+	}
+	if err = db.Update("is_read", 1).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+}
+
+/**
+@api {GET} /api/notification/stream 实时推送通知
+@apiDescription 以Server-Sent Events方式推送当前用户的新通知。
+
+连接建立后首先以事件"unread"推送未读通知数量，此后每条新通知以事件"notification"推送，
+数据为通知JSON，结构同获取通知列表；每30秒推送一次事件"ping"保持连接。
+连接断开期间的通知可通过获取通知列表补全。
+@apiName NotificationStream
+@apiGroup Notification
+
+@apiPermission 用户
+
+@apiParamExample {get} 请求示例
+GET /api/notification/stream
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: text/event-stream
+
+event:unread
+data:3
+
+event:notification
+data:{"id":21,"createdAt":"2026-10-19 10:00:00","userId":31,"type":"share_granted","title":"笔记分享","content":"王沁涛 将笔记《接口设计》分享给你，权限：可编辑","noteId":12,"isRead":0}
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// stream 实时推送通知
+func (c *NotificationController) stream(ctx *gin.Context) {
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	// 先订阅再查询未读数量，防止遗漏期间产生的通知
+	ch, cancel := notify.Subscribe(claims.Sub)
+	defer cancel()
+	count, err := unreadCount(claims.Sub)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(200)
+	ctx.SSEvent("unread", count)
+	ctx.Writer.Flush()
+
+	heartbeat := time.NewTicker(notificationHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case n := <-ch:
+			data, _ := json.Marshal(n)
+			ctx.SSEvent("notification", string(data))
+		case <-heartbeat.C:
+			ctx.SSEvent("ping", "")
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+/**
+@api {GET} /api/notification/mailPreference 获取邮件通知偏好
+@apiDescription 获取当前用户的邮件通知偏好，未设置时全部事件均发送邮件。
+
+邮件发送至用户信息中的邮箱，用户未设置邮箱或系统未配置邮件服务器（配置项 mail）时不发送。
+@apiName NotificationMailPreference
+@apiGroup Notification
+
+@apiPermission 用户
+
+@apiSuccess {Boolean} enabled 系统是否启用邮件通知。
+@apiSuccess {String} email 接收邮件的邮箱，为空时不发送邮件。
+@apiSuccess {Integer} shareGranted 笔记分享给我或修改分享权限时是否发送邮件 0 - 不发送 1 - 发送。
+@apiSuccess {Integer} accessRequest 收到笔记权限申请时是否发送邮件 0 - 不发送 1 - 发送。
+@apiSuccess {Integer} shareExpiring 分享即将到期时是否发送邮件 0 - 不发送 1 - 发送。
+@apiSuccess {Integer} digest 是否发送关注笔记的变更摘要邮件 0 - 不发送 1 - 发送。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"enabled": true,
+	"email": "wangqintao@example.com",
+	"shareGranted": 1,
+	"accessRequest": 1,
+	"shareExpiring": 0,
+	"digest": 1
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// mailPreference 获取邮件通知偏好
+func (c *NotificationController) mailPreference(ctx *gin.Context) {
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	pref, err := mail.Preference(claims.Sub)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	var user entity.User
+	if err = repo.DBDao.Select("email").Where("id = ?", claims.Sub).Limit(1).Find(&user).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, gin.H{
+		"enabled":       mail.Enabled(),
+		"email":         user.Email,
+		"shareGranted":  pref.ShareGranted,
+		"accessRequest": pref.AccessRequest,
+		"shareExpiring": pref.ShareExpiring,
+		"digest":        pref.Digest,
+	})
+}
+
+/**
+@api {POST} /api/notification/mailPreference 设置邮件通知偏好
+@apiDescription 设置当前用户各类事件是否发送邮件，站内通知不受影响。
+@apiName NotificationSetMailPreference
+@apiGroup Notification
+
+@apiPermission 用户
+
+@apiParam {Integer=0,1} shareGranted 笔记分享给我或修改分享权限时是否发送邮件。
+@apiParam {Integer=0,1} accessRequest 收到笔记权限申请时是否发送邮件。
+@apiParam {Integer=0,1} shareExpiring 分享即将到期时是否发送邮件。
+@apiParam {Integer=0,1} digest 是否发送关注笔记的变更摘要邮件。
+
+@apiParamExample {json} 请求示例
+{
+	"shareGranted": 1,
+	"accessRequest": 1,
+	"shareExpiring": 0,
+	"digest": 1
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// setMailPreference 设置邮件通知偏好
+func (c *NotificationController) setMailPreference(ctx *gin.Context) {
+	var info dto.MailPreferenceDto
+	err := ctx.BindJSON(&info)
+	if err != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	for _, v := range []int{info.ShareGranted, info.AccessRequest, info.ShareExpiring, info.Digest} {
+		if v != 0 && v != 1 {
+			ErrIllegal(ctx, "参数非法，无法解析")
+			return
+		}
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	pref := entity.MailPreference{UserId: claims.Sub}
+	err = repo.DBDao.Where("user_id = ?", claims.Sub).Assign(map[string]interface{}{
+		"share_granted":  info.ShareGranted,
+		"access_request": info.AccessRequest,
+		"share_expiring": info.ShareExpiring,
+		"digest":         info.Digest,
+	}).FirstOrCreate(&pref).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	applog.L(ctx, "设置邮件通知偏好", info)
+}
+
+// unreadCount 用户未读通知数量
+func unreadCount(userId int) (int64, error) {
+	var count int64
+	err := repo.DBDao.Model(&entity.Notification{}).Where("user_id = ? AND is_read = 0", userId).Count(&count).Error
+	return count, err
+}
+
+// sendNotify 发送站内通知，发送失败仅记录日志不影响业务处理
+func sendNotify(ctx *gin.Context, userIds []int, typ, title, content string, noteId int) {
+	if err := notify.SendAll(userIds, typ, title, content, noteId); err != nil {
+		middle.Logger(ctx).Warn("发送站内通知失败", zap.Ints("userIds", userIds), zap.String("type", typ), zap.Error(err))
+	}
+}
+
+// without 排除操作者本人，操作者无需收到自己操作的通知
+func without(userIds []int, userId int) []int {
+	res := make([]int, 0, len(userIds))
+	for _, id := range userIds {
+		if id != userId {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// operatorName 操作者名称，用于通知内容
+func operatorName(claims *jwt.Claims) string {
+	if claims.Type != "user" {
+		return "管理员"
+	}
+	var user entity.User
+	repo.DBDao.Select("name").Where("id = ?", claims.Sub).Limit(1).Find(&user)
+	return user.Name
+}
+
+// noteTitle 获取笔记名称，用于通知内容
+func noteTitle(noteId int) string {
+	var note entity.Note
+	repo.DBDao.Select("title").Where("id = ?", noteId).Limit(1).Find(&note)
+	return note.Title
+}
+
+// groupName 获取用户组名称，用于通知内容
+func groupName(groupId int) string {
+	var group entity.UserGroup
+	repo.DBDao.Select("name").Where("id = ?", groupId).Limit(1).Find(&group)
+	return group.Name
+}
@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"note/controller/dto"
+	"note/logg/applog"
+	"note/repo"
+	"note/repo/entity"
+	"sync/atomic"
+)
+
+// 离职交接默认接收人的工号或用户名，用于配置热加载
+var defaultSuccessor atomic.Value
+
+// offboardUser 离职交接
+// 将用户拥有的笔记及文件夹转移至接收人的交接文件夹下，取消分享给该用户的笔记，
+// 退出所有用户组（用户为拥有者时由接收人接任），禁用账号。
+// 会话吊销不可回滚，调用方须在事务提交成功后调用 revokeSessions 吊销会话。
+// tx: 事务
+// trigger: 触发方式 manual、aync、scim
+func offboardUser(tx *gorm.DB, userId, successorId int, trigger string) (*dto.OffboardReportDto, error) {
+	if userId == successorId {
+		return nil, illegalErr("接收人不能为离职用户本人")
+	}
+	var user, successor entity.User
+	if err := tx.First(&user, "id = ?", userId).Error; err == gorm.ErrRecordNotFound {
+		return nil, illegalErr("离职用户不存在")
+	} else if err != nil {
+		return nil, err
+	}
+	if err := tx.First(&successor, "id = ? AND is_delete = 0", successorId).Error; err == gorm.ErrRecordNotFound {
+		return nil, illegalErr("接收人不存在或已被删除")
+	} else if err != nil {
+		return nil, err
+	}
+	report := &dto.OffboardReportDto{
+		UserId:        user.ID,
+		Username:      user.Username,
+		SuccessorId:   successor.ID,
+		SuccessorName: successor.Username,
+		Trigger:       trigger,
+	}
+
+	// 接收人的交接文件夹，离职用户的文件夹整体移至该文件夹下
+	handover := entity.Folder{UserId: successor.ID, Name: fmt.Sprintf("交接-%s(%s)", user.Name, user.Username)}
+	err := tx.FirstOrCreate(&handover, "user_id = ? AND name = ? AND parent_id = 0", handover.UserId, handover.Name).Error
+	if err != nil {
+		return nil, err
+	}
+	report.FolderId = handover.ID
+	res := tx.Model(&entity.Folder{}).Where("user_id = ? AND parent_id = 0", user.ID).Update("parent_id", handover.ID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	res = tx.Model(&entity.Folder{}).Where("user_id = ?", user.ID).Update("user_id", successor.ID)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	report.Folders = int(res.RowsAffected)
+
+	// 转移拥有的笔记，接收人原有的分享记录由拥有者记录替代
+	var notes []int
+	err = tx.Model(&entity.NoteMember{}).Where("user_id = ? AND role = 0", user.ID).Pluck("note_id", &notes).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) > 0 {
+		if err = tx.Where("user_id = ? AND note_id IN ?", successor.ID, notes).Delete(&entity.NoteMember{}).Error; err != nil {
+			return nil, err
+		}
+		err = tx.Model(&entity.NoteMember{}).Where("user_id = ? AND role = 0 AND folder_id = 0", user.ID).
+			Update("folder_id", handover.ID).Error
+		if err != nil {
+			return nil, err
+		}
+		if err = tx.Model(&entity.NoteMember{}).Where("user_id = ? AND role = 0", user.ID).Update("user_id", successor.ID).Error; err != nil {
+			return nil, err
+		}
+		// 不修改笔记更新时间，防止影响回收站清理
+		if err = tx.Model(&entity.Note{}).Where("id IN ?", notes).UpdateColumn("user_id", successor.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	report.Notes = len(notes)
+
+	// 取消分享给该用户的笔记及文件夹
+	res = tx.Where("user_id = ?", user.ID).Delete(&entity.NoteMember{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	report.SharesRemoved = int(res.RowsAffected)
+	res = tx.Where("user_id = ?", user.ID).Delete(&entity.FolderGrant{})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	report.SharesRemoved += int(res.RowsAffected)
+
+	// 退出用户组，用户为拥有者时由接收人接任
+	var members []entity.GroupMember
+	if err = tx.Where("user_id = ?", user.ID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.Role == 0 {
+			if err = transferGroup(tx, m.Belong, successor.ID); err != nil {
+				return nil, err
+			}
+			report.GroupsTransferred++
+		}
+		if err = repo.UserGroupRepo.RemoveMember(tx, m.Belong, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	report.Groups = len(members)
+
+	if err = tx.Model(&entity.User{}).Where("id = ?", user.ID).Update("is_delete", 1).Error; err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// transferGroup 由接收人接任用户组拥有者
+func transferGroup(tx *gorm.DB, groupId, successorId int) error {
+	res := tx.Model(&entity.GroupMember{}).Where("belong = ? AND user_id = ?", groupId, successorId).Update("role", 0)
+	if res.Error != nil || res.RowsAffected > 0 {
+		return res.Error
+	}
+	return repo.UserGroupRepo.AddMember(tx, groupId, successorId, 0)
+}
+
+// findSuccessor 查找默认接收人
+// return: 接收人用户ID，未配置默认接收人时返回0
+func findSuccessor(tx *gorm.DB) (int, error) {
+	ref, _ := defaultSuccessor.Load().(string)
+	if ref == "" {
+		return 0, nil
+	}
+	var user entity.User
+	err := tx.Select("id").First(&user, "(openid = ? OR username = ?) AND is_delete = 0", ref, ref).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, illegalErr(fmt.Sprintf("默认接收人 %s 不存在或已被删除", ref))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+// autoOffboard 同步离职时自动交接
+// 配置了默认接收人时执行离职交接，否则仅禁用账号。
+// 默认接收人配置错误（如已被删除）时记录告警并仅禁用账号，不影响同步本身。
+// 调用方须在事务提交成功后调用 revokeSessions 吊销会话。
+// trigger: 触发方式 aync、scim
+func autoOffboard(tx *gorm.DB, userId int, trigger string) (*dto.OffboardReportDto, error) {
+	successorId, err := findSuccessor(tx)
+	var illegal illegalErr
+	if errors.As(err, &illegal) {
+		zap.L().Warn("默认接收人配置错误，仅禁用账号", zap.Int("userId", userId),
+			zap.String("trigger", trigger), zap.String("reason", illegal.Error()))
+		successorId, err = 0, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if successorId == 0 || successorId == userId {
+		return nil, tx.Model(&entity.User{}).Where("id = ?", userId).Update("is_delete", 1).Error
+	}
+	return offboardUser(tx, userId, successorId, trigger)
+}
+
+// logOffboard 记录离职交接报告
+func logOffboard(report *dto.OffboardReportDto) {
+	if report == nil {
+		return
+	}
+	zap.L().Info("离职交接", zap.Any("report", report))
+	applog.Anonymous("离职交接", report)
+}
+
+// revokeSessions 吊销用户当前所有会话
+func revokeSessions(userId int) {
+	if tokenManager != nil {
+		tokenManager.Revoke(userId)
+	}
+}
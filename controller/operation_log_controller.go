@@ -4,9 +4,14 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"io"
+	"note/appconf/dir"
 	"note/controller/dto"
+	"note/logg/applog"
 	"note/repo"
 	"note/repo/entity"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -19,6 +24,16 @@ func NewOperationLogController(router gin.IRouter) *OperationLogController {
 	r.GET("/search", Audit, res.search)
 	// 导出日志
 	r.GET("/export", Audit, res.export)
+	// 归档文件列表
+	r.GET("/archive/list", Audit, res.archiveList)
+	// 下载归档文件
+	r.GET("/archive/download", Audit, res.archiveDownload)
+	// 导入归档文件
+	r.POST("/archive/import", Audit, res.archiveImport)
+	// 清除导入的归档日志
+	r.DELETE("/archive/release", Audit, res.archiveRelease)
+	// 搜索导入的归档日志
+	r.GET("/archive/search", Audit, res.archiveSearch)
 	return res
 }
 
@@ -48,6 +63,7 @@ type OperationLogController struct {
 </ul>
 @apiParam {Integer} [opID] 用户ID
 @apiParam {String} [opName] 操作名称,支持模糊搜索
+@apiParam {String} [requestId] 请求ID，用于关联系统日志
 
 @apiParam {Integer} [page=1] 分页查询页码，表示第几页，默认 1。
 @apiParam {Integer} [limit=20] 单页多少数据，默认 20。
@@ -81,7 +97,8 @@ HTTP/1.1 200 OK
 	            "userId": 2,
 	            "name": "test",
 	            "opName": "退出项目",
-	            "opParam": "{}"
+	            "opParam": "{}",
+	            "requestId": "3f2a9c0e5b1d4e7f8a6b2c1d0e9f8a7b"
 	        },
 	    ],
 		"total": 19,
@@ -111,12 +128,12 @@ func (c *OperationLogController) search(ctx *gin.Context) {
 	}
 
 	query, tx := repo.NewPageQueryFnc(repo.DBDao, &entity.Log{}, param.Page, param.Limit, func(db *gorm.DB) *gorm.DB {
-		// SELECT logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,users.id AS user_id, users.name
+		// SELECT logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,logs.request_id,users.id AS user_id, users.name
 		// FROM logs LEFT JOIN users
 		// ON logs.op_id = users.id AND logs.op_type = 2
 		// WHERE
 		db = db.Table("logs").
-			Select("logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,users.id AS user_id, users.name").
+			Select("logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,logs.request_id,users.id AS user_id, users.name").
 			Joins("left join users ON logs.op_id = users.id AND logs.op_type = 2 ")
 		if param.Start != 0 && param.End != 0 {
 			db = db.Where("logs.created_at BETWEEN ? AND ? ", time.UnixMilli(param.Start), time.UnixMilli(param.End))
@@ -131,6 +148,9 @@ func (c *OperationLogController) search(ctx *gin.Context) {
 			db = db.Where("logs.op_name like ?",
 				fmt.Sprintf("%%%s%%", param.OpName))
 		}
+		if param.RequestId != "" {
+			db = db.Where("logs.request_id = ?", param.RequestId)
+		}
 		// 前端数据展示排序
 		db = db.Order("logs.created_at desc")
 		return db
@@ -194,12 +214,12 @@ func (c *OperationLogController) export(ctx *gin.Context) {
 	}
 
 	// 查询条件
-	// SELECT logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,users.id AS user_id, users.name
+	// SELECT logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,logs.request_id,users.id AS user_id, users.name
 	// FROM logs LEFT JOIN users
 	// ON logs.op_id = users.id AND logs.op_type = 2
 	// WHERE
 	db := repo.DBDao.Table("logs").
-		Select("logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,users.id AS user_id, users.name").
+		Select("logs.id AS log_id,logs.created_at,logs.op_type,logs.op_id,logs.op_name,logs.op_param,logs.request_id,users.id AS user_id, users.name").
 		Joins("left join users ON logs.op_id = users.id AND logs.op_type = 2 ")
 	if param.Start != 0 && param.End != 0 {
 		db = db.Where("logs.created_at BETWEEN ? AND ? ", time.UnixMilli(param.Start), time.UnixMilli(param.End))
@@ -260,3 +280,273 @@ func (c *OperationLogController) export(ctx *gin.Context) {
 		}
 	}
 }
+
+/**
+@api {GET} /api/oplog/archive/list 归档文件列表
+@apiDescription 超过保存天数的操作日志会按天归档为gzip压缩的JSON Lines文件，并生成SM3摘要文件，
+该接口返回所有归档文件，按归档日期降序排列。
+@apiName OplogArchiveList
+@apiGroup Oplog
+
+@apiPermission 审计员
+
+@apiParamExample {get} 请求示例
+GET /api/oplog/archive/list
+
+@apiSuccess {Archive[]} Body 归档文件列表。
+@apiSuccess (Archive) {String} name 归档文件名称。
+@apiSuccess (Archive) {String} day 归档日期，格式"YYYY-MM-DD"。
+@apiSuccess (Archive) {Integer} size 文件大小，单位（B）。
+@apiSuccess (Archive) {String} checksum SM3摘要Hex。
+@apiSuccess (Archive) {String} updatedAt 归档时间，格式"YYYY-MM-DD HH:mm:ss"。
+@apiSuccess (Archive) {Integer} imported 已导入的日志条数，0表示未导入。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+	[{
+		"name": "oplog-2024-01-02.jsonl.gz",
+		"day": "2024-01-02",
+		"size": 2048,
+		"checksum": "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0",
+		"updatedAt": "2024-04-02 00:00:01",
+		"imported": 0
+	}]
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// archiveList 归档文件列表
+func (c *OperationLogController) archiveList(ctx *gin.Context) {
+	res, err := applog.ListArchives()
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, res)
+}
+
+/**
+@api {GET} /api/oplog/archive/download 下载归档文件
+@apiDescription 下载归档文件，文件的SM3摘要可通过归档文件列表获取。
+@apiName OplogArchiveDownload
+@apiGroup Oplog
+
+@apiPermission 审计员
+
+@apiParam {String} name 归档文件名称。
+
+@apiParamExample {get} 请求示例
+GET /api/oplog/archive/download?name=oplog-2024-01-02.jsonl.gz
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+归档文件不存在
+*/
+
+// archiveDownload 下载归档文件
+func (c *OperationLogController) archiveDownload(ctx *gin.Context) {
+	name := ctx.Query("name")
+	applog.L(ctx, "下载归档日志", map[string]interface{}{
+		"name": name,
+	})
+	if !applog.ValidArchiveName(name) {
+		ErrIllegal(ctx, "归档文件名称错误")
+		return
+	}
+
+	file, err := os.Open(filepath.Join(dir.ArchiveDir, name))
+	if err != nil {
+		ErrIllegal(ctx, "归档文件不存在")
+		return
+	}
+	defer file.Close()
+
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name))
+	ctx.Header("Content-Type", "application/gzip")
+	if _, err = io.Copy(ctx.Writer, file); err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+}
+
+/**
+@api {POST} /api/oplog/archive/import 导入归档文件
+@apiDescription 校验归档文件的SM3摘要后将其临时导入，导入的日志可通过归档日志搜索接口检索，
+超过24小时后自动清除。重复导入同一归档文件会覆盖之前的导入记录。
+@apiName OplogArchiveImport
+@apiGroup Oplog
+
+@apiPermission 审计员
+
+@apiParam {String} name 归档文件名称。
+
+@apiParamExample {json} 请求示例
+{
+	"name": "oplog-2024-01-02.jsonl.gz"
+}
+
+@apiSuccess {Integer} body 导入的日志条数。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+128
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+归档文件摘要校验失败，文件可能已被篡改
+*/
+
+// archiveImport 导入归档文件
+func (c *OperationLogController) archiveImport(ctx *gin.Context) {
+	var info dto.OplogArchiveImportDto
+	err := ctx.BindJSON(&info)
+	applog.L(ctx, "导入归档日志", map[string]interface{}{
+		"name": info.Name,
+	})
+	if err != nil || !applog.ValidArchiveName(info.Name) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	if _, err = os.Stat(filepath.Join(dir.ArchiveDir, info.Name)); err != nil {
+		ErrIllegal(ctx, "归档文件不存在")
+		return
+	}
+
+	n, err := applog.ImportArchive(info.Name)
+	if err != nil {
+		ErrIllegalE(ctx, err)
+		return
+	}
+	ctx.JSON(200, n)
+}
+
+/**
+@api {DELETE} /api/oplog/archive/release 清除导入的归档日志
+@apiDescription 清除临时导入的归档日志，不影响归档文件本身。
+@apiName OplogArchiveRelease
+@apiGroup Oplog
+
+@apiPermission 审计员
+
+@apiParam {String} name 归档文件名称。
+
+@apiParamExample {delete} 请求示例
+DELETE /api/oplog/archive/release?name=oplog-2024-01-02.jsonl.gz
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// archiveRelease 清除导入的归档日志
+func (c *OperationLogController) archiveRelease(ctx *gin.Context) {
+	name := ctx.Query("name")
+	applog.L(ctx, "清除导入的归档日志", map[string]interface{}{
+		"name": name,
+	})
+	if !applog.ValidArchiveName(name) {
+		ErrIllegal(ctx, "归档文件名称错误")
+		return
+	}
+	if err := applog.ReleaseArchive(name); err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+}
+
+/**
+@api {GET} /api/oplog/archive/search 搜索归档日志
+@apiDescription 搜索临时导入的归档日志，查询条件与操作日志搜索一致。
+@apiName OplogArchiveSearch
+@apiGroup Oplog
+
+@apiPermission 审计员
+
+@apiParam {String} [archive] 归档文件名称，为空表示所有已导入的归档。
+@apiParam {String} [start] 时间段搜索：开始时间
+@apiParam {String} [end] 时间段搜索：截止时间
+@apiParam {Integer=0,1,2,255} [opType=255] 角色类型
+@apiParam {Integer} [opID] 用户ID
+@apiParam {String} [opName] 操作名称,支持模糊搜索
+@apiParam {String} [requestId] 请求ID
+@apiParam {Integer} [page=1] 分页查询页码，表示第几页，默认 1。
+@apiParam {Integer} [limit=20] 单页多少数据，默认 20。
+
+@apiParamExample {get} 请求示例
+GET /api/oplog/archive/search?archive=oplog-2024-01-02.jsonl.gz&opName=删除&page=1&limit=20
+
+@apiSuccess {Log[]} records 查询结果列表，结构与操作日志搜索一致。
+@apiSuccess {Integer} total 记录总数。
+@apiSuccess {Integer} size 每页显示条数，默认 20。
+@apiSuccess {Integer} current 当前页。
+@apiSuccess {Integer} pages 总页数。
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+权限错误
+*/
+
+// archiveSearch 搜索归档日志
+func (c *OperationLogController) archiveSearch(ctx *gin.Context) {
+	var param dto.OplogArchiveSearchDto
+
+	// 设置默认值
+	param.OpType = 255
+	param.Page = 1
+	param.Limit = 20
+
+	if ctx.ShouldBindQuery(&param) != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	query, tx := repo.NewPageQueryFnc(repo.DBDao, &entity.ArchiveLog{}, param.Page, param.Limit, func(db *gorm.DB) *gorm.DB {
+		db = db.Table("archive_logs").
+			Select("archive_logs.log_id AS id,archive_logs.created_at,archive_logs.op_type,archive_logs.op_id,archive_logs.op_name,archive_logs.op_param,archive_logs.request_id,users.id AS user_id, users.name").
+			Joins("left join users ON archive_logs.op_id = users.id AND archive_logs.op_type = 2 ")
+		if param.Archive != "" {
+			db = db.Where("archive_logs.archive = ?", param.Archive)
+		}
+		if param.Start != 0 && param.End != 0 {
+			db = db.Where("archive_logs.created_at BETWEEN ? AND ? ", time.UnixMilli(param.Start), time.UnixMilli(param.End))
+		}
+		if param.OpType != 255 {
+			db = db.Where("archive_logs.op_type = ?", param.OpType)
+		}
+		if param.OpId != 0 {
+			db = db.Where("archive_logs.op_id = ?", param.OpId)
+		}
+		if param.OpName != "" {
+			db = db.Where("archive_logs.op_name like ?",
+				fmt.Sprintf("%%%s%%", param.OpName))
+		}
+		if param.RequestId != "" {
+			db = db.Where("archive_logs.request_id = ?", param.RequestId)
+		}
+		db = db.Order("archive_logs.created_at desc")
+		return db
+	})
+	log := []dto.OplogDto{}
+	if err := tx.Find(&log).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	query.Records = log
+	ctx.JSON(200, query)
+}
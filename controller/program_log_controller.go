@@ -1,13 +1,18 @@
 package controller
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"io"
 	"io/fs"
 	"net/url"
 	"note/appconf/dir"
 	"note/controller/dto"
+	"note/logg"
+	"note/logg/applog"
 	"note/reuint"
 	"os"
 	"path"
@@ -25,6 +30,14 @@ func NewProgramLogController(router gin.IRouter) *ProgramLogController {
 	r.GET("/list", Audit, res.list)
 	// 下载系统日志
 	r.GET("/download", Audit, res.download)
+	// 查询系统日志
+	r.GET("/query", Audit, res.query)
+	// 实时跟踪系统日志
+	r.GET("/tail", Audit, res.tail)
+	// 查询系统日志级别
+	r.GET("/level", Admin, res.level)
+	// 调整系统日志级别
+	r.POST("/level", Admin, res.setLevel)
 	return res
 }
 
@@ -182,3 +195,266 @@ func (c *ProgramLogController) download(ctx *gin.Context) {
 		return
 	}
 }
+
+/**
+@api {GET} /api/log/query 查询系统日志
+@apiDescription 在当前及已切分（gzip压缩）的系统日志文件中搜索，结果按时间降序排列。
+不符合日志格式的行（如堆栈）归属于上一条日志的附加字段。
+@apiName LogQuery
+@apiGroup Log
+
+@apiPermission 审计员
+
+@apiParam {Integer} [start] 开始时间，Unix毫秒时间戳。
+@apiParam {Integer} [end] 截止时间，Unix毫秒时间戳。
+@apiParam {String=debug,info,warn,error,dpanic,panic,fatal} [level=debug] 最低日志级别。
+@apiParam {String} [keyword] 消息或附加字段包含的内容。
+@apiParam {String} [caller] 调用位置包含的内容，如"controller/"。
+@apiParam {Integer} [limit=200] 最大返回条数，最大1000。
+
+@apiParamExample {get} 请求示例
+GET /api/log/query?start=1704038400000&end=1704124800000&level=warn&keyword=失败&limit=100
+
+@apiSuccess {Entry[]} Body 查询结果列表。
+@apiSuccess (Entry) {String} time 日志时间，RFC3339格式。
+@apiSuccess (Entry) {String} level 日志级别。
+@apiSuccess (Entry) {String} caller 调用位置。
+@apiSuccess (Entry) {String} message 日志消息。
+@apiSuccess (Entry) {String} fields 附加字段（JSON）、堆栈等。
+@apiSuccess (Entry) {String} file 所属日志文件。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+	[{
+		"time": "2024-01-01T12:00:00+08:00",
+		"level": "warn",
+		"caller": "applog/alert.go:102",
+		"message": "告警推送失败",
+		"fields": "{\"webhook\": \"http://127.0.0.1/hook\"}",
+		"file": "note.log"
+	}]
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// query 查询系统日志
+func (c *ProgramLogController) query(ctx *gin.Context) {
+	var param dto.ProgramLogQueryDto
+	param.Limit = 200
+	if ctx.ShouldBindQuery(&param) != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	q, err := newLogQuery(param.Level, param.Keyword, param.Caller)
+	if err != nil {
+		ErrIllegal(ctx, "日志级别错误")
+		return
+	}
+	if param.Start != 0 {
+		q.Start = time.UnixMilli(param.Start)
+	}
+	if param.End != 0 {
+		q.End = time.UnixMilli(param.End)
+	}
+	q.Limit = param.Limit
+	if q.Limit <= 0 || q.Limit > 1000 {
+		q.Limit = 1000
+	}
+	applog.L(ctx, "查询系统日志", map[string]interface{}{
+		"start":   param.Start,
+		"end":     param.End,
+		"level":   param.Level,
+		"keyword": param.Keyword,
+		"caller":  param.Caller,
+	})
+
+	res, err := logg.Search(*q)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, res)
+}
+
+/**
+@api {GET} /api/log/tail 实时跟踪系统日志
+@apiDescription 以Server-Sent Events方式推送系统日志新写入的内容，日志文件切分后自动跟踪新文件。
+每条日志以事件"log"推送，数据为日志条目JSON，结构同查询系统日志；不符合日志格式的行（如堆栈）
+仅在上一条日志满足过滤条件时推送，其内容位于message字段。
+@apiName LogTail
+@apiGroup Log
+
+@apiPermission 审计员
+
+@apiParam {String=debug,info,warn,error,dpanic,panic,fatal} [level=debug] 最低日志级别。
+@apiParam {String} [keyword] 消息或附加字段包含的内容。
+@apiParam {String} [caller] 调用位置包含的内容。
+
+@apiParamExample {get} 请求示例
+GET /api/log/tail?level=info
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: text/event-stream
+
+event:log
+data:{"time":"2024-01-01T12:00:00+08:00","level":"info","caller":"note/server.go:30","message":"服务启动","fields":"","file":"note.log"}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+日志级别错误
+*/
+
+// tail 实时跟踪系统日志
+func (c *ProgramLogController) tail(ctx *gin.Context) {
+	q, err := newLogQuery(ctx.Query("level"), ctx.Query("keyword"), ctx.Query("caller"))
+	if err != nil {
+		ErrIllegal(ctx, "日志级别错误")
+		return
+	}
+	applog.L(ctx, "跟踪系统日志", map[string]interface{}{
+		"level":   ctx.Query("level"),
+		"keyword": ctx.Query("keyword"),
+		"caller":  ctx.Query("caller"),
+	})
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(200)
+	ctx.Writer.Flush()
+
+	// 上一条日志是否满足过滤条件，用于判断堆栈等多行内容是否推送
+	matched := false
+	err = logg.Follow(ctx.Request.Context(), time.Second, func(line string) error {
+		e, ok := logg.ParseLine(line)
+		if ok {
+			matched = q.Match(e)
+			e.File = logg.LogFileName
+		} else {
+			e = &logg.Entry{Message: line, File: logg.LogFileName}
+		}
+		if !matched || line == "" {
+			return nil
+		}
+		data, _ := json.Marshal(e)
+		ctx.SSEvent("log", string(data))
+		ctx.Writer.Flush()
+		return ctx.Request.Context().Err()
+	})
+	if err != nil && ctx.Request.Context().Err() == nil {
+		ctx.SSEvent("error", err.Error())
+		ctx.Writer.Flush()
+	}
+}
+
+// newLogQuery 创建系统日志查询条件
+func newLogQuery(level, keyword, caller string) (*logg.Query, error) {
+	q := &logg.Query{Level: zapcore.DebugLevel, Keyword: keyword, Caller: caller}
+	if level != "" {
+		if err := q.Level.UnmarshalText([]byte(level)); err != nil {
+			return nil, err
+		}
+	}
+	return q, nil
+}
+
+/**
+@api {GET} /api/log/level 查询系统日志级别
+@apiDescription 查询系统日志的全局级别及各包的级别覆盖。
+@apiName LogLevel
+@apiGroup Log
+
+@apiPermission 管理员
+
+@apiParamExample {get} 请求示例
+GET /api/log/level
+
+@apiSuccess {String} level 全局日志级别。
+@apiSuccess {PackageLevel[]} packages 包级别覆盖。
+@apiSuccess (PackageLevel) {String} package 包路径，覆盖该包及其子包。
+@apiSuccess (PackageLevel) {String} level 日志级别。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+	{
+		"level": "info",
+		"packages": [{"package": "note/controller", "level": "debug"}]
+	}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+权限错误
+*/
+
+// level 查询系统日志级别
+func (c *ProgramLogController) level(ctx *gin.Context) {
+	ctx.JSON(200, gin.H{
+		"level":    logg.Level.String(),
+		"packages": logg.PackageLevels(),
+	})
+}
+
+/**
+@api {POST} /api/log/level 调整系统日志级别
+@apiDescription 在运行时调整系统日志级别，立即生效，重启后恢复为配置文件的级别。
+指定包路径时仅调整该包及其子包的级别，包的级别优先于全局级别。
+@apiName LogSetLevel
+@apiGroup Log
+
+@apiPermission 管理员
+
+@apiParam {String} [package] 包路径，为空表示调整全局级别，如"note/controller"。
+@apiParam {String=debug,info,warn,error} [level] 日志级别，包路径不为空且级别为空时删除该包的级别覆盖。
+
+@apiParamExample {json} 请求示例
+{
+	"package": "note/controller",
+	"level": "debug"
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+日志级别错误
+*/
+
+// setLevel 调整系统日志级别
+func (c *ProgramLogController) setLevel(ctx *gin.Context) {
+	var info dto.LogLevelDto
+	err := ctx.BindJSON(&info)
+	applog.L(ctx, "调整系统日志级别", map[string]interface{}{
+		"package": info.Package,
+		"level":   info.Level,
+	})
+	if err != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	if info.Package != "" && info.Level == "" {
+		logg.RemovePackageLevel(info.Package)
+		return
+	}
+	var lvl zapcore.Level
+	if err = lvl.UnmarshalText([]byte(info.Level)); err != nil || info.Level == "" {
+		ErrIllegal(ctx, "日志级别错误")
+		return
+	}
+	if info.Package == "" {
+		logg.Level.SetLevel(lvl)
+	} else {
+		logg.SetPackageLevel(info.Package, lvl)
+	}
+	zap.L().Info("系统日志级别已调整", zap.String("package", info.Package), zap.String("level", lvl.String()))
+}
@@ -8,6 +8,7 @@ import (
 	"note/appconf"
 	"note/appconf/dir"
 	"note/controller/middle"
+	"note/metrics"
 )
 
 // token管理器
@@ -20,14 +21,25 @@ var (
 	editLock *middle.EditLock
 )
 
+// 单点登录控制器，用于配置热加载
+var (
+	ssoController *SsoController
+)
+
 // RouteMapping HTTP路由注册
 // r: 路由注册器
 func RouteMapping(r gin.IRouter, cfg *appconf.Application) {
 	// 中间件 - 拦截器 按顺序依次执行
 	tokenManager = middle.NewTokenFilter()
+	defaultSuccessor.Store(cfg.Successor)
 	editLock = middle.NewEditLock()
+	metrics.GaugeFunc("edit_locks", "当前持有的笔记编辑锁数量", func() float64 {
+		return float64(editLock.Count())
+	})
 	r.Use(
+		middle.RequestId,
 		middle.Recovery(),
+		metrics.Middleware,
 		middle.Anonymous,
 		tokenManager.Filter,
 	)
@@ -41,6 +53,9 @@ func RouteMapping(r gin.IRouter, cfg *appconf.Application) {
 		context.Redirect(http.StatusMovedPermanently, "/ui/#/")
 	})
 
+	// 健康检查
+	NewHealthController(r)
+
 	// 所有RestFul接口都以 /api开始
 	r = r.Group("/api")
 	NewLoginController(r)
@@ -53,5 +68,20 @@ func RouteMapping(r gin.IRouter, cfg *appconf.Application) {
 	NewOperationLogController(r)
 	NewRootCertsController(r)
 	NewFolderController(r)
-	NewSsoController(r, cfg.SSOBaseUrl)
+	NewSpaceController(r)
+	NewShareLinkController(r)
+	NewAccessRequestController(r)
+	NewNotificationController(r)
+	NewWebhookController(r)
+	NewWatchController(r)
+	ssoController = NewSsoController(r, cfg)
+	NewAlertController(r)
+}
+
+// Reload 重新加载控制器的配置，目前包括单点登录配置及离职交接默认接收人
+func Reload(cfg *appconf.Application) {
+	defaultSuccessor.Store(cfg.Successor)
+	if ssoController != nil {
+		ssoController.update(cfg)
+	}
 }
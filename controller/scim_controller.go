@@ -0,0 +1,1573 @@
+package controller
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/emmansun/gmsm/sm3"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"net/http"
+	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	scimContentType = "application/scim+json"
+	scimMaxCount    = 200 // 单页最大资源数
+	scimCoreUser    = "urn:ietf:params:scim:schemas:core:2.0:user:"
+	scimEnterprise  = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:user"
+)
+
+// scimUserColumns 用户过滤属性（小写）对应的数据库列
+var scimUserColumns = map[string]string{
+	"id":                           "id",
+	"username":                     "username",
+	"externalid":                   "openid",
+	"displayname":                  "name",
+	"name.formatted":               "name",
+	"title":                        "title",
+	"emails":                       "email",
+	"emails.value":                 "email",
+	"phonenumbers":                 "phone",
+	"phonenumbers.value":           "phone",
+	"active":                       "is_delete",
+	scimEnterprise + ":department": "department_code",
+}
+
+// scimGroupColumns 用户组过滤属性（小写）对应的数据库列
+var scimGroupColumns = map[string]string{
+	"id":          "id",
+	"displayname": "name",
+}
+
+// scimErr SCIM协议错误，响应为SCIM错误格式
+type scimErr struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimErr) Error() string {
+	return e.detail
+}
+
+// ScimController SCIM 2.0 用户供应控制器
+// 用户映射至 entity.User（externalId 为工号，active=false 时禁用），用户组映射至 entity.UserGroup 及其成员。
+type ScimController struct {
+}
+
+// NewScimController 创建SCIM控制器
+// router: SCIM根路径，如 /scim/v2
+func NewScimController(router gin.IRouter) *ScimController {
+	res := &ScimController{}
+	// 服务配置
+	router.GET("/ServiceProviderConfig", res.serviceProviderConfig)
+	router.GET("/ResourceTypes", res.resourceTypes)
+	// 用户
+	router.GET("/Users", res.userList)
+	router.GET("/Users/:id", res.userGet)
+	router.POST("/Users", res.userCreate)
+	router.PUT("/Users/:id", res.userReplace)
+	router.PATCH("/Users/:id", res.userPatch)
+	router.DELETE("/Users/:id", res.userDelete)
+	// 用户组
+	router.GET("/Groups", res.groupList)
+	router.GET("/Groups/:id", res.groupGet)
+	router.POST("/Groups", res.groupCreate)
+	router.PUT("/Groups/:id", res.groupReplace)
+	router.PATCH("/Groups/:id", res.groupPatch)
+	router.DELETE("/Groups/:id", res.groupDelete)
+	return res
+}
+
+// NewScimAuth 创建SCIM认证中间件
+// 使用 Authorization: Bearer <token> 认证，认证失败时返回401并记录日志。
+func NewScimAuth(token string) gin.HandlerFunc {
+	expect := sm3.Sum([]byte(token))
+	return func(ctx *gin.Context) {
+		auth := ctx.GetHeader("Authorization")
+		got := sm3.Sum([]byte(strings.TrimPrefix(auth, "Bearer ")))
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare(got[:], expect[:]) != 1 {
+			logSyncReject(ctx, http.StatusUnauthorized, "SCIM令牌错误")
+			ctx.Header("WWW-Authenticate", `Bearer realm="scim"`)
+			scimError(ctx, &scimErr{status: http.StatusUnauthorized, detail: "认证失败"})
+			return
+		}
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, syncMaxBody)
+	}
+}
+
+/**
+@api {GET} /scim/v2/ServiceProviderConfig SCIM服务配置
+@apiDescription SCIM 2.0 服务配置（RFC 7643 5），运行于sync端口，所有SCIM接口均需使用
+配置项 scim.token 作为令牌认证，请求及响应类型为 application/scim+json。
+@apiName ScimServiceProviderConfig
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{
+    "schemas": ["urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"],
+    "patch": {"supported": true},
+    "bulk": {"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+    "filter": {"supported": true, "maxResults": 200},
+    "changePassword": {"supported": true},
+    "sort": {"supported": false},
+    "etag": {"supported": false},
+    "authenticationSchemes": [{"type": "oauthbearertoken", "name": "OAuth Bearer Token", "primary": true}]
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 401 Unauthorized
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "401", "detail": "认证失败"}
+*/
+
+// serviceProviderConfig SCIM服务配置
+func (c *ScimController) serviceProviderConfig(ctx *gin.Context) {
+	scimJSON(ctx, http.StatusOK, gin.H{
+		"schemas":        []string{dto.ScimSchemaSPConfig},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": scimMaxCount},
+		"changePassword": gin.H{"supported": true},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "使用配置项 scim.token 作为令牌",
+			"primary":     true,
+		}},
+	})
+}
+
+/**
+@api {GET} /scim/v2/ResourceTypes SCIM资源类型
+@apiDescription 支持的SCIM资源类型：User（含企业用户扩展）、Group。
+@apiName ScimResourceTypes
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{
+    "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+    "totalResults": 2,
+    "startIndex": 1,
+    "itemsPerPage": 2,
+    "Resources": [
+        {"id": "User", "name": "User", "endpoint": "/Users", "schema": "urn:ietf:params:scim:schemas:core:2.0:User", ...},
+        {"id": "Group", "name": "Group", "endpoint": "/Groups", "schema": "urn:ietf:params:scim:schemas:core:2.0:Group", ...}
+    ]
+}
+*/
+
+// resourceTypes SCIM资源类型
+func (c *ScimController) resourceTypes(ctx *gin.Context) {
+	res := []gin.H{
+		{
+			"schemas":  []string{dto.ScimSchemaResource},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   dto.ScimSchemaUser,
+			"schemaExtensions": []gin.H{
+				{"schema": dto.ScimSchemaEnterprise, "required": false},
+			},
+		},
+		{
+			"schemas":  []string{dto.ScimSchemaResource},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   dto.ScimSchemaGroup,
+		},
+	}
+	scimJSON(ctx, http.StatusOK, dto.ScimListResponse{
+		Schemas:      []string{dto.ScimSchemaList},
+		TotalResults: int64(len(res)),
+		StartIndex:   1,
+		ItemsPerPage: len(res),
+		Resources:    res,
+	})
+}
+
+/**
+@api {GET} /scim/v2/Users SCIM查询用户
+@apiDescription 查询用户（RFC 7644 3.4.2），包括已禁用（active=false）的用户。
+过滤表达式仅支持以 and 连接的 eq、ne、co、sw、ew、pr 比较，可过滤的属性：
+id、userName、externalId、displayName、name.formatted、title、emails[.value]、phoneNumbers[.value]、active、
+urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department。
+@apiName ScimUserList
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiParam {String} [filter] 过滤表达式，如：userName eq "21011"。
+@apiParam {Integer} [startIndex=1] 起始序号，从1开始。
+@apiParam {Integer} [count=100] 单页资源数，最大200。
+
+@apiParamExample {HTTP} 请求示例
+GET /scim/v2/Users?filter=externalId%20eq%20%2221011%22&startIndex=1&count=10
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{
+    "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+    "totalResults": 1,
+    "startIndex": 1,
+    "itemsPerPage": 1,
+    "Resources": [
+        {
+            "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+            "id": "15",
+            "externalId": "21011",
+            "userName": "21011",
+            "name": {"formatted": "张三"},
+            "displayName": "张三",
+            "active": true,
+            "emails": [{"value": "123456@mail.com", "type": "work", "primary": true}],
+            "meta": {"resourceType": "User", "created": "2026-10-19T09:00:00+08:00", "location": "https://host:8015/scim/v2/Users/15"}
+        }
+    ]
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "400", "scimType": "invalidFilter", "detail": "不支持的运算符 \"gt\""}
+*/
+
+// userList 查询用户
+func (c *ScimController) userList(ctx *gin.Context) {
+	start, count := scimPage(ctx)
+	db, err := scimWhere(repo.DBDao.Model(&entity.User{}), ctx.Query("filter"), scimUserColumns)
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var total int64
+	if err = db.Count(&total).Error; err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var users []entity.User
+	if count > 0 {
+		if err = db.Order("id").Offset(start - 1).Limit(count).Find(&users).Error; err != nil {
+			scimError(ctx, err)
+			return
+		}
+	}
+	resources := make([]dto.ScimUser, 0, len(users))
+	for i := range users {
+		resources = append(resources, toScimUser(ctx, &users[i]))
+	}
+	scimJSON(ctx, http.StatusOK, dto.ScimListResponse{
+		Schemas:      []string{dto.ScimSchemaList},
+		TotalResults: total,
+		StartIndex:   start,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+/**
+@api {GET} /scim/v2/Users/:id SCIM获取用户
+@apiName ScimUserGet
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiParam {String} id 用户ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{
+    "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+    "id": "15",
+    "externalId": "21011",
+    "userName": "21011",
+    "name": {"formatted": "张三"},
+    "displayName": "张三",
+    "title": "工程师",
+    "active": true,
+    "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "D0102", "manager": {"value": "20001"}},
+    "meta": {"resourceType": "User", "created": "2026-10-19T09:00:00+08:00", "location": "https://host:8015/scim/v2/Users/15"}
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 404 Not Found
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "404", "detail": "用户不存在"}
+*/
+
+// userGet 获取用户
+func (c *ScimController) userGet(ctx *gin.Context) {
+	user, err := scimFindUser(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	scimJSON(ctx, http.StatusOK, toScimUser(ctx, user))
+}
+
+/**
+@api {POST} /scim/v2/Users SCIM创建用户
+@apiDescription 创建用户，userName 或 externalId 已存在时返回409。
+externalId 对应工号（openid），为空时使用 userName；未设置口令时生成随机口令，用户需通过单点登录访问。
+@apiName ScimUserCreate
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+@apiHeader {String} Content-Type application/scim+json
+
+@apiParam {String} userName 用户名。
+@apiParam {String} [externalId] 工号。
+@apiParam {Object} [name] 姓名，displayName 为空时使用 name.formatted 或 familyName + givenName。
+@apiParam {String} [displayName] 姓名。
+@apiParam {String} [title] 职务。
+@apiParam {Boolean} [active=true] 是否可用。
+@apiParam {String} [password] 口令。
+@apiParam {Object[]} [emails] 邮箱，使用主要邮箱或第一个邮箱。
+@apiParam {Object[]} [phoneNumbers] 手机号，使用主要手机号或第一个手机号。
+@apiParam {Object} [urn:ietf:params:scim:schemas:extension:enterprise:2.0:User] 企业用户扩展，支持 department（部门编号）、manager.value（直属上级工号）。
+
+@apiParamExample {json} 请求示例
+{
+    "schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+    "userName": "21011",
+    "externalId": "21011",
+    "displayName": "张三",
+    "active": true,
+    "emails": [{"value": "123456@mail.com", "type": "work", "primary": true}]
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 201 Created
+Content-Type: application/scim+json
+Location: https://host:8015/scim/v2/Users/15
+
+{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "15", ...}
+
+@apiErrorExample 失败响应
+HTTP/1.1 409 Conflict
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "409", "scimType": "uniqueness", "detail": "用户名或工号已存在"}
+*/
+
+// userCreate 创建用户
+func (c *ScimController) userCreate(ctx *gin.Context) {
+	var in dto.ScimUser
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		scimError(ctx, &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "参数非法，无法解析"})
+		return
+	}
+	if in.ExternalId == "" {
+		in.ExternalId = in.UserName
+	}
+	if in.Password == "" {
+		// 未设置口令时生成随机口令，用户通过单点登录访问
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			scimError(ctx, err)
+			return
+		}
+		in.Password = hex.EncodeToString(b)
+	}
+	if in.Active == nil {
+		active := true
+		in.Active = &active
+	}
+
+	var user entity.User
+	if err := applyScimUser(&user, &in); err != nil {
+		scimError(ctx, err)
+		return
+	}
+	if err := scimUnique(&user); err != nil {
+		scimError(ctx, err)
+		return
+	}
+	if err := repo.DBDao.Create(&user).Error; err != nil {
+		scimError(ctx, err)
+		return
+	}
+	applog.Anonymous("SCIM创建用户", map[string]interface{}{"id": user.ID, "userName": user.Username, "externalId": user.Openid})
+	emitUserSynced(&user, "scim")
+
+	res := toScimUser(ctx, &user)
+	ctx.Header("Location", res.Meta.Location)
+	scimJSON(ctx, http.StatusCreated, res)
+}
+
+/**
+@api {PUT} /scim/v2/Users/:id SCIM替换用户
+@apiDescription 使用请求中的属性替换用户属性，未提供的属性将被清空，externalId 及口令未提供时保持不变。
+@apiName ScimUserReplace
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+@apiHeader {String} Content-Type application/scim+json
+
+@apiParam {String} id 用户ID。
+@apiParam {Object} body 用户资源，字段同创建用户。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "15", ...}
+
+@apiErrorExample 失败响应
+HTTP/1.1 404 Not Found
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "404", "detail": "用户不存在"}
+*/
+
+// userReplace 替换用户
+func (c *ScimController) userReplace(ctx *gin.Context) {
+	user, err := scimFindUser(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var in dto.ScimUser
+	if err = ctx.ShouldBindJSON(&in); err != nil {
+		scimError(ctx, &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "参数非法，无法解析"})
+		return
+	}
+	if in.ExternalId == "" {
+		in.ExternalId = user.Openid
+	}
+	if in.Active == nil {
+		active := user.IsDelete == 0
+		in.Active = &active
+	}
+	c.saveUser(ctx, user, &in)
+}
+
+/**
+@api {PATCH} /scim/v2/Users/:id SCIM修改用户
+@apiDescription 部分修改用户（RFC 7644 3.5.2），操作类型支持 add、replace、remove（不区分大小写），
+path 为空时 value 为属性对象。支持的属性：userName、externalId、displayName、name、name.formatted、
+name.givenName、name.familyName、title、active、password、emails、emails[type eq "work"].value、
+phoneNumbers、phoneNumbers[type eq "work"].value 以及企业用户扩展的 department、manager。
+@apiName ScimUserPatch
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+@apiHeader {String} Content-Type application/scim+json
+
+@apiParam {String} id 用户ID。
+@apiParam {Object[]} Operations 修改操作。
+
+@apiParamExample {json} 禁用用户
+{
+    "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+    "Operations": [
+        {"op": "replace", "path": "active", "value": false}
+    ]
+}
+@apiParamExample {json} 修改多个属性
+{
+    "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+    "Operations": [
+        {"op": "Replace", "value": {"displayName": "张三", "title": "高级工程师"}},
+        {"op": "Replace", "path": "emails[type eq \"work\"].value", "value": "zhangsan@mail.com"}
+    ]
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "id": "15", "active": false, ...}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "400", "scimType": "invalidPath", "detail": "不支持的属性 \"nickName\""}
+*/
+
+// userPatch 部分修改用户
+func (c *ScimController) userPatch(ctx *gin.Context) {
+	user, err := scimFindUser(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var in dto.ScimPatchDto
+	if err = ctx.ShouldBindJSON(&in); err != nil {
+		scimError(ctx, &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "参数非法，无法解析"})
+		return
+	}
+	res := toScimUser(ctx, user)
+	for _, op := range in.Operations {
+		if err = patchScimUser(&res, op.Op, op.Path, op.Value); err != nil {
+			scimError(ctx, err)
+			return
+		}
+	}
+	c.saveUser(ctx, user, &res)
+}
+
+// saveUser 保存修改后的用户，用户被禁用时自动离职交接
+func (c *ScimController) saveUser(ctx *gin.Context, user *entity.User, in *dto.ScimUser) {
+	active := user.IsDelete == 0
+	if err := applyScimUser(user, in); err != nil {
+		scimError(ctx, err)
+		return
+	}
+	if err := scimUnique(user); err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var report *dto.OffboardReportDto
+	err := repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if active && user.IsDelete != 0 {
+			var err error
+			report, err = autoOffboard(tx, user.ID, "scim")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		scimError(ctx, scimIllegal(err))
+		return
+	}
+	if active && user.IsDelete != 0 {
+		revokeSessions(user.ID)
+	}
+	applog.Anonymous("SCIM修改用户", map[string]interface{}{"id": user.ID, "userName": user.Username, "active": user.IsDelete == 0})
+	logOffboard(report)
+	emitUserSynced(user, "scim")
+	scimJSON(ctx, http.StatusOK, toScimUser(ctx, user))
+}
+
+/**
+@api {DELETE} /scim/v2/Users/:id SCIM删除用户
+@apiDescription 禁用用户，与设置 active=false 相同，配置了默认接收人（配置项 successor）时自动离职交接，否则用户的笔记等数据保留。
+@apiName ScimUserDelete
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiParam {String} id 用户ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 204 No Content
+
+@apiErrorExample 失败响应
+HTTP/1.1 404 Not Found
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "404", "detail": "用户不存在"}
+*/
+
+// userDelete 删除（禁用）用户
+func (c *ScimController) userDelete(ctx *gin.Context) {
+	user, err := scimFindUser(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	active := user.IsDelete == 0
+	var report *dto.OffboardReportDto
+	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).Where("id = ?", user.ID).Update("is_delete", 1).Error; err != nil {
+			return err
+		}
+		if active {
+			var err error
+			report, err = autoOffboard(tx, user.ID, "scim")
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		scimError(ctx, scimIllegal(err))
+		return
+	}
+	if active {
+		revokeSessions(user.ID)
+	}
+	applog.Anonymous("SCIM删除用户", map[string]interface{}{"id": user.ID, "userName": user.Username})
+	logOffboard(report)
+	user.IsDelete = 1
+	emitUserSynced(user, "scim")
+	ctx.Status(http.StatusNoContent)
+}
+
+/**
+@api {GET} /scim/v2/Groups SCIM查询用户组
+@apiDescription 查询用户组，可过滤的属性：id、displayName，
+设置 excludedAttributes=members 时不返回成员。
+@apiName ScimGroupList
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiParam {String} [filter] 过滤表达式，如：displayName eq "研发一部"。
+@apiParam {Integer} [startIndex=1] 起始序号，从1开始。
+@apiParam {Integer} [count=100] 单页资源数，最大200。
+@apiParam {String} [excludedAttributes] 不返回的属性，仅支持 members。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{
+    "schemas": ["urn:ietf:params:scim:api:messages:2.0:ListResponse"],
+    "totalResults": 1,
+    "startIndex": 1,
+    "itemsPerPage": 1,
+    "Resources": [
+        {
+            "schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+            "id": "3",
+            "displayName": "研发一部",
+            "members": [{"value": "15", "display": "张三", "$ref": "https://host:8015/scim/v2/Users/15"}],
+            "meta": {"resourceType": "Group", "created": "2026-10-19T09:00:00+08:00", "location": "https://host:8015/scim/v2/Groups/3"}
+        }
+    ]
+}
+*/
+
+// groupList 查询用户组
+func (c *ScimController) groupList(ctx *gin.Context) {
+	start, count := scimPage(ctx)
+	db, err := scimWhere(repo.DBDao.Model(&entity.UserGroup{}), ctx.Query("filter"), scimGroupColumns)
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var total int64
+	if err = db.Count(&total).Error; err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var groups []entity.UserGroup
+	if count > 0 {
+		if err = db.Order("id").Offset(start - 1).Limit(count).Find(&groups).Error; err != nil {
+			scimError(ctx, err)
+			return
+		}
+	}
+	withMembers := !strings.Contains(strings.ToLower(ctx.Query("excludedAttributes")), "members")
+	resources, err := toScimGroups(ctx, groups, withMembers)
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	scimJSON(ctx, http.StatusOK, dto.ScimListResponse{
+		Schemas:      []string{dto.ScimSchemaList},
+		TotalResults: total,
+		StartIndex:   start,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+/**
+@api {GET} /scim/v2/Groups/:id SCIM获取用户组
+@apiName ScimGroupGet
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiParam {String} id 用户组ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "id": "3", "displayName": "研发一部", "members": [...], "meta": {...}}
+
+@apiErrorExample 失败响应
+HTTP/1.1 404 Not Found
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "404", "detail": "用户组不存在"}
+*/
+
+// groupGet 获取用户组
+func (c *ScimController) groupGet(ctx *gin.Context) {
+	group, err := scimFindGroup(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	c.groupResponse(ctx, http.StatusOK, group)
+}
+
+/**
+@api {POST} /scim/v2/Groups SCIM创建用户组
+@apiDescription 创建用户组，名称已存在时返回409，成员以普通用户角色加入用户组。
+@apiName ScimGroupCreate
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+@apiHeader {String} Content-Type application/scim+json
+
+@apiParam {String} displayName 用户组名称。
+@apiParam {Object[]} [members] 成员，value 为用户ID。
+
+@apiParamExample {json} 请求示例
+{
+    "schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+    "displayName": "研发一部",
+    "members": [{"value": "15"}]
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 201 Created
+Content-Type: application/scim+json
+Location: https://host:8015/scim/v2/Groups/3
+
+{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "id": "3", "displayName": "研发一部", ...}
+
+@apiErrorExample 失败响应
+HTTP/1.1 409 Conflict
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "409", "scimType": "uniqueness", "detail": "用户组已经存在"}
+*/
+
+// groupCreate 创建用户组
+func (c *ScimController) groupCreate(ctx *gin.Context) {
+	var in dto.ScimGroup
+	if err := ctx.ShouldBindJSON(&in); err != nil {
+		scimError(ctx, &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "参数非法，无法解析"})
+		return
+	}
+	members, err := scimMemberIds(in.Members)
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var group entity.UserGroup
+	if err = c.saveGroup(&group, in.DisplayName, members); err != nil {
+		scimError(ctx, err)
+		return
+	}
+	applog.Anonymous("SCIM创建用户组", map[string]interface{}{"id": group.ID, "name": group.Name, "members": len(members)})
+	ctx.Header("Location", scimLocation(ctx, "Groups", group.ID))
+	c.groupResponse(ctx, http.StatusCreated, &group)
+}
+
+/**
+@api {PUT} /scim/v2/Groups/:id SCIM替换用户组
+@apiDescription 替换用户组名称及成员，不在成员列表中的用户将被移出用户组，新成员以普通用户角色加入。
+@apiName ScimGroupReplace
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+@apiHeader {String} Content-Type application/scim+json
+
+@apiParam {String} id 用户组ID。
+@apiParam {String} displayName 用户组名称。
+@apiParam {Object[]} [members] 成员，value 为用户ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "id": "3", "displayName": "研发一部", ...}
+*/
+
+// groupReplace 替换用户组
+func (c *ScimController) groupReplace(ctx *gin.Context) {
+	group, err := scimFindGroup(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var in dto.ScimGroup
+	if err = ctx.ShouldBindJSON(&in); err != nil {
+		scimError(ctx, &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "参数非法，无法解析"})
+		return
+	}
+	members, err := scimMemberIds(in.Members)
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	if err = c.saveGroup(group, in.DisplayName, members); err != nil {
+		scimError(ctx, err)
+		return
+	}
+	applog.Anonymous("SCIM修改用户组", map[string]interface{}{"id": group.ID, "name": group.Name, "members": len(members)})
+	c.groupResponse(ctx, http.StatusOK, group)
+}
+
+/**
+@api {PATCH} /scim/v2/Groups/:id SCIM修改用户组
+@apiDescription 部分修改用户组，支持修改名称（displayName）及添加、移除、替换成员（members），
+移除指定成员时 path 为 members[value eq "用户ID"] 或 path 为 members 且 value 为成员列表。
+@apiName ScimGroupPatch
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+@apiHeader {String} Content-Type application/scim+json
+
+@apiParam {String} id 用户组ID。
+@apiParam {Object[]} Operations 修改操作。
+
+@apiParamExample {json} 请求示例
+{
+    "schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+    "Operations": [
+        {"op": "add", "path": "members", "value": [{"value": "16"}]},
+        {"op": "remove", "path": "members[value eq \"15\"]"},
+        {"op": "replace", "path": "displayName", "value": "研发二部"}
+    ]
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"], "id": "3", "displayName": "研发二部", ...}
+*/
+
+// groupPatch 部分修改用户组
+func (c *ScimController) groupPatch(ctx *gin.Context) {
+	group, err := scimFindGroup(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	var in dto.ScimPatchDto
+	if err = ctx.ShouldBindJSON(&in); err != nil {
+		scimError(ctx, &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "参数非法，无法解析"})
+		return
+	}
+	var current []int
+	if err = repo.DBDao.Model(&entity.GroupMember{}).Where("belong = ?", group.ID).Pluck("user_id", &current).Error; err != nil {
+		scimError(ctx, err)
+		return
+	}
+	members := map[int]bool{}
+	for _, id := range current {
+		members[id] = true
+	}
+	name := group.Name
+	for _, op := range in.Operations {
+		if err = patchScimGroup(&name, members, op.Op, op.Path, op.Value); err != nil {
+			scimError(ctx, err)
+			return
+		}
+	}
+	ids := make([]int, 0, len(members))
+	for id := range members {
+		ids = append(ids, id)
+	}
+	if err = c.saveGroup(group, name, ids); err != nil {
+		scimError(ctx, err)
+		return
+	}
+	applog.Anonymous("SCIM修改用户组", map[string]interface{}{"id": group.ID, "name": group.Name, "members": len(ids)})
+	c.groupResponse(ctx, http.StatusOK, group)
+}
+
+/**
+@api {DELETE} /scim/v2/Groups/:id SCIM删除用户组
+@apiDescription 删除用户组及其成员，同时取消分享给该用户组的笔记。
+用户组拥有团队空间（含未删除的团队空间笔记）时不可删除，返回409，需先转移或删除团队空间。
+@apiName ScimGroupDelete
+@apiGroup Scim
+
+@apiHeader {String} Authorization Bearer {scim.token}
+
+@apiParam {String} id 用户组ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 204 No Content
+
+@apiErrorExample 失败响应
+HTTP/1.1 409 Conflict
+Content-Type: application/scim+json
+
+{"schemas": ["urn:ietf:params:scim:api:messages:2.0:Error"], "status": "409", "detail": "用户组拥有团队空间，请先转移或删除团队空间"}
+*/
+
+// groupDelete 删除用户组
+func (c *ScimController) groupDelete(ctx *gin.Context) {
+	group, err := scimFindGroup(ctx.Param("id"))
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		// 团队空间的文件夹及笔记归属于用户组，删除用户组后无人可访问，需先转移或删除团队空间
+		var folders, notes int64
+		if err := tx.Model(&entity.Folder{}).Where("group_id = ?", group.ID).Count(&folders).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entity.Note{}).Where("group_id = ? AND is_delete = 0", group.ID).Count(&notes).Error; err != nil {
+			return err
+		}
+		if folders+notes > 0 {
+			return &scimErr{status: http.StatusConflict, detail: "用户组拥有团队空间，请先转移或删除团队空间"}
+		}
+		if err := tx.Where("group_id = ?", group.ID).Delete(&entity.NoteGroupGrant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", group.ID).Delete(&entity.FolderGrant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("belong = ?", group.ID).Delete(&entity.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	applog.Anonymous("SCIM删除用户组", map[string]interface{}{"id": group.ID, "name": group.Name})
+	ctx.Status(http.StatusNoContent)
+}
+
+// saveGroup 保存用户组名称及成员
+// 名称为空或与其他用户组重复时返回错误，成员不存在时返回错误，新成员以普通用户角色加入。
+func (c *ScimController) saveGroup(group *entity.UserGroup, name string, members []int) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "用户组名称为空"}
+	}
+	if name != group.Name {
+		var count int64
+		if err := repo.DBDao.Model(&entity.UserGroup{}).Where("name = ? AND id <> ?", name, group.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return &scimErr{status: http.StatusConflict, scimType: "uniqueness", detail: "用户组已经存在"}
+		}
+		py, err := reuint.PinyinConversion(name)
+		if err != nil {
+			return err
+		}
+		group.Name, group.NamePy = name, py
+	}
+	if len(members) > 0 {
+		var count int64
+		if err := repo.DBDao.Model(&entity.User{}).Where("id IN ?", members).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(members) {
+			return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "成员不存在"}
+		}
+	}
+
+	return repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(group).Error; err != nil {
+			return err
+		}
+		var current []int
+		if err := tx.Model(&entity.GroupMember{}).Where("belong = ?", group.ID).Pluck("user_id", &current).Error; err != nil {
+			return err
+		}
+		keep := map[int]bool{}
+		for _, id := range members {
+			keep[id] = true
+		}
+		for _, id := range current {
+			if keep[id] {
+				delete(keep, id)
+				continue
+			}
+			if err := repo.UserGroupRepo.RemoveMember(tx, group.ID, id); err != nil {
+				return err
+			}
+		}
+		for _, id := range members {
+			if !keep[id] {
+				continue
+			}
+			if err := repo.UserGroupRepo.AddMember(tx, group.ID, id, 1); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// groupResponse 响应用户组资源
+func (c *ScimController) groupResponse(ctx *gin.Context, status int, group *entity.UserGroup) {
+	res, err := toScimGroups(ctx, []entity.UserGroup{*group}, true)
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	scimJSON(ctx, status, res[0])
+}
+
+// scimJSON 以SCIM媒体类型响应
+func scimJSON(ctx *gin.Context, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		scimError(ctx, err)
+		return
+	}
+	ctx.Data(status, scimContentType, b)
+}
+
+// scimError 以SCIM错误格式响应，非SCIM协议错误记录日志并响应500
+func scimError(ctx *gin.Context, err error) {
+	e, ok := err.(*scimErr)
+	if !ok {
+		middle.Logger(ctx).Error("系统内部错误",
+			zap.String("errTyp", "Inn"),
+			zap.Error(err),
+			zap.String("caller", caller()))
+		e = &scimErr{status: http.StatusInternalServerError, detail: "系统内部错误"}
+	}
+	b, _ := json.Marshal(dto.ScimErrorDto{
+		Schemas:  []string{dto.ScimSchemaError},
+		Status:   strconv.Itoa(e.status),
+		ScimType: e.scimType,
+		Detail:   e.detail,
+	})
+	ctx.Abort()
+	ctx.Data(e.status, scimContentType, b)
+}
+
+// scimIllegal 将请求数据非法错误转换为SCIM错误
+func scimIllegal(err error) error {
+	if illegal, ok := err.(illegalErr); ok {
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: string(illegal)}
+	}
+	return err
+}
+
+// scimPage 解析分页参数
+// return: 起始序号（从1开始）、单页资源数
+func scimPage(ctx *gin.Context) (int, int) {
+	start, err := strconv.Atoi(ctx.Query("startIndex"))
+	if err != nil || start < 1 {
+		start = 1
+	}
+	count, err := strconv.Atoi(ctx.DefaultQuery("count", "100"))
+	if err != nil || count > scimMaxCount {
+		count = scimMaxCount
+	}
+	if count < 0 {
+		count = 0
+	}
+	return start, count
+}
+
+// scimWhere 将过滤表达式转换为查询条件
+// columns: 过滤属性（小写）对应的数据库列，is_delete 列按 active 布尔值比较
+func scimWhere(db *gorm.DB, filter string, columns map[string]string) (*gorm.DB, error) {
+	filters, err := reuint.ParseScimFilter(filter)
+	if err != nil {
+		return nil, &scimErr{status: http.StatusBadRequest, scimType: "invalidFilter", detail: err.Error()}
+	}
+	like := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	for _, f := range filters {
+		attr := strings.TrimPrefix(strings.ToLower(f.Attr), scimCoreUser)
+		column, ok := columns[attr]
+		if !ok {
+			return nil, &scimErr{status: http.StatusBadRequest, scimType: "invalidFilter", detail: fmt.Sprintf("不支持的属性 %q", f.Attr)}
+		}
+		if column == "is_delete" {
+			active, err := strconv.ParseBool(f.Value)
+			if err != nil || (f.Op != "eq" && f.Op != "ne") {
+				return nil, &scimErr{status: http.StatusBadRequest, scimType: "invalidFilter", detail: "active 仅支持 eq、ne 布尔值比较"}
+			}
+			if f.Op == "ne" {
+				active = !active
+			}
+			if active {
+				db = db.Where("is_delete = 0")
+			} else {
+				db = db.Where("is_delete <> 0")
+			}
+			continue
+		}
+		switch f.Op {
+		case "eq":
+			db = db.Where(column+" = ?", f.Value)
+		case "ne":
+			db = db.Where(column+" <> ?", f.Value)
+		case "co":
+			db = db.Where(column+" LIKE ?", "%"+like.Replace(f.Value)+"%")
+		case "sw":
+			db = db.Where(column+" LIKE ?", like.Replace(f.Value)+"%")
+		case "ew":
+			db = db.Where(column+" LIKE ?", "%"+like.Replace(f.Value))
+		case "pr":
+			db = db.Where(column + " IS NOT NULL AND " + column + " <> ''")
+		}
+	}
+	return db, nil
+}
+
+// scimLocation 资源地址
+func scimLocation(ctx *gin.Context, resource string, id int) string {
+	scheme := "http"
+	if middle.IsSecure(ctx.Request) {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/scim/v2/%s/%d", scheme, ctx.Request.Host, resource, id)
+}
+
+// scimFindUser 查找用户，包括已禁用的用户
+func scimFindUser(id string) (*entity.User, error) {
+	userId, _ := strconv.Atoi(id)
+	var user entity.User
+	err := repo.DBDao.First(&user, "id = ?", userId).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, &scimErr{status: http.StatusNotFound, detail: "用户不存在"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// scimFindGroup 查找用户组
+func scimFindGroup(id string) (*entity.UserGroup, error) {
+	groupId, _ := strconv.Atoi(id)
+	var group entity.UserGroup
+	err := repo.DBDao.First(&group, "id = ?", groupId).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, &scimErr{status: http.StatusNotFound, detail: "用户组不存在"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// scimUnique 检查用户名及工号是否与其他用户重复
+func scimUnique(user *entity.User) error {
+	var count int64
+	err := repo.DBDao.Model(&entity.User{}).
+		Where("(username = ? OR openid = ?) AND id <> ?", user.Username, user.Openid, user.ID).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return &scimErr{status: http.StatusConflict, scimType: "uniqueness", detail: "用户名或工号已存在"}
+	}
+	return nil
+}
+
+// toScimUser 转换为SCIM用户资源
+func toScimUser(ctx *gin.Context, user *entity.User) dto.ScimUser {
+	active := user.IsDelete == 0
+	res := dto.ScimUser{
+		Schemas:     []string{dto.ScimSchemaUser},
+		Id:          strconv.Itoa(user.ID),
+		ExternalId:  user.Openid,
+		UserName:    user.Username,
+		Name:        &dto.ScimName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Title:       user.Title,
+		Active:      &active,
+		Meta: &dto.ScimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt.Format(time.RFC3339),
+			Location:     scimLocation(ctx, "Users", user.ID),
+		},
+	}
+	if user.Email != "" {
+		res.Emails = []dto.ScimMultiValue{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.Phone != "" {
+		res.PhoneNumbers = []dto.ScimMultiValue{{Value: user.Phone, Type: "work", Primary: true}}
+	}
+	if user.DepartmentCode != "" || user.Manager != "" {
+		res.Schemas = append(res.Schemas, dto.ScimSchemaEnterprise)
+		res.Enterprise = &dto.ScimEnterprise{Department: user.DepartmentCode}
+		if user.Manager != "" {
+			res.Enterprise.Manager = &dto.ScimManager{Value: user.Manager}
+		}
+	}
+	return res
+}
+
+// applyScimUser 将SCIM用户资源的属性应用至用户
+func applyScimUser(user *entity.User, in *dto.ScimUser) error {
+	in.UserName = strings.TrimSpace(in.UserName)
+	if in.UserName == "" {
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "userName 不能为空"}
+	}
+	user.Username = in.UserName
+	user.Openid = in.ExternalId
+
+	name := in.DisplayName
+	if name == "" && in.Name != nil {
+		name = in.Name.Formatted
+		if name == "" {
+			name = in.Name.FamilyName + in.Name.GivenName
+		}
+	}
+	user.Name = name
+	user.NamePy = ""
+	if name != "" {
+		py, err := reuint.PinyinConversion(name)
+		if err != nil {
+			return err
+		}
+		user.NamePy = py
+	}
+	user.Email = scimPrimary(in.Emails)
+	user.Phone = scimPrimary(in.PhoneNumbers)
+	user.Title = in.Title
+	user.DepartmentCode, user.Manager = "", ""
+	if in.Enterprise != nil {
+		user.DepartmentCode = in.Enterprise.Department
+		if in.Enterprise.Manager != nil {
+			user.Manager = in.Enterprise.Manager.Value
+		}
+	}
+	if in.Active != nil {
+		if *in.Active {
+			user.IsDelete = 0
+		} else {
+			user.IsDelete = 1
+		}
+	}
+	if in.Password != "" {
+		pwd, salt, err := reuint.GenPasswordSalt(in.Password)
+		if err != nil {
+			return err
+		}
+		user.Password, user.Salt = entity.Pwd(pwd), salt
+	}
+	return nil
+}
+
+// scimPrimary 多值属性的主要值，没有主要值时使用第一个值
+func scimPrimary(values []dto.ScimMultiValue) string {
+	for _, v := range values {
+		if v.Primary {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// patchScimUser 对SCIM用户资源执行修改操作
+// op: 操作类型 add、replace、remove
+// path: 属性路径，为空时 value 为属性对象
+func patchScimUser(u *dto.ScimUser, op, path string, value json.RawMessage) error {
+	op = strings.ToLower(op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: fmt.Sprintf("不支持的操作 %q", op)}
+	}
+	if op == "remove" {
+		value = nil
+	}
+	attr := strings.TrimPrefix(strings.ToLower(path), scimCoreUser)
+
+	// 未指定路径时 value 为属性对象，企业用户扩展为嵌套对象
+	if attr == "" {
+		if op == "remove" {
+			return &scimErr{status: http.StatusBadRequest, scimType: "noTarget", detail: "remove 操作必须指定 path"}
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attrs); err != nil {
+			return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "value 必须为属性对象"}
+		}
+		for k, v := range attrs {
+			if strings.ToLower(k) == scimEnterprise {
+				var ext map[string]json.RawMessage
+				if err := json.Unmarshal(v, &ext); err != nil {
+					return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "企业用户扩展必须为对象"}
+				}
+				for ek, ev := range ext {
+					if err := patchScimUser(u, op, scimEnterprise+":"+ek, ev); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := patchScimUser(u, op, k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var err error
+	switch {
+	case attr == "username":
+		err = scimDecode(value, &u.UserName)
+	case attr == "externalid":
+		err = scimDecode(value, &u.ExternalId)
+	case attr == "displayname" || attr == "name.formatted":
+		err = scimDecode(value, &u.DisplayName)
+		u.Name = &dto.ScimName{Formatted: u.DisplayName}
+	case attr == "name":
+		var name dto.ScimName
+		err = scimDecode(value, &name)
+		u.Name, u.DisplayName = &name, ""
+	case attr == "name.givenname" || attr == "name.familyname":
+		if u.Name == nil {
+			u.Name = &dto.ScimName{}
+		}
+		if attr == "name.givenname" {
+			err = scimDecode(value, &u.Name.GivenName)
+		} else {
+			err = scimDecode(value, &u.Name.FamilyName)
+		}
+		u.Name.Formatted, u.DisplayName = "", ""
+	case attr == "title":
+		err = scimDecode(value, &u.Title)
+	case attr == "password":
+		err = scimDecode(value, &u.Password)
+	case attr == "active":
+		active := false
+		if value != nil {
+			// 兼容以字符串表示的布尔值，如 "False"
+			var s string
+			if json.Unmarshal(value, &s) == nil {
+				active, err = strconv.ParseBool(s)
+			} else {
+				err = json.Unmarshal(value, &active)
+			}
+		}
+		u.Active = &active
+	case attr == "emails" || attr == "phonenumbers":
+		var values []dto.ScimMultiValue
+		err = scimDecode(value, &values)
+		if attr == "emails" {
+			u.Emails = values
+		} else {
+			u.PhoneNumbers = values
+		}
+	case strings.HasPrefix(attr, "emails[") || strings.HasPrefix(attr, "phonenumbers["):
+		// 仅有一个值，按类型过滤的值路径均视为修改该值
+		if !strings.HasSuffix(attr, "].value") {
+			return &scimErr{status: http.StatusBadRequest, scimType: "invalidPath", detail: fmt.Sprintf("不支持的属性 %q", path)}
+		}
+		var s string
+		err = scimDecode(value, &s)
+		values := []dto.ScimMultiValue{{Value: s, Type: "work", Primary: true}}
+		if s == "" {
+			values = nil
+		}
+		if strings.HasPrefix(attr, "emails[") {
+			u.Emails = values
+		} else {
+			u.PhoneNumbers = values
+		}
+	case attr == scimEnterprise+":department":
+		if u.Enterprise == nil {
+			u.Enterprise = &dto.ScimEnterprise{}
+		}
+		err = scimDecode(value, &u.Enterprise.Department)
+	case attr == scimEnterprise+":manager" || attr == scimEnterprise+":manager.value":
+		if u.Enterprise == nil {
+			u.Enterprise = &dto.ScimEnterprise{}
+		}
+		// 兼容直接以字符串表示的直属上级
+		var manager dto.ScimManager
+		if json.Unmarshal(value, &manager.Value) != nil {
+			err = scimDecode(value, &manager)
+		}
+		u.Enterprise.Manager = &manager
+	default:
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidPath", detail: fmt.Sprintf("不支持的属性 %q", path)}
+	}
+	if err != nil {
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: fmt.Sprintf("属性 %q 的值非法", path)}
+	}
+	return nil
+}
+
+// scimDecode 解析属性值，值为空时置为零值
+func scimDecode(value json.RawMessage, v interface{}) error {
+	if len(value) == 0 || string(value) == "null" {
+		rv := reflect.ValueOf(v).Elem()
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	return json.Unmarshal(value, v)
+}
+
+// patchScimGroup 对用户组名称及成员集合执行修改操作
+func patchScimGroup(name *string, members map[int]bool, op, path string, value json.RawMessage) error {
+	op = strings.ToLower(op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: fmt.Sprintf("不支持的操作 %q", op)}
+	}
+	attr := strings.ToLower(path)
+	if attr == "" {
+		if op == "remove" {
+			return &scimErr{status: http.StatusBadRequest, scimType: "noTarget", detail: "remove 操作必须指定 path"}
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attrs); err != nil {
+			return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "value 必须为属性对象"}
+		}
+		for k, v := range attrs {
+			if err := patchScimGroup(name, members, op, k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case attr == "displayname":
+		if op == "remove" || json.Unmarshal(value, name) != nil {
+			return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "displayName 非法"}
+		}
+	case attr == "members":
+		var list []dto.ScimMember
+		if len(value) > 0 && json.Unmarshal(value, &list) != nil {
+			return &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: "members 必须为成员列表"}
+		}
+		ids, err := scimMemberIds(list)
+		if err != nil {
+			return err
+		}
+		switch op {
+		case "replace":
+			for id := range members {
+				delete(members, id)
+			}
+			fallthrough
+		case "add":
+			for _, id := range ids {
+				members[id] = true
+			}
+		case "remove":
+			// 未指定成员时移除所有成员
+			if len(value) == 0 {
+				for id := range members {
+					delete(members, id)
+				}
+			}
+			for _, id := range ids {
+				delete(members, id)
+			}
+		}
+	case strings.HasPrefix(attr, "members[") && strings.HasSuffix(attr, "]") && op == "remove":
+		filters, err := reuint.ParseScimFilter(path[len("members[") : len(path)-1])
+		if err != nil || len(filters) != 1 || strings.ToLower(filters[0].Attr) != "value" || filters[0].Op != "eq" {
+			return &scimErr{status: http.StatusBadRequest, scimType: "invalidFilter", detail: "仅支持 members[value eq \"用户ID\"]"}
+		}
+		id, _ := strconv.Atoi(filters[0].Value)
+		delete(members, id)
+	default:
+		return &scimErr{status: http.StatusBadRequest, scimType: "invalidPath", detail: fmt.Sprintf("不支持的属性 %q", path)}
+	}
+	return nil
+}
+
+// scimMemberIds 解析成员用户ID
+func scimMemberIds(members []dto.ScimMember) ([]int, error) {
+	var res []int
+	seen := map[int]bool{}
+	for _, m := range members {
+		id, err := strconv.Atoi(m.Value)
+		if err != nil || id <= 0 {
+			return nil, &scimErr{status: http.StatusBadRequest, scimType: "invalidValue", detail: fmt.Sprintf("成员ID非法 %q", m.Value)}
+		}
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	return res, nil
+}
+
+// toScimGroups 转换为SCIM用户组资源
+// withMembers: 是否查询成员
+func toScimGroups(ctx *gin.Context, groups []entity.UserGroup, withMembers bool) ([]dto.ScimGroup, error) {
+	res := make([]dto.ScimGroup, 0, len(groups))
+	index := map[int]int{}
+	for i, g := range groups {
+		index[g.ID] = i
+		res = append(res, dto.ScimGroup{
+			Schemas:     []string{dto.ScimSchemaGroup},
+			Id:          strconv.Itoa(g.ID),
+			DisplayName: g.Name,
+			Meta: &dto.ScimMeta{
+				ResourceType: "Group",
+				Created:      g.CreatedAt.Format(time.RFC3339),
+				Location:     scimLocation(ctx, "Groups", g.ID),
+			},
+		})
+	}
+	if !withMembers || len(groups) == 0 {
+		return res, nil
+	}
+
+	var rows []struct {
+		Belong int
+		UserId int
+		Name   string
+	}
+	err := repo.DBDao.Model(&entity.GroupMember{}).
+		Select("group_members.belong, group_members.user_id, users.name").
+		Joins("LEFT JOIN users ON users.id = group_members.user_id").
+		Where("group_members.belong IN ?", keys(index)).
+		Order("group_members.id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		g := &res[index[row.Belong]]
+		g.Members = append(g.Members, dto.ScimMember{
+			Value:   strconv.Itoa(row.UserId),
+			Display: row.Name,
+			Ref:     scimLocation(ctx, "Users", row.UserId),
+		})
+	}
+	return res, nil
+}
+
+// keys 返回映射的所有键
+func keys(m map[int]int) []int {
+	res := make([]int, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	return res
+}
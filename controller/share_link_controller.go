@@ -0,0 +1,570 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+	"io"
+	"note/appconf/dir"
+	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint"
+	"note/reuint/jwt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	shareLinkMaxFailures = 10 // 访问密码连续错误次数上限，达到后锁定一段时间
+)
+
+// NewShareLinkController 创建公开分享链接控制器
+func NewShareLinkController(router gin.IRouter) *ShareLinkController {
+	res := &ShareLinkController{
+		tickets:  cache.New(30*time.Minute, 10*time.Minute),
+		failures: cache.New(10*time.Minute, 10*time.Minute),
+	}
+	r := router.Group("/shareLink")
+	// 创建公开分享链接
+	r.POST("/create", User, res.create)
+	// 获取笔记的公开分享链接
+	r.GET("/list", User, res.list)
+	// 撤销公开分享链接
+	r.POST("/revoke", User, res.revoke)
+
+	// 匿名访问接口，见 middle.Anonymous
+	p := router.Group("/public")
+	// 查看公开分享的笔记
+	p.POST("/note", res.note)
+	// 下载公开分享的笔记资源
+	p.GET("/assert", res.assert)
+	return res
+}
+
+// ShareLinkController 公开分享链接控制器
+type ShareLinkController struct {
+	tickets  *cache.Cache // 资源下载凭证 - 链接ID，查看笔记时签发，下载资源不计入访问次数
+	failures *cache.Cache // 链接令牌 - 访问密码连续错误次数
+}
+
+/**
+@api {POST} /api/shareLink/create 创建公开分享链接
+@apiDescription 创建笔记的公开分享链接，持有链接的匿名访问者可只读查看笔记，仅笔记拥有者可创建。
+
+访问地址由前端根据链接令牌生成，笔记内容通过 /api/public/note 获取。
+
+@apiName ShareLinkCreate
+@apiGroup ShareLink
+
+@apiPermission 用户
+
+@apiParam {Integer} noteId 笔记ID。
+@apiParam {String} [expiredAt] 过期时间，格式：2006-01-02 15:04:05，为空表示不过期。
+@apiParam {String} [password] 访问密码，为空表示无需密码。
+@apiParam {Integer} [maxViews=0] 最大访问次数，0表示不限制。
+@apiParam {Boolean} [allowAssets=false] 是否允许下载笔记中的图片、附件等资源。
+
+@apiParamExample {json} 请求示例
+{
+	"noteId": 12,
+	"expiredAt": "2026-11-01 00:00:00",
+	"password": "8821",
+	"maxViews": 50,
+	"allowAssets": true
+}
+
+@apiSuccess {Integer} id 链接ID。
+@apiSuccess {String} token 链接令牌。
+@apiSuccess {String} expiredAt 过期时间，为空表示不过期。
+@apiSuccess {Boolean} hasPassword 是否需要访问密码。
+@apiSuccess {Integer} maxViews 最大访问次数。
+@apiSuccess {Integer} views 已访问次数。
+@apiSuccess {Integer} allowAssets 是否允许下载笔记资源。
+@apiSuccess {Integer} revoked 是否已撤销。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"id": 3,
+	"createdAt": "2026-10-19 10:00:00",
+	"noteId": 12,
+	"userId": 30,
+	"token": "9f2c4b1e8a7d6c5b4a3f2e1d0c9b8a7f",
+	"expiredAt": "2026-11-01 00:00:00",
+	"hasPassword": true,
+	"maxViews": 50,
+	"views": 0,
+	"allowAssets": 1,
+	"revoked": 0
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+无权限
+*/
+
+// create 创建公开分享链接
+func (c *ShareLinkController) create(ctx *gin.Context) {
+	var info dto.ShareLinkCreateDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "创建公开分享链接", map[string]interface{}{
+		"noteId":      info.NoteId,
+		"expiredAt":   info.ExpiredAt,
+		"password":    info.Password != "",
+		"maxViews":    info.MaxViews,
+		"allowAssets": info.AllowAssets,
+	})
+	if err != nil || info.NoteId <= 0 || info.MaxViews < 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+	if !c.owner(ctx, claims.Sub, info.NoteId) {
+		return
+	}
+
+	link := entity.ShareLink{
+		NoteId:   info.NoteId,
+		UserId:   claims.Sub,
+		MaxViews: info.MaxViews,
+	}
+	if info.AllowAssets {
+		link.AllowAssets = 1
+	}
+	if info.ExpiredAt != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", info.ExpiredAt, time.Local)
+		if err != nil {
+			ErrIllegal(ctx, "过期时间格式错误")
+			return
+		}
+		if t.Before(time.Now()) {
+			ErrIllegal(ctx, "过期时间早于当前时间")
+			return
+		}
+		link.ExpiredAt = &t
+	}
+	if info.Password != "" {
+		pwd, salt, err := reuint.GenPasswordSalt(info.Password)
+		if err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		link.Password, link.Salt = entity.Pwd(pwd), salt
+	}
+	if link.Token, err = randomHex(16); err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	if err = repo.DBDao.Create(&link).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, &link)
+}
+
+/**
+@api {GET} /api/shareLink/list 获取笔记的公开分享链接
+@apiDescription 获取笔记的所有公开分享链接，包含已撤销及已过期的链接，仅笔记拥有者可查看。
+@apiName ShareLinkList
+@apiGroup ShareLink
+
+@apiPermission 用户
+
+@apiParam {Integer} noteId 笔记ID。
+
+@apiParamExample 请求示例
+GET /api/shareLink/list?noteId=12
+
+@apiSuccess {ShareLink[]} links 链接列表，字段见创建公开分享链接。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+[
+	{
+		"id": 3,
+		"createdAt": "2026-10-19 10:00:00",
+		"noteId": 12,
+		"userId": 30,
+		"token": "9f2c4b1e8a7d6c5b4a3f2e1d0c9b8a7f",
+		"expiredAt": null,
+		"hasPassword": false,
+		"maxViews": 0,
+		"views": 8,
+		"allowAssets": 0,
+		"revoked": 0
+	}
+]
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+无权限
+*/
+
+// list 获取笔记的公开分享链接
+func (c *ShareLinkController) list(ctx *gin.Context) {
+	noteId, _ := strconv.Atoi(ctx.Query("noteId"))
+	if noteId <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+	if !c.owner(ctx, claims.Sub, noteId) {
+		return
+	}
+
+	links := []entity.ShareLink{}
+	err := repo.DBDao.Where("note_id = ?", noteId).Order("id desc").Find(&links).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, links)
+}
+
+/**
+@api {POST} /api/shareLink/revoke 撤销公开分享链接
+@apiDescription 撤销后链接立即失效，已签发的资源下载凭证同时失效，仅笔记拥有者可撤销。
+@apiName ShareLinkRevoke
+@apiGroup ShareLink
+
+@apiPermission 用户
+
+@apiParam {Integer} id 链接ID。
+
+@apiParamExample {json} 请求示例
+{
+	"id": 3
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+链接不存在
+*/
+
+// revoke 撤销公开分享链接
+func (c *ShareLinkController) revoke(ctx *gin.Context) {
+	var info dto.ShareLinkRevokeDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "撤销公开分享链接", map[string]interface{}{
+		"id": info.Id,
+	})
+	if err != nil || info.Id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	var link entity.ShareLink
+	err = repo.DBDao.First(&link, "id = ?", info.Id).Error
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "链接不存在")
+		return
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+	if !c.owner(ctx, claims.Sub, link.NoteId) {
+		return
+	}
+
+	err = repo.DBDao.Model(&entity.ShareLink{}).Where("id = ?", link.ID).Update("revoked", 1).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+}
+
+// owner 检查用户是否为笔记拥有者，否则返回错误响应
+func (c *ShareLinkController) owner(ctx *gin.Context, userId int, noteId int) bool {
+	role, err := repo.NoteMemberRepo.Check(userId, noteId)
+	if err != nil {
+		ErrSys(ctx, err)
+		return false
+	}
+	if role != 0 {
+		ErrIllegal(ctx, "无权限")
+		return false
+	}
+	return true
+}
+
+/**
+@api {POST} /api/public/note 查看公开分享的笔记
+@apiDescription 匿名访问公开分享的笔记，每次成功访问计入访问次数，所有访问（含失败）均记录操作日志。
+
+允许下载资源时，笔记内容中的资源地址替换为 /api/public/assert 的下载地址，下载凭证有效期30分钟。
+访问密码连续错误10次后该链接锁定10分钟。
+
+@apiName PublicNote
+@apiGroup ShareLink
+
+@apiPermission 匿名
+
+@apiParam {String} token 链接令牌。
+@apiParam {String} [password] 访问密码。
+
+@apiParamExample {json} 请求示例
+{
+	"token": "9f2c4b1e8a7d6c5b4a3f2e1d0c9b8a7f",
+	"password": "8821"
+}
+
+@apiSuccess {String} title 笔记名称。
+@apiSuccess {String} updatedAt 更新时间。
+@apiSuccess {String} content 笔记内容。
+@apiSuccess {Boolean} allowAssets 是否允许下载笔记资源。
+@apiSuccess {String} ticket 资源下载凭证。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"title": "接口说明",
+	"updatedAt": "2026-10-19 10:00:00",
+	"content": "![](/api/public/assert?ticket=5a1c...&file=202610191000000001.png)",
+	"allowAssets": true,
+	"ticket": "5a1c..."
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 403 Forbidden
+
+需要访问密码
+*/
+
+// note 查看公开分享的笔记
+func (c *ShareLinkController) note(ctx *gin.Context) {
+	var info dto.PublicNoteReqDto
+	if err := ctx.ShouldBindJSON(&info); err != nil || info.Token == "" {
+		c.logAccess(ctx, "访问公开分享链接", nil, info.Token, "参数错误")
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	link, note, reason, err := c.find(info.Token)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if reason != "" {
+		c.logAccess(ctx, "访问公开分享链接", link, info.Token, reason)
+		ErrIllegal(ctx, "链接不存在或已失效")
+		return
+	}
+
+	// 校验访问密码
+	if link.Password != "" {
+		if failures, ok := c.failures.Get(link.Token); ok && failures.(int) >= shareLinkMaxFailures {
+			c.logAccess(ctx, "访问公开分享链接", link, info.Token, "密码错误次数过多")
+			ErrForbidden(ctx, "密码错误次数过多，请稍后再试")
+			return
+		}
+		if info.Password == "" {
+			c.logAccess(ctx, "访问公开分享链接", link, info.Token, "需要访问密码")
+			ErrForbidden(ctx, "需要访问密码")
+			return
+		}
+		if !reuint.VerifyPasswordSalt(info.Password, link.Password.String(), link.Salt) {
+			if c.failures.Add(link.Token, 1, cache.DefaultExpiration) != nil {
+				_, _ = c.failures.IncrementInt(link.Token, 1)
+			}
+			c.logAccess(ctx, "访问公开分享链接", link, info.Token, "访问密码错误")
+			ErrForbidden(ctx, "访问密码错误")
+			return
+		}
+		c.failures.Delete(link.Token)
+	}
+
+	// 计入访问次数，达到上限时拒绝访问
+	res := repo.DBDao.Model(&entity.ShareLink{}).
+		Where("id = ? AND (max_views = 0 OR views < max_views)", link.ID).
+		UpdateColumn("views", gorm.Expr("views + 1"))
+	if res.Error != nil {
+		ErrSys(ctx, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.logAccess(ctx, "访问公开分享链接", link, info.Token, "访问次数已达上限")
+		ErrIllegal(ctx, "链接访问次数已达上限")
+		return
+	}
+
+	file, err := os.Open(filepath.Join(dir.NoteDir, strconv.Itoa(note.ID), note.Filename))
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	result := dto.PublicNoteDto{
+		Title:     note.Title,
+		UpdatedAt: note.UpdatedAt,
+		Content:   string(content),
+	}
+	if link.AllowAssets == 1 {
+		if result.Ticket, err = randomHex(16); err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		c.tickets.Set(result.Ticket, link.ID, cache.DefaultExpiration)
+		result.AllowAssets = true
+		result.Content = strings.ReplaceAll(result.Content,
+			fmt.Sprintf("/api/note/assert?id=%d&file=", note.ID),
+			fmt.Sprintf("/api/public/assert?ticket=%s&file=", result.Ticket))
+	}
+	c.logAccess(ctx, "访问公开分享链接", link, info.Token, "成功")
+	ctx.JSON(200, &result)
+}
+
+/**
+@api {GET} /api/public/assert 下载公开分享的笔记资源
+@apiDescription 使用查看笔记时签发的下载凭证下载笔记资源，不计入访问次数，链接撤销或过期后凭证失效，所有访问均记录操作日志。
+@apiName PublicAssert
+@apiGroup ShareLink
+
+@apiPermission 匿名
+
+@apiParam {String} ticket 资源下载凭证。
+@apiParam {String} file 文件名称。
+
+@apiParamExample {http} 请求示例
+GET /api/public/assert?ticket=5a1c...&file=202610191000000001.png
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+下载凭证无效或已过期
+*/
+
+// assert 下载公开分享的笔记资源
+func (c *ShareLinkController) assert(ctx *gin.Context) {
+	ticket := ctx.Query("ticket")
+	value, ok := c.tickets.Get(ticket)
+	if ticket == "" || !ok {
+		c.logAccess(ctx, "下载公开分享资源", nil, "", "下载凭证无效")
+		ErrIllegal(ctx, "下载凭证无效或已过期")
+		return
+	}
+
+	var link entity.ShareLink
+	err := repo.DBDao.First(&link, "id = ?", value.(int)).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		ErrSys(ctx, err)
+		return
+	}
+	_, _, reason, err := c.find(link.Token)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if reason == "" && link.AllowAssets != 1 {
+		reason = "不允许下载资源"
+	}
+	if reason != "" {
+		c.tickets.Delete(ticket)
+		c.logAccess(ctx, "下载公开分享资源", &link, link.Token, reason)
+		ErrIllegal(ctx, "下载凭证无效或已过期")
+		return
+	}
+
+	c.logAccess(ctx, "下载公开分享资源", &link, link.Token, "成功")
+	serveAssert(ctx, strconv.Itoa(link.NoteId), ctx.Query("file"))
+}
+
+// find 查找有效的公开分享链接及其笔记
+// return: 链接, 笔记, 链接无效的原因（有效时为空）, 错误
+func (c *ShareLinkController) find(token string) (*entity.ShareLink, *entity.Note, string, error) {
+	if token == "" {
+		return nil, nil, "链接不存在", nil
+	}
+	var link entity.ShareLink
+	err := repo.DBDao.First(&link, "token = ?", token).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil, "链接不存在", nil
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if link.Revoked == 1 {
+		return &link, nil, "链接已撤销", nil
+	}
+	if link.Expired() {
+		return &link, nil, "链接已过期", nil
+	}
+	var note entity.Note
+	err = repo.DBDao.First(&note, "id = ?", link.NoteId).Error
+	if err == gorm.ErrRecordNotFound || (err == nil && note.IsDelete != 0) {
+		return &link, nil, "笔记不存在或已删除", nil
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return &link, &note, "", nil
+}
+
+// logAccess 记录公开分享链接的访问至操作日志
+// 链接不存在时仅记录令牌前8位，防止日志泄露有效令牌。
+func (c *ShareLinkController) logAccess(ctx *gin.Context, name string, link *entity.ShareLink, token string, result string) {
+	param := map[string]interface{}{
+		"ip":        ctx.ClientIP(),
+		"result":    result,
+		"requestId": ctx.GetString(middle.FlagRequestId),
+	}
+	if link != nil {
+		param["linkId"] = link.ID
+		param["noteId"] = link.NoteId
+	} else if token != "" {
+		if len(token) > 8 {
+			token = token[:8]
+		}
+		param["token"] = token
+	}
+	if file := ctx.Query("file"); file != "" {
+		param["file"] = file
+	}
+	applog.Anonymous(name, param)
+}
+
+// randomHex 生成指定字节数的随机数Hex
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
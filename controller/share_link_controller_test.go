@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"note/appconf/dir"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestServeAssert 公开分享下载资源不可越过笔记目录
+func TestServeAssert(t *testing.T) {
+	noteDir := dir.NoteDir
+	defer func() { dir.NoteDir = noteDir }()
+	dir.NoteDir = t.TempDir()
+	for _, id := range []string{"1", "12"} {
+		_ = os.MkdirAll(filepath.Join(dir.NoteDir, id), 0700)
+		_ = os.WriteFile(filepath.Join(dir.NoteDir, id, "a.txt"), []byte("note"+id), 0600)
+	}
+	_ = os.WriteFile(filepath.Join(filepath.Dir(dir.NoteDir), "secret.txt"), []byte("secret"), 0600)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// 与 ShareLinkController.assert 相同，笔记ID来自分享链接，文件名来自请求参数
+	r.GET("/api/public/assert", func(ctx *gin.Context) {
+		serveAssert(ctx, "1", ctx.Query("file"))
+	})
+	tests := []struct {
+		file string
+		want int
+		body string
+	}{
+		{"a.txt", http.StatusOK, "note1"},
+		{"../12/a.txt", http.StatusBadRequest, ""},
+		{"../1/a.txt", http.StatusOK, "note1"},
+		{"../../secret.txt", http.StatusBadRequest, ""},
+		{"..", http.StatusBadRequest, ""},
+		{"", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/public/assert?file="+tt.file, nil)
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("serveAssert(%q) = %d %q, want %d", tt.file, w.Code, w.Body.String(), tt.want)
+		}
+	}
+}
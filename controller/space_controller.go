@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint/jwt"
+)
+
+// NewSpaceController 创建团队空间控制器
+func NewSpaceController(router gin.IRouter) *SpaceController {
+	res := &SpaceController{}
+	r := router.Group("/space")
+	// 创建团队空间
+	r.POST("/create", User, res.create)
+	// 获取团队空间列表
+	r.GET("/list", User, res.list)
+	return res
+}
+
+// SpaceController 团队空间控制器
+// 团队空间是属于用户组的根文件夹，其中的文件夹及笔记属于用户组而非创建者，成员离开后内容仍保留在团队空间中。
+// 用户组拥有者管理团队空间，维护可编辑，普通用户可查看。
+// 团队空间的文件夹通过 /api/folder 接口管理，笔记通过 /api/note/noteList 的 space 参数查询。
+type SpaceController struct {
+}
+
+/**
+@api {POST} /api/space/create 创建团队空间
+@apiDescription 为用户组创建团队空间，每个用户组仅有一个团队空间，仅用户组拥有者可创建。
+@apiName SpaceCreate
+@apiGroup Space
+
+@apiPermission 用户
+
+@apiParam {Integer} groupId 用户组ID。
+@apiParam {String} name 团队空间名称。
+
+@apiParamExample {json} 请求示例
+{
+	"groupId": 1,
+	"name": "研发部知识库"
+}
+
+@apiSuccess {Integer} id 团队空间根文件夹ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+12
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+用户组已创建团队空间
+*/
+
+// create 创建团队空间
+func (c *SpaceController) create(ctx *gin.Context) {
+	var info dto.SpaceCreateDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "创建团队空间", map[string]interface{}{
+		"groupId": info.GroupId,
+		"name":    info.Name,
+	})
+	if err != nil || info.GroupId <= 0 || info.Name == "" {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	// 判断用户是否为用户组拥有者
+	role, err := repo.UserGroupRepo.Role(claims.Sub, info.GroupId)
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "用户组不存在或用户未在用户组")
+		return
+	} else if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if role != 0 {
+		ErrIllegal(ctx, "用户权限不足")
+		return
+	}
+
+	var count int64
+	err = repo.DBDao.Model(&entity.Folder{}).Where("group_id = ? AND parent_id = 0", info.GroupId).Count(&count).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if count > 0 {
+		ErrIllegal(ctx, "用户组已创建团队空间")
+		return
+	}
+
+	folder := entity.Folder{GroupId: info.GroupId, Name: info.Name}
+	err = repo.DBDao.Create(&folder).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	ctx.JSON(200, folder.ID)
+}
+
+/**
+@api {GET} /api/space/list 获取团队空间列表
+@apiDescription 获取用户所在用户组的团队空间。
+@apiName SpaceList
+@apiGroup Space
+
+@apiPermission 用户
+
+@apiSuccess {Space[]} spaces 团队空间列表。
+@apiSuccess (Space) {Integer} groupId 所属用户组ID，用于笔记列表的 space 参数。
+@apiSuccess (Space) {String} groupName 用户组名称。
+@apiSuccess (Space) {Integer} folderId 根文件夹ID，子文件夹通过 /api/folder/list 查看。
+@apiSuccess (Space) {String} name 团队空间名称。
+@apiSuccess (Space) {Integer} role 用户在用户组中的角色
+<ul>
+	<li>0 - 拥有者，管理团队空间</li>
+	<li>1 - 普通用户，可查看</li>
+	<li>2 - 维护，可编辑</li>
+</ul>
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+[
+    {
+        "groupId": 1,
+        "groupName": "研发部",
+        "folderId": 12,
+        "name": "研发部知识库",
+        "role": 2
+    }
+]
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// list 获取团队空间列表
+func (c *SpaceController) list(ctx *gin.Context) {
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	spaces := []dto.SpaceDto{}
+	err := repo.DBDao.Table("folders").
+		Select("folders.group_id , user_groups.`name` AS group_name , folders.id AS folder_id , folders.`name` , group_members.role").
+		Joins("INNER JOIN group_members ON group_members.belong = folders.group_id").
+		Joins("INNER JOIN user_groups ON user_groups.id = folders.group_id").
+		Where("folders.parent_id = 0 AND folders.group_id <> 0 AND group_members.user_id = ?", claims.Sub).
+		Find(&spaces).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	ctx.JSON(200, spaces)
+}
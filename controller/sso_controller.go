@@ -7,24 +7,38 @@ import (
 	"gorm.io/gorm"
 	"io"
 	"net/http"
+	"net/url"
+	"note/appconf"
 	"note/controller/dto"
 	"note/repo"
 	"note/repo/entity"
 	"note/reuint/jwt"
-	"time"
+	"sync"
 )
 
 // NewSsoController 创建单点登录控制器
-func NewSsoController(router gin.IRouter, baseUrl string) *SsoController {
+func NewSsoController(router gin.IRouter, cfg *appconf.Application) *SsoController {
 	res := &SsoController{}
 	router.GET("/redirect", res.redirect)
-	res.ssoBaseUrl = baseUrl
+	res.update(cfg)
 	return res
 }
 
 // SsoController 单点登录控制器
 type SsoController struct {
-	ssoBaseUrl string
+	mu           sync.RWMutex
+	ssoBaseUrl   string
+	clientId     string // 客户端ID
+	clientSecret string // 客户端密钥
+}
+
+// update 更新单点登录配置
+func (c *SsoController) update(cfg *appconf.Application) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ssoBaseUrl = cfg.SSOBaseUrl
+	c.clientId = cfg.SSOClientId
+	c.clientSecret = cfg.SSOClientSecret
 }
 
 /**
@@ -51,14 +65,23 @@ GET /oauth/redirect?code=b9502e98e4e3adf1dd400b39c60e272f7c9458db146d22229f3796c
 
 // redirect 单点登录回调接口
 func (c *SsoController) redirect(ctx *gin.Context) {
-	const clientId = "c05e7e41-18ae-4361-81c4-9010d9dd4623"
-	const clientSecret = "b50720a5f8beb350f4d91e1ab49595312230f31ed13e7077e86f9905bc740b37"
 	const grantType = "authorization_code"
 	code := ctx.Query("code")
+	c.mu.RLock()
+	baseUrl, clientId, clientSecret := c.ssoBaseUrl, c.clientId, c.clientSecret
+	c.mu.RUnlock()
+	if clientId == "" {
+		ErrIllegal(ctx, "未配置单点登录")
+		return
+	}
 
 	// 获取access_token
-	tokenRes, _ := http.NewRequest("GET", c.ssoBaseUrl+"/oauth/token?client_id="+
-		clientId+"&client_secret="+clientSecret+"&grant_type="+grantType+"&code="+code, nil)
+	query := url.Values{}
+	query.Set("client_id", clientId)
+	query.Set("client_secret", clientSecret)
+	query.Set("grant_type", grantType)
+	query.Set("code", code)
+	tokenRes, _ := http.NewRequest("GET", baseUrl+"/oauth/token?"+query.Encode(), nil)
 	tokenResp, _ := http.DefaultClient.Do(tokenRes)
 	body, _ := io.ReadAll(tokenResp.Body)
 	defer tokenResp.Body.Close()
@@ -70,7 +93,7 @@ func (c *SsoController) redirect(ctx *gin.Context) {
 
 	// 获取用户信息
 	accessToken := fmt.Sprintf("Bearer %s", tokenInfo.AccessToken)
-	infoRes, _ := http.NewRequest("GET", c.ssoBaseUrl+"/info", nil)
+	infoRes, _ := http.NewRequest("GET", baseUrl+"/info", nil)
 	infoRes.Header.Add("accept", "application/json")
 	infoRes.Header.Add("Authorization", accessToken)
 	infoResp, _ := http.DefaultClient.Do(infoRes)
@@ -93,12 +116,16 @@ func (c *SsoController) redirect(ctx *gin.Context) {
 		ErrSys(ctx, err)
 		return
 	}
+	if user.Expired() {
+		ErrIllegal(ctx, "账号已过期，请联系管理员")
+		return
+	}
 
 	// 生成用户token进入主页
-	claims := jwt.Claims{Type: "user", Sub: user.ID, Exp: time.Now().Add(10 * time.Hour).UnixMilli()}
+	claims := jwt.Claims{Type: "user", Sub: user.ID, Exp: userTokenExp(&user)}
 	token := tokenManager.GenToken(&claims)
 	// 设置头部 Cookies 有效时间为8小时
-	ctx.SetCookie("token", token, 10*3600, "", "", false, true)
+	tokenManager.SetToken(ctx, token, 10*3600)
 
 	ctx.Redirect(http.StatusMovedPermanently, "/ui/#/index/noteList")
 }
@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"note/appconf"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/reuint"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderSyncTimestamp = "X-Sync-Timestamp" // 请求时间戳（Unix秒）
+	HeaderSyncNonce     = "X-Sync-Nonce"     // 请求随机数，有效期内不可重复
+	HeaderSyncSignature = "X-Sync-Signature" // 请求签名Hex，算法见 reuint.SignRequest
+
+	syncMaxBody = 4 << 20 // 用户同步请求体最大长度
+)
+
+// syncAuth 用户同步服务认证
+type syncAuth struct {
+	secret  []byte
+	maxSkew time.Duration
+	nonces  *cache.Cache // 有效期内已使用的随机数，防止重放
+}
+
+// NewSyncAuth 创建用户同步服务认证中间件
+// 校验时间戳、随机数以及HMAC-SM3签名，认证失败时返回401，被拒绝的请求均记录系统日志及操作日志。
+func NewSyncAuth(cfg appconf.SyncAuth) (gin.HandlerFunc, error) {
+	if len(cfg.Secret) < 16 {
+		return nil, fmt.Errorf("用户同步密钥不少于16个字符")
+	}
+	res := &syncAuth{
+		secret:  []byte(cfg.Secret),
+		maxSkew: time.Duration(cfg.MaxSkew) * time.Second,
+	}
+	if res.maxSkew <= 0 {
+		res.maxSkew = 300 * time.Second
+	}
+	// 随机数缓存时长为时间戳有效区间，超出区间的请求已被时间戳校验拒绝
+	res.nonces = cache.New(2*res.maxSkew, 10*time.Minute)
+	return res.handle, nil
+}
+
+// NewSyncIPFilter 创建用户同步服务来源IP过滤中间件
+// 来源IP不在允许列表中时返回403，允许列表为空时不限制。
+// allowIPs: 允许访问的IP或网段（CIDR）
+func NewSyncIPFilter(allowIPs []string) (gin.HandlerFunc, error) {
+	var nets []*net.IPNet
+	for _, item := range allowIPs {
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("非法的IP或网段 %q", item)
+			}
+			ipNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)}
+		}
+		nets = append(nets, ipNet)
+	}
+	return func(ctx *gin.Context) {
+		if len(nets) == 0 {
+			return
+		}
+		ip := net.ParseIP(ctx.ClientIP())
+		for _, item := range nets {
+			if ip != nil && item.Contains(ip) {
+				return
+			}
+		}
+		rejectSync(ctx, http.StatusForbidden, "来源IP不允许")
+	}, nil
+}
+
+// handle 认证请求
+func (a *syncAuth) handle(ctx *gin.Context) {
+	timestamp := ctx.GetHeader(HeaderSyncTimestamp)
+	nonce := ctx.GetHeader(HeaderSyncNonce)
+	signature := ctx.GetHeader(HeaderSyncSignature)
+	if timestamp == "" || nonce == "" || signature == "" {
+		rejectSync(ctx, http.StatusUnauthorized, "缺少签名信息")
+		return
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		rejectSync(ctx, http.StatusUnauthorized, "时间戳格式错误")
+		return
+	}
+	skew := time.Since(time.Unix(ts, 0))
+	if skew > a.maxSkew || skew < -a.maxSkew {
+		rejectSync(ctx, http.StatusUnauthorized, "时间戳超出允许范围")
+		return
+	}
+	if len(nonce) > 64 {
+		rejectSync(ctx, http.StatusUnauthorized, "随机数过长")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, syncMaxBody+1))
+	if err != nil {
+		rejectSync(ctx, http.StatusBadRequest, "请求体读取失败")
+		return
+	}
+	if len(body) > syncMaxBody {
+		rejectSync(ctx, http.StatusRequestEntityTooLarge, "请求体过大")
+		return
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	if !reuint.VerifyRequest(a.secret, ctx.Request.Method, ctx.Request.URL.Path, timestamp, nonce, body, signature) {
+		rejectSync(ctx, http.StatusUnauthorized, "签名错误")
+		return
+	}
+	// 签名通过后再记录随机数，防止伪造请求占用随机数
+	if err = a.nonces.Add(nonce, struct{}{}, cache.DefaultExpiration); err != nil {
+		rejectSync(ctx, http.StatusUnauthorized, "重复的请求")
+		return
+	}
+	ctx.Next()
+}
+
+// rejectSync 拒绝用户同步服务请求并记录日志
+func rejectSync(ctx *gin.Context, status int, reason string) {
+	logSyncReject(ctx, status, reason)
+	ctx.Writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.AbortWithStatus(status)
+	_, _ = ctx.Writer.WriteString(reason)
+}
+
+// logSyncReject 记录用户同步服务拒绝的请求至系统日志及操作日志
+func logSyncReject(ctx *gin.Context, status int, reason string) {
+	param := map[string]interface{}{
+		"ip":        ctx.ClientIP(),
+		"method":    ctx.Request.Method,
+		"path":      ctx.Request.URL.Path,
+		"status":    status,
+		"reason":    reason,
+		"requestId": ctx.GetString(middle.FlagRequestId),
+	}
+	middle.Logger(ctx).Warn("用户同步请求拒绝",
+		zap.String("ip", ctx.ClientIP()),
+		zap.Int("status", status),
+		zap.String("reason", reason))
+	applog.Anonymous("用户同步请求拒绝", param)
+}
@@ -2,10 +2,23 @@ package controller
 
 import (
 	"github.com/gin-gonic/gin"
+	"io/fs"
 	"note/appconf"
+	"note/appconf/dir"
+	"note/controller/dto"
+	"note/repo"
 	"note/repo/entity"
+	"note/reuint"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
 )
 
+// startedAt 程序启动时间
+var startedAt = time.Now()
+
 // SystemInfoController 系统版本控制器
 type SystemInfoController struct {
 }
@@ -15,6 +28,7 @@ func NewSystemInfoController(router gin.IRouter) *SystemInfoController {
 	res := &SystemInfoController{}
 	r := router.Group("/system")
 	r.GET("/version", res.version)
+	r.GET("/info", Admin, res.info)
 	return res
 }
 
@@ -53,3 +67,118 @@ func (c *SystemInfoController) version(ctx *gin.Context) {
 	reqInfo.SystemVersion = appconf.Version
 	ctx.JSON(200, reqInfo)
 }
+
+/**
+@api {GET} /api/system/info 系统运行信息
+@apiDescription 查询系统版本、构建信息、运行时长、数据库版本、磁盘使用情况及用户、笔记、资源数量。
+@apiName SystemInfoInfo
+@apiGroup System
+
+@apiPermission 管理员
+
+@apiParamExample 请求示例
+GET /api/system/info
+
+@apiSuccess {String} systemVersion 系统版本号。
+@apiSuccess {String} buildCommit 构建时的代码提交ID，未设置时为"unknown"。
+@apiSuccess {String} goVersion Go版本。
+@apiSuccess {String} startedAt 启动时间，格式"YYYY-MM-DD HH:mm:ss"。
+@apiSuccess {Integer} uptime 运行时长，单位（秒）。
+@apiSuccess {String} dbVersion 数据库版本号。
+@apiSuccess {Object} disk 笔记存储区所在磁盘使用情况，单位（B），获取失败时为null。
+@apiSuccess {Integer} disk.total 总容量。
+@apiSuccess {Integer} disk.free 可用容量。
+@apiSuccess {Integer} disk.used 已用容量。
+@apiSuccess {Integer} users 用户数量。
+@apiSuccess {Integer} notes 笔记数量（不含已删除）。
+@apiSuccess {Integer} assets 笔记资源文件数量。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+	{
+		"systemVersion": "V1.3.1",
+		"buildCommit": "01eea61",
+		"goVersion": "go1.19.5",
+		"startedAt": "2024-01-01 08:00:00",
+		"uptime": 3600,
+		"dbVersion": "2026101903",
+		"disk": {"total": 107374182400, "free": 53687091200, "used": 53687091200},
+		"users": 120,
+		"notes": 3400,
+		"assets": 8200
+	}
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// info 系统运行信息
+func (c *SystemInfoController) info(ctx *gin.Context) {
+	res := dto.SystemInfoDto{
+		SystemVersion: appconf.Version,
+		BuildCommit:   appconf.BuildCommit,
+		GoVersion:     runtime.Version(),
+		StartedAt:     startedAt.Format("2006-01-02 15:04:05"),
+		Uptime:        int64(time.Since(startedAt).Seconds()),
+	}
+
+	var cfg entity.Config
+	err := repo.DBDao.Where("item_name = ?", "db_version").Find(&cfg).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	res.DBVersion = cfg.Content
+
+	if err = repo.DBDao.Model(&entity.User{}).Count(&res.Users).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if err = repo.DBDao.Model(&entity.Note{}).Where("is_delete = 0").Count(&res.Notes).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	res.Assets, err = countAssets()
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+
+	// 磁盘信息获取失败不影响其他信息
+	res.Disk, _ = reuint.GetDiskUsage(dir.NoteDir)
+	ctx.JSON(200, res)
+}
+
+// countAssets 统计笔记资源文件数量，即笔记目录下除笔记内容文件外的文件
+func countAssets() (int64, error) {
+	var notes []entity.Note
+	if err := repo.DBDao.Select("id", "filename").Find(&notes).Error; err != nil {
+		return 0, err
+	}
+	contents := make(map[string]bool, len(notes))
+	for _, note := range notes {
+		contents[filepath.Join(strconv.Itoa(note.ID), note.Filename)] = true
+	}
+
+	var count int64
+	err := filepath.WalkDir(dir.NoteDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir.NoteDir, path)
+		if !contents[rel] {
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
@@ -42,6 +42,8 @@ func NewUserController(router gin.IRouter) *UserController {
 	r.GET("/avatar", User, res.avatar)
 	// 删除用户
 	r.DELETE("/delete", Admin, res.delete)
+	// 离职交接
+	r.POST("/offboard", Admin, res.offboard)
 	return res
 }
 
@@ -796,5 +798,105 @@ func (c *UserController) delete(ctx *gin.Context) {
 		ErrSys(ctx, err)
 		return
 	}
+	for _, id := range idArray {
+		revokeSessions(id)
+	}
+}
+
+/**
+@api {POST} /api/user/offboard 离职交接
+@apiDescription 用户离职交接，在同一事务中完成以下操作：
+<ul>
+ <li>用户拥有的笔记及文件夹转移至接收人名为"交接-姓名(用户名)"的文件夹下，文件夹结构保持不变</li>
+ <li>取消分享给该用户的笔记</li>
+ <li>退出所有用户组，用户为用户组拥有者时由接收人接任</li>
+ <li>禁用账号并吊销用户当前所有会话</li>
+</ul>
+交接报告记录于操作日志。员工管理系统同步离职（状态6）或SCIM禁用用户时，
+若配置了默认接收人（配置项 successor）将自动执行离职交接。
+@apiName UserOffboard
+@apiGroup User
+
+@apiPermission 管理员
+
+@apiParam {Integer} userId 离职用户ID，可以是已删除的用户。
+@apiParam {Integer} [successorId] 接收人用户ID，为空时使用配置的默认接收人。
+
+@apiParamExample {json} 请求示例
+{
+    "userId": 15,
+    "successorId": 20
+}
 
+@apiSuccess {Integer} userId 离职用户ID。
+@apiSuccess {String} username 离职用户名。
+@apiSuccess {Integer} successorId 接收人用户ID。
+@apiSuccess {String} successorName 接收人用户名。
+@apiSuccess {Integer} folderId 接收人的交接文件夹ID。
+@apiSuccess {Integer} notes 转移的笔记数量。
+@apiSuccess {Integer} folders 转移的文件夹数量。
+@apiSuccess {Integer} sharesRemoved 取消的分享给该用户的笔记数量。
+@apiSuccess {Integer} groups 退出的用户组数量。
+@apiSuccess {Integer} groupsTransferred 转交拥有者的用户组数量。
+@apiSuccess {String} trigger 触发方式：manual - 管理员、aync - 用户同步、scim - SCIM。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+    "userId": 15,
+    "username": "21011",
+    "successorId": 20,
+    "successorName": "20001",
+    "folderId": 88,
+    "notes": 32,
+    "folders": 5,
+    "sharesRemoved": 12,
+    "groups": 3,
+    "groupsTransferred": 1,
+    "trigger": "manual"
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400
+
+接收人不存在或已被删除
+*/
+
+// offboard 离职交接
+func (c *UserController) offboard(ctx *gin.Context) {
+	var info dto.OffboardDto
+	if err := ctx.ShouldBindJSON(&info); err != nil || info.UserId <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	var report *dto.OffboardReportDto
+	err := repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		successorId := info.SuccessorId
+		if successorId <= 0 {
+			id, err := findSuccessor(tx)
+			if err != nil {
+				return err
+			}
+			if id == 0 {
+				return illegalErr("请选择接收人")
+			}
+			successorId = id
+		}
+		var err error
+		report, err = offboardUser(tx, info.UserId, successorId, "manual")
+		return err
+	})
+	if illegal, ok := err.(illegalErr); ok {
+		ErrIllegal(ctx, string(illegal))
+		return
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	revokeSessions(report.UserId)
+	applog.L(ctx, "离职交接", report)
+	ctx.JSON(http.StatusOK, report)
 }
@@ -7,13 +7,18 @@ import (
 	"note/controller/dto"
 	"note/controller/middle"
 	"note/logg/applog"
+	"note/notify"
 	"note/repo"
 	"note/repo/entity"
 	"note/reuint"
 	"note/reuint/jwt"
+	"note/webhook"
 	"strconv"
 )
 
+// 用户组角色名称
+var groupRoleNames = map[int]string{0: "拥有者", 1: "普通用户", 2: "维护"}
+
 // NewUserGroupController 创建用户组控制器
 func NewUserGroupController(router gin.IRouter) *UserGroupController {
 	res := &UserGroupController{}
@@ -245,47 +250,15 @@ func (c *UserGroupController) add(ctx *gin.Context) {
 		return
 	}
 
-	// 创建用户角色
-	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
-		// 创建用户组成员记录
-		err = tx.Create(&reqInfo).Error
-		if err != nil {
-			return err
-		}
-
-		var notes []int
-		err = repo.DBDao.Model(&entity.NoteMember{}).Select("note_id").Where("group_id", info.GroupId).Find(&notes).Error
-		if err != nil {
-			return err
-		}
-		// 添加用户至用户组时将分享的笔记分享给该用户
-		for _, note := range notes {
-			exist, err = repo.NoteMemberRepo.Exist(reqInfo.UserId, note, "user")
-			if err != nil {
-				return err
-			}
-			// 若该用户未曾被分享过
-			if !exist {
-				var tmp entity.NoteMember
-
-				tmp.Role = reqInfo.Role
-				tmp.UserId = reqInfo.UserId
-				tmp.GroupId = reqInfo.Belong
-				tmp.NoteId = note
-				err = repo.DBDao.Create(&tmp).Error
-				if err != nil {
-					return err
-				}
-			}
-			// 若该用户已经被分享过则保留原有权限
-		}
-
-		return nil
-	})
+	// 创建用户角色，分享给用户组的笔记在访问时根据用户组授权计算权限
+	err = repo.DBDao.Create(&reqInfo).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
 	}
+	content := fmt.Sprintf("%s 将你加入用户组「%s」，角色：%s", operatorName(claims), groupName(info.GroupId), groupRoleNames[info.Role])
+	sendNotify(ctx, without([]int{info.UserId}, claims.Sub), notify.TypeGroupAdded, "加入用户组", content, 0)
+	emitGroupMember(ctx, webhook.EventGroupMemberAdded, info.GroupId, info.UserId, info.Role)
 
 }
 
@@ -379,25 +352,14 @@ func (c *UserGroupController) change(ctx *gin.Context) {
 
 	reqInfo.Role = info.Role
 
-	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
-		// 修改用户
-		err = tx.Save(&reqInfo).Error
-		if err != nil {
-			return err
-		}
-		// 修改笔记成员中的权限
-		err = tx.Model(&entity.NoteMember{}).Where("group_id", info.GroupId).Where("user_id", info.UserId).Update("role", reqInfo.Role).Error
-		if err != nil {
-			return err
-		}
-
-		return nil
-	})
-
+	err = repo.DBDao.Save(&reqInfo).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
 	}
+	content := fmt.Sprintf("%s 将你在用户组「%s」中的角色修改为%s", operatorName(claims), groupName(info.GroupId), groupRoleNames[info.Role])
+	sendNotify(ctx, without([]int{info.UserId}, claims.Sub), notify.TypeGroupRoleChanged, "用户组角色变更", content, 0)
+	emitGroupMember(ctx, webhook.EventGroupMemberUpdated, info.GroupId, info.UserId, info.Role)
 }
 
 /**
@@ -456,23 +418,30 @@ func (c *UserGroupController) delete(ctx *gin.Context) {
 	if res.Role == 0 {
 		return
 	}
-	// 取消删除用户的分享
-	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
-		err = tx.Delete(&entity.GroupMember{}, res.ID).Error
-		if err != nil {
-			return err
-		}
-		err = tx.Where("group_id", groupId).Where("user_id", id).Delete(&entity.NoteMember{}).Error
-		if err != nil {
-			return err
-		}
-		return nil
-	})
-
+	// 移出用户组后不再获得分享给该用户组的笔记权限
+	err = repo.DBDao.Delete(&entity.GroupMember{}, res.ID).Error
 	if err != nil {
 		ErrSys(ctx, err)
 		return
 	}
+
+	// 获取用户信息
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+	content := fmt.Sprintf("%s 将你移出用户组「%s」", operatorName(claims), groupName(groupId))
+	sendNotify(ctx, without([]int{id}, claims.Sub), notify.TypeGroupRemoved, "移出用户组", content, 0)
+	emitGroupMember(ctx, webhook.EventGroupMemberRemoved, groupId, id, res.Role)
+}
+
+// emitGroupMember 发布用户组成员变更事件，用户组成员及被变更的用户可接收
+// role: 成员的角色，移出时为移出前的角色
+func emitGroupMember(ctx *gin.Context, name string, groupId, userId, role int) {
+	emitEvent(ctx, &webhook.Event{
+		Name:    name,
+		GroupId: groupId,
+		Users:   []int{userId},
+		Data:    map[string]interface{}{"groupId": groupId, "groupName": groupName(groupId), "userId": userId, "role": role},
+	})
 }
 
 /**
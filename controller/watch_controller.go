@@ -0,0 +1,482 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/patrickmn/go-cache"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/mail"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint"
+	"note/reuint/jwt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	watchMaxPerUser = 200 // 每个用户最多关注的笔记及文件夹数量
+
+	noteBaselineTTL = 12 * time.Hour // 笔记变更比较基准的有效期
+	noteBaselineMax = 2000           // 笔记变更比较基准的最大记录数，达到上限后不再记录新的基准
+)
+
+// NewWatchController 创建关注控制器
+func NewWatchController(router gin.IRouter) *WatchController {
+	res := &WatchController{}
+	r := router.Group("/watch")
+	// 关注笔记或文件夹
+	r.POST("/create", User, res.create)
+	// 取消关注
+	r.DELETE("/delete", User, res.delete)
+	// 获取关注列表
+	r.GET("/list", User, res.list)
+	// 获取笔记或文件夹的关注状态
+	r.GET("/status", User, res.status)
+	// 获取变更摘要设置
+	r.GET("/setting", User, res.setting)
+	// 设置变更摘要
+	r.POST("/setting", User, res.setSetting)
+	return res
+}
+
+// WatchController 关注控制器
+// 用户关注笔记或文件夹后，他人手动保存其中的笔记时记录变更，按用户设置的频率（每小时、每天）
+// 汇总为变更摘要以站内通知及邮件发送，见 noteDaemon 包的变更摘要精灵。
+type WatchController struct {
+}
+
+/**
+@api {POST} /api/watch/create 关注笔记或文件夹
+@apiDescription 关注可访问的笔记或文件夹，关注文件夹时包含其子文件夹中的笔记。
+
+关注后他人手动保存笔记内容时，按变更摘要设置（/api/watch/setting）汇总发送，自己的保存不计入摘要。
+@apiName WatchCreate
+@apiGroup Watch
+
+@apiPermission 用户
+
+@apiParam {Integer} [noteId] 笔记ID，与文件夹ID仅可指定其一。
+@apiParam {Integer} [folderId] 文件夹ID，与笔记ID仅可指定其一。
+
+@apiParamExample {json} 请求示例
+{
+	"noteId": 12
+}
+
+@apiSuccess {Integer} id 关注ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"id": 5
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+已关注
+*/
+
+// create 关注笔记或文件夹
+func (c *WatchController) create(ctx *gin.Context) {
+	var info dto.WatchCreateDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "关注", info)
+	if err != nil || info.NoteId < 0 || info.FolderId < 0 || (info.NoteId > 0) == (info.FolderId > 0) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	// 判断是否拥有访问权限
+	role := -1
+	if info.NoteId > 0 {
+		var count int64
+		err = repo.DBDao.Model(&entity.Note{}).Where("id = ? AND is_delete = 0", info.NoteId).Count(&count).Error
+		if err == nil && count > 0 {
+			role, err = repo.NoteMemberRepo.Check(claims.Sub, info.NoteId)
+		}
+	} else {
+		role, err = repo.FolderRepo.Role(claims.Sub, info.FolderId)
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if role == -1 {
+		ErrIllegal(ctx, "笔记或文件夹不存在或无权限")
+		return
+	}
+
+	var watches []entity.Watch
+	err = repo.DBDao.Where("user_id = ?", claims.Sub).Find(&watches).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	for _, w := range watches {
+		if w.NoteId == info.NoteId && w.FolderId == info.FolderId {
+			ErrIllegal(ctx, "已关注")
+			return
+		}
+	}
+	if len(watches) >= watchMaxPerUser {
+		ErrIllegal(ctx, "关注数量已达上限")
+		return
+	}
+
+	watch := &entity.Watch{UserId: claims.Sub, NoteId: info.NoteId, FolderId: info.FolderId}
+	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(watch).Error; err != nil {
+			return err
+		}
+		// 首次关注时创建缺省的摘要设置，摘要自关注时开始
+		now := time.Now()
+		setting := entity.DefaultWatchSetting(claims.Sub)
+		setting.LastDigestAt = &now
+		return tx.Where("user_id = ?", claims.Sub).FirstOrCreate(&setting).Error
+	})
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, gin.H{"id": watch.ID})
+}
+
+/**
+@api {DELETE} /api/watch/delete 取消关注
+@apiDescription 取消关注笔记或文件夹。
+@apiName WatchDelete
+@apiGroup Watch
+
+@apiPermission 用户
+
+@apiParam {Integer} id 关注ID。
+
+@apiParamExample 请求示例
+DELETE /api/watch/delete?id=5
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+关注不存在
+*/
+
+// delete 取消关注
+func (c *WatchController) delete(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Query("id"))
+	// 记录日志
+	applog.L(ctx, "取消关注", map[string]interface{}{
+		"id": id,
+	})
+	if id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	res := repo.DBDao.Where("id = ? AND user_id = ?", id, claims.Sub).Delete(&entity.Watch{})
+	if res.Error != nil {
+		ErrSys(ctx, res.Error)
+		return
+	}
+	if res.RowsAffected == 0 {
+		ErrIllegal(ctx, "关注不存在")
+		return
+	}
+}
+
+/**
+@api {GET} /api/watch/list 获取关注列表
+@apiDescription 获取当前用户关注的笔记及文件夹，按关注时间倒序。
+@apiName WatchList
+@apiGroup Watch
+
+@apiPermission 用户
+
+@apiSuccess {Object[]} body 关注列表。
+@apiSuccess (Watch) {Integer} id 关注ID。
+@apiSuccess (Watch) {String} createdAt 关注时间。
+@apiSuccess (Watch) {Integer} noteId 笔记ID，0表示关注文件夹。
+@apiSuccess (Watch) {Integer} folderId 文件夹ID，0表示关注笔记。
+@apiSuccess (Watch) {String} name 笔记或文件夹名称。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+[
+	{
+		"id": 5,
+		"createdAt": "2026-10-19 10:12:03",
+		"noteId": 12,
+		"folderId": 0,
+		"name": "部署手册"
+	},
+	{
+		"id": 4,
+		"createdAt": "2026-10-18 16:40:11",
+		"noteId": 0,
+		"folderId": 3,
+		"name": "运维"
+	}
+]
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// list 获取关注列表
+func (c *WatchController) list(ctx *gin.Context) {
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	records := []dto.WatchListDto{}
+	err := repo.DBDao.Table("watches").
+		Select("watches.id, watches.created_at, watches.note_id, watches.folder_id, COALESCE(notes.title, folders.`name`, '') AS `name`").
+		Joins("LEFT JOIN notes ON notes.id = watches.note_id AND watches.note_id > 0").
+		Joins("LEFT JOIN folders ON folders.id = watches.folder_id AND watches.folder_id > 0").
+		Where("watches.user_id = ?", claims.Sub).
+		Order("watches.id desc").Find(&records).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, records)
+}
+
+/**
+@api {GET} /api/watch/status 获取关注状态
+@apiDescription 获取当前用户是否关注了笔记或文件夹，笔记ID与文件夹ID仅可指定其一。
+@apiName WatchStatus
+@apiGroup Watch
+
+@apiPermission 用户
+
+@apiParam {Integer} [noteId] 笔记ID。
+@apiParam {Integer} [folderId] 文件夹ID。
+
+@apiParamExample 请求示例
+GET /api/watch/status?noteId=12
+
+@apiSuccess {Boolean} watching 是否已关注。
+@apiSuccess {Integer} id 关注ID，未关注时为0。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"watching": true,
+	"id": 5
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// status 获取关注状态
+func (c *WatchController) status(ctx *gin.Context) {
+	noteId, _ := strconv.Atoi(ctx.DefaultQuery("noteId", "0"))
+	folderId, _ := strconv.Atoi(ctx.DefaultQuery("folderId", "0"))
+	if noteId < 0 || folderId < 0 || (noteId > 0) == (folderId > 0) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	var watch entity.Watch
+	err := repo.DBDao.Where("user_id = ? AND note_id = ? AND folder_id = ?", claims.Sub, noteId, folderId).
+		Limit(1).Find(&watch).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, gin.H{"watching": watch.ID > 0, "id": watch.ID})
+}
+
+/**
+@api {GET} /api/watch/setting 获取变更摘要设置
+@apiDescription 获取当前用户关注的笔记及文件夹的变更摘要设置，未设置时每天发送站内通知摘要。
+
+每小时摘要于整点发送，每天摘要于9时发送，摘要包含上次摘要以来他人手动保存的笔记、保存者及新增删除的行数，无变更时不发送。
+站内通知的类型为 digest，邮件发送至用户信息中的邮箱，系统未配置邮件服务器（配置项 mail）时不发送。
+@apiName WatchSetting
+@apiGroup Watch
+
+@apiPermission 用户
+
+@apiSuccess {String} frequency 摘要频率 hourly - 每小时 daily - 每天。
+@apiSuccess {Integer} inApp 是否发送站内通知摘要 0 - 不发送 1 - 发送。
+@apiSuccess {Integer} email 是否发送摘要邮件 0 - 不发送 1 - 发送，同邮件通知偏好（/api/notification/mailPreference）中的 digest。
+@apiSuccess {Boolean} mailEnabled 系统是否启用邮件通知。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"frequency": "daily",
+	"inApp": 1,
+	"email": 1,
+	"mailEnabled": true
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// setting 获取变更摘要设置
+func (c *WatchController) setting(ctx *gin.Context) {
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	setting := entity.DefaultWatchSetting(claims.Sub)
+	var list []entity.WatchSetting
+	err := repo.DBDao.Where("user_id = ?", claims.Sub).Limit(1).Find(&list).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	if len(list) > 0 {
+		setting = list[0]
+	}
+	pref, err := mail.Preference(claims.Sub)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, gin.H{
+		"frequency":   setting.Frequency,
+		"inApp":       setting.InApp,
+		"email":       pref.Digest,
+		"mailEnabled": mail.Enabled(),
+	})
+}
+
+/**
+@api {POST} /api/watch/setting 设置变更摘要
+@apiDescription 设置变更摘要的频率及发送方式，新的频率自下一个摘要周期生效。
+@apiName WatchSetSetting
+@apiGroup Watch
+
+@apiPermission 用户
+
+@apiParam {String=hourly,daily} frequency 摘要频率 hourly - 每小时 daily - 每天。
+@apiParam {Integer=0,1} inApp 是否发送站内通知摘要。
+@apiParam {Integer=0,1} email 是否发送摘要邮件。
+
+@apiParamExample {json} 请求示例
+{
+	"frequency": "hourly",
+	"inApp": 1,
+	"email": 0
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+参数非法，无法解析
+*/
+
+// setSetting 设置变更摘要
+func (c *WatchController) setSetting(ctx *gin.Context) {
+	var info dto.WatchSettingDto
+	err := ctx.BindJSON(&info)
+	if err != nil ||
+		(info.Frequency != entity.DigestHourly && info.Frequency != entity.DigestDaily) ||
+		(info.InApp != 0 && info.InApp != 1) || (info.Email != 0 && info.Email != 1) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		setting := entity.DefaultWatchSetting(claims.Sub)
+		setting.LastDigestAt = &now
+		err := tx.Where("user_id = ?", claims.Sub).Assign(map[string]interface{}{
+			"frequency": info.Frequency,
+			"in_app":    info.InApp,
+		}).FirstOrCreate(&setting).Error
+		if err != nil {
+			return err
+		}
+		pref := entity.DefaultMailPreference(claims.Sub)
+		return tx.Where("user_id = ?", claims.Sub).
+			Assign(map[string]interface{}{"digest": info.Email}).
+			FirstOrCreate(&pref).Error
+	})
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	applog.L(ctx, "设置变更摘要", info)
+}
+
+// noteBaselines 笔记上次手动保存后的内容，key：笔记ID
+// 自动保存覆盖文件前记录，手动保存时与之比较生成变更记录，
+// 程序重启、超过有效期或记录数达到上限时丢失，此时以手动保存前的文件内容为准。
+// 删除笔记时清除，批量删除及回收站清理的笔记在有效期后自动清除。
+var noteBaselines = cache.New(noteBaselineTTL, 10*time.Minute)
+
+// changeBaseline 获取笔记变更的比较基准，需在写入笔记文件前调用
+// 自动保存时记录基准（已记录时不覆盖），手动保存时取出基准并清除。
+func changeBaseline(noteId int, filename string, autoSave bool) string {
+	key := strconv.Itoa(noteId)
+	if v, ok := noteBaselines.Get(key); ok {
+		if !autoSave {
+			noteBaselines.Delete(key)
+		}
+		return v.(string)
+	}
+	data, _ := os.ReadFile(filename)
+	if autoSave && noteBaselines.ItemCount() < noteBaselineMax {
+		noteBaselines.Set(key, string(data), cache.DefaultExpiration)
+	}
+	return string(data)
+}
+
+// clearBaseline 清除笔记变更的比较基准
+func clearBaseline(noteId int) {
+	noteBaselines.Delete(strconv.Itoa(noteId))
+}
+
+// recordChange 记录手动保存的笔记变更并更新笔记修改时间，用于关注者的变更摘要
+// 内容无变化时仅更新修改时间，失败仅记录日志不影响保存。
+func recordChange(ctx *gin.Context, noteId, userId int, baseline, content string) {
+	err := repo.DBDao.Model(&entity.Note{}).Where("id = ?", noteId).UpdateColumn("updated_at", time.Now()).Error
+	if err == nil {
+		added, removed := reuint.LineDiff(baseline, content)
+		if added+removed > 0 {
+			err = repo.DBDao.Create(&entity.NoteChange{NoteId: noteId, UserId: userId, Added: added, Removed: removed}).Error
+		}
+	}
+	if err != nil {
+		middle.Logger(ctx).Warn("记录笔记变更失败", zap.Int("noteId", noteId), zap.Error(err))
+	}
+}
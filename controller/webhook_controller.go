@@ -0,0 +1,786 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net"
+	"net/url"
+	"note/controller/dto"
+	"note/controller/middle"
+	"note/logg/applog"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint/jwt"
+	"note/webhook"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	webhookMaxPerUser = 20 // 每个用户最多创建的订阅数量
+)
+
+// NewWebhookController 创建事件推送控制器
+func NewWebhookController(router gin.IRouter) *WebhookController {
+	res := &WebhookController{}
+	auth := Authenticate([]string{UserTypeAdmin, UserTypeUser})
+	r := router.Group("/webhook")
+	// 获取可订阅的事件
+	r.GET("/events", auth, res.events)
+	// 获取事件推送订阅列表
+	r.GET("/list", auth, res.list)
+	// 创建事件推送订阅
+	r.POST("/create", auth, res.create)
+	// 修改事件推送订阅
+	r.POST("/update", auth, res.update)
+	// 删除事件推送订阅
+	r.DELETE("/delete", auth, res.delete)
+	// 测试推送
+	r.POST("/ping", auth, res.ping)
+	// 获取推送记录列表
+	r.GET("/deliveries", auth, res.deliveries)
+	// 获取推送记录详情
+	r.GET("/delivery", auth, res.delivery)
+	// 重新推送
+	r.POST("/redeliver", auth, res.redeliver)
+	return res
+}
+
+// WebhookController 事件推送控制器
+// 管理员的订阅为系统订阅，所有管理员共同管理并接收全部事件；
+// 用户的订阅仅本人管理，仅接收有权限访问的笔记、文件夹及所在用户组的事件。
+type WebhookController struct {
+}
+
+/**
+@api {GET} /api/webhook/events 获取可订阅的事件
+@apiDescription 获取当前用户可订阅的事件，订阅时可使用通配符，如：note.*、group.member.*，* 表示全部事件。
+@apiName WebhookEvents
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiSuccess {String[]} events 事件名称列表
+<ul>
+	<li>note.created - 创建笔记</li>
+	<li>note.updated - 手动保存笔记内容</li>
+	<li>note.deleted - 删除笔记（移入回收站）</li>
+	<li>note.restored - 恢复笔记</li>
+	<li>note.shared - 笔记分享给用户或用户组，含修改分享权限</li>
+	<li>folder.created - 创建文件夹</li>
+	<li>folder.renamed - 文件夹重命名</li>
+	<li>folder.moved - 文件夹移动</li>
+	<li>folder.deleted - 删除文件夹</li>
+	<li>folder.shared - 文件夹分享给用户或用户组，含修改分享权限</li>
+	<li>folder.unshared - 取消分享文件夹</li>
+	<li>group.member.added - 用户组添加成员</li>
+	<li>group.member.updated - 修改用户组成员的角色</li>
+	<li>group.member.removed - 移出用户组成员</li>
+	<li>user.synced - 用户同步（员工管理系统同步、SCIM），仅管理员可订阅</li>
+</ul>
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+["note.created", "note.updated", "note.deleted"]
+*/
+
+// events 获取可订阅的事件
+func (c *WebhookController) events(ctx *gin.Context) {
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	res := []string{}
+	for _, event := range webhook.Events {
+		if claims.Type == UserTypeAdmin || event != webhook.EventUserSynced {
+			res = append(res, event)
+		}
+	}
+	ctx.JSON(200, res)
+}
+
+/**
+@api {GET} /api/webhook/list 获取事件推送订阅列表
+@apiDescription 管理员获取系统订阅，用户获取本人的订阅。
+@apiName WebhookList
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiSuccess {Webhook[]} list 订阅列表。
+@apiSuccess (Webhook) {Integer} id 订阅ID。
+@apiSuccess (Webhook) {String} createdAt 创建时间。
+@apiSuccess (Webhook) {String} updatedAt 更新时间。
+@apiSuccess (Webhook) {Integer} userId 订阅者ID，0表示管理员订阅。
+@apiSuccess (Webhook) {String} name 名称。
+@apiSuccess (Webhook) {String} url 推送地址。
+@apiSuccess (Webhook) {String[]} events 订阅的事件。
+@apiSuccess (Webhook) {Integer} enabled 是否启用 0 - 停用 1 - 启用。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+[
+	{
+		"id": 3,
+		"createdAt": "2026-10-19 10:00:00",
+		"updatedAt": "2026-10-19 10:00:00",
+		"userId": 31,
+		"name": "文档机器人",
+		"url": "https://bot.example.com/hooks/note",
+		"events": ["note.*", "folder.shared"],
+		"enabled": 1
+	}
+]
+
+@apiErrorExample 失败响应
+HTTP/1.1 500
+
+系统内部错误
+*/
+
+// list 获取事件推送订阅列表
+func (c *WebhookController) list(ctx *gin.Context) {
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	hooks := []entity.Webhook{}
+	err := repo.DBDao.Where("user_id = ?", hookOwner(claims)).Order("id desc").Find(&hooks).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, hooks)
+}
+
+/**
+@api {POST} /api/webhook/create 创建事件推送订阅
+@apiDescription 创建事件推送订阅，订阅的事件发生时以HTTP POST推送至推送地址。
+
+请求体为JSON：{"event": 事件名称, "createdAt": 事件时间, "operator": {"type": 操作者类型 user、admin、sync, "id": 操作者ID, "name": 操作者名称}, "data": 事件数据}；
+请求头 X-Webhook-Event 为事件名称，X-Webhook-Delivery 为推送记录ID，
+X-Webhook-Timestamp、X-Webhook-Nonce、X-Webhook-Signature 为时间戳（Unix秒）、随机数及签名Hex，
+签名算法与用户同步接口相同：HMAC-SM3(密钥, "POST\n推送地址的路径\n时间戳\n随机数\nHex(SM3(请求体))")。
+
+响应状态码为2xx时视为推送成功，否则分别间隔1分钟、5分钟、30分钟、2小时、6小时重试，全部失败后不再推送。
+每个用户最多创建20个订阅，用户不可订阅 user.synced 事件。
+推送不跟随重定向；用户订阅仅可推送至公网地址（推送时校验域名解析结果），推送记录不保存响应体。
+@apiName WebhookCreate
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiParam {String{..64}} name 名称。
+@apiParam {String} url 推送地址，仅支持http、https，用户订阅不可为内网地址且不跟随重定向。
+@apiParam {String[]} events 订阅的事件，见获取可订阅的事件。
+@apiParam {String{16..256}} [secret] 签名密钥，为空时自动生成。
+
+@apiParamExample {json} 请求示例
+{
+	"name": "文档机器人",
+	"url": "https://bot.example.com/hooks/note",
+	"events": ["note.*", "folder.shared"]
+}
+
+@apiSuccess {Integer} id 订阅ID。
+@apiSuccess {String} secret 签名密钥，仅在此时返回，请妥善保存。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"id": 3,
+	"secret": "9f86d081884c7d659a2feaa0c55ad015"
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+不支持的事件 note.archived
+*/
+
+// create 创建事件推送订阅
+func (c *WebhookController) create(ctx *gin.Context) {
+	var info dto.WebhookCreateDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "创建事件推送订阅", map[string]interface{}{
+		"name":   info.Name,
+		"url":    info.Url,
+		"events": info.Events,
+	})
+	if err != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	events, err := checkWebhook(claims, info.Name, info.Url, info.Events, info.Secret)
+	if err != nil {
+		ErrIllegalE(ctx, err)
+		return
+	}
+	if claims.Type != UserTypeAdmin {
+		var count int64
+		if err = repo.DBDao.Model(&entity.Webhook{}).Where("user_id = ?", claims.Sub).Count(&count).Error; err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+		if count >= webhookMaxPerUser {
+			ErrIllegal(ctx, "订阅数量已达上限")
+			return
+		}
+	}
+	if info.Secret == "" {
+		if info.Secret, err = randomHex(16); err != nil {
+			ErrSys(ctx, err)
+			return
+		}
+	}
+
+	hook := entity.Webhook{
+		UserId:  hookOwner(claims),
+		Name:    info.Name,
+		Url:     info.Url,
+		Secret:  info.Secret,
+		Events:  events,
+		Enabled: 1,
+	}
+	if err = repo.DBDao.Create(&hook).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, dto.WebhookSecretDto{Id: hook.ID, Secret: hook.Secret})
+}
+
+/**
+@api {POST} /api/webhook/update 修改事件推送订阅
+@apiDescription 修改事件推送订阅，停用后未完成的推送将标记为失败。
+@apiName WebhookUpdate
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiParam {Integer} id 订阅ID。
+@apiParam {String{..64}} name 名称。
+@apiParam {String} url 推送地址，仅支持http、https，用户订阅不可为内网地址且不跟随重定向。
+@apiParam {String[]} events 订阅的事件。
+@apiParam {Integer=0,1} enabled 是否启用 0 - 停用 1 - 启用。
+@apiParam {String{16..256}} [secret] 新的签名密钥，为空表示不修改。
+
+@apiParamExample {json} 请求示例
+{
+	"id": 3,
+	"name": "文档机器人",
+	"url": "https://bot.example.com/hooks/note",
+	"events": ["note.*"],
+	"enabled": 1
+}
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+订阅不存在
+*/
+
+// update 修改事件推送订阅
+func (c *WebhookController) update(ctx *gin.Context) {
+	var info dto.WebhookUpdateDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "修改事件推送订阅", map[string]interface{}{
+		"id":      info.Id,
+		"name":    info.Name,
+		"url":     info.Url,
+		"events":  info.Events,
+		"enabled": info.Enabled,
+		"secret":  info.Secret != "",
+	})
+	if err != nil || info.Id <= 0 || (info.Enabled != 0 && info.Enabled != 1) {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	hook, ok := findWebhook(ctx, claims, info.Id)
+	if !ok {
+		return
+	}
+	events, err := checkWebhook(claims, info.Name, info.Url, info.Events, info.Secret)
+	if err != nil {
+		ErrIllegalE(ctx, err)
+		return
+	}
+	hook.Name = info.Name
+	hook.Url = info.Url
+	hook.Events = events
+	hook.Enabled = info.Enabled
+	if info.Secret != "" {
+		hook.Secret = info.Secret
+	}
+	if err = repo.DBDao.Save(hook).Error; err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+}
+
+/**
+@api {DELETE} /api/webhook/delete 删除事件推送订阅
+@apiDescription 删除事件推送订阅及其推送记录。
+@apiName WebhookDelete
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiParam {Integer} id 订阅ID。
+
+@apiParamExample 请求示例
+DELETE /api/webhook/delete?id=3
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+订阅不存在
+*/
+
+// delete 删除事件推送订阅
+func (c *WebhookController) delete(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Query("id"))
+	// 记录日志
+	applog.L(ctx, "删除事件推送订阅", map[string]interface{}{
+		"id": id,
+	})
+	if id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	hook, ok := findWebhook(ctx, claims, id)
+	if !ok {
+		return
+	}
+	err := repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(hook).Error; err != nil {
+			return err
+		}
+		return tx.Where("webhook_id = ?", hook.ID).Delete(&entity.WebhookDelivery{}).Error
+	})
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+}
+
+/**
+@api {POST} /api/webhook/ping 测试推送
+@apiDescription 向订阅推送 ping 事件，用于验证推送地址及签名，推送结果见推送记录。
+@apiName WebhookPing
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiParam {Integer} id 订阅ID。
+
+@apiParamExample {json} 请求示例
+{
+	"id": 3
+}
+
+@apiSuccess {Integer} id 推送记录ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+128
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+订阅已停用
+*/
+
+// ping 测试推送
+func (c *WebhookController) ping(ctx *gin.Context) {
+	var info dto.WebhookIdDto
+	if err := ctx.BindJSON(&info); err != nil || info.Id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	hook, ok := findWebhook(ctx, claims, info.Id)
+	if !ok {
+		return
+	}
+	if hook.Enabled != 1 {
+		ErrIllegal(ctx, "订阅已停用")
+		return
+	}
+	delivery, err := webhook.Ping(hook)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, delivery.ID)
+}
+
+/**
+@api {GET} /api/webhook/deliveries 获取推送记录列表
+@apiDescription 获取订阅的推送记录，按时间倒序，推送记录保存30天。
+@apiName WebhookDeliveries
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiParam {Integer} id 订阅ID。
+@apiParam {Integer} [page=1] 页码。
+@apiParam {Integer} [limit=20] 每页数量。
+@apiParam {Integer=0,1,2,255} [status=255] 状态 0 - 待推送 1 - 成功 2 - 失败 255 - 全部。
+
+@apiParamExample 请求示例
+GET /api/webhook/deliveries?id=3&page=1&limit=20&status=2
+
+@apiSuccess {Delivery[]} records 查询结果列表。
+@apiSuccess {Integer} total 记录总数。
+@apiSuccess {Integer} size 每页显示条数。
+@apiSuccess {Integer} current 当前页。
+@apiSuccess {Integer} pages 总页数。
+@apiSuccess (Delivery) {Integer} id 推送记录ID。
+@apiSuccess (Delivery) {String} createdAt 创建时间。
+@apiSuccess (Delivery) {Integer} webhookId 订阅ID。
+@apiSuccess (Delivery) {String} event 事件名称。
+@apiSuccess (Delivery) {Integer} status 状态 0 - 待推送 1 - 成功 2 - 失败。
+@apiSuccess (Delivery) {Integer} attempts 已推送次数。
+@apiSuccess (Delivery) {Integer} statusCode 最后一次推送的响应状态码，0表示未收到响应。
+@apiSuccess (Delivery) {String} error 最后一次推送的错误信息。
+@apiSuccess (Delivery) {String} nextAt 下次推送时间。
+@apiSuccess (Delivery) {String} deliveredAt 最后一次推送时间。
+@apiSuccess (Delivery) {Integer} [redelivery] 重新推送的原推送记录ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"records": [{
+		"id": 128,
+		"createdAt": "2026-10-19 10:00:00",
+		"webhookId": 3,
+		"event": "note.updated",
+		"status": 0,
+		"attempts": 1,
+		"statusCode": 502,
+		"error": "响应状态码 502",
+		"nextAt": "2026-10-19 10:01:00",
+		"deliveredAt": "2026-10-19 10:00:00"
+	}],
+	"total": 1,
+	"size": 20,
+	"current": 1,
+	"pages": 1
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+订阅不存在
+*/
+
+// deliveries 获取推送记录列表
+func (c *WebhookController) deliveries(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Query("id"))
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page <= 0 || id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+	status, err := strconv.Atoi(ctx.DefaultQuery("status", "255"))
+	if err != nil {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	if _, ok := findWebhook(ctx, claims, id); !ok {
+		return
+	}
+	query, tx := repo.NewPageQueryFnc(repo.DBDao, &entity.WebhookDelivery{}, page, limit, func(db *gorm.DB) *gorm.DB {
+		db = db.Where("webhook_id = ?", id).Order("id desc")
+		if status != 255 {
+			db = db.Where("status = ?", status)
+		}
+		return db
+	})
+	records := []entity.WebhookDelivery{}
+	err = tx.Select("id, created_at, webhook_id, event, status, attempts, status_code, error, next_at, delivered_at, redelivery").
+		Find(&records).Error
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	query.Records = records
+	ctx.JSON(200, query)
+}
+
+/**
+@api {GET} /api/webhook/delivery 获取推送记录详情
+@apiDescription 获取推送记录详情，包括推送的请求体及最后一次推送的响应体。
+@apiName WebhookDelivery
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiParam {Integer} id 推送记录ID。
+
+@apiParamExample 请求示例
+GET /api/webhook/delivery?id=128
+
+@apiSuccess {Integer} id 推送记录ID。
+@apiSuccess {String} payload 推送的请求体。
+@apiSuccess {String} response 最后一次推送的响应体（最多1024个字符），仅管理员订阅保存。
+@apiSuccess {Object} others 其余字段同获取推送记录列表。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+{
+	"id": 128,
+	"createdAt": "2026-10-19 10:00:00",
+	"webhookId": 3,
+	"event": "note.updated",
+	"payload": "{\"event\":\"note.updated\",\"createdAt\":\"2026-10-19 10:00:00\",\"operator\":{\"type\":\"user\",\"id\":31,\"name\":\"王沁涛\"},\"data\":{\"id\":12,\"title\":\"接口设计\"}}",
+	"status": 1,
+	"attempts": 2,
+	"statusCode": 200,
+	"response": "ok",
+	"error": "",
+	"nextAt": null,
+	"deliveredAt": "2026-10-19 10:01:00"
+}
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+推送记录不存在
+*/
+
+// delivery 获取推送记录详情
+func (c *WebhookController) delivery(ctx *gin.Context) {
+	id, _ := strconv.Atoi(ctx.Query("id"))
+	if id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	delivery, ok := findDelivery(ctx, claims, id)
+	if !ok {
+		return
+	}
+	ctx.JSON(200, delivery)
+}
+
+/**
+@api {POST} /api/webhook/redeliver 重新推送
+@apiDescription 以相同的请求体重新推送，生成新的推送记录，订阅需处于启用状态。
+@apiName WebhookRedeliver
+@apiGroup Webhook
+
+@apiPermission 管理员,用户
+
+@apiParam {Integer} id 推送记录ID。
+
+@apiParamExample {json} 请求示例
+{
+	"id": 128
+}
+
+@apiSuccess {Integer} id 新的推送记录ID。
+
+@apiSuccessExample 成功响应
+HTTP/1.1 200 OK
+
+131
+
+@apiErrorExample 失败响应
+HTTP/1.1 400 Bad Request
+
+推送记录不存在
+*/
+
+// redeliver 重新推送
+func (c *WebhookController) redeliver(ctx *gin.Context) {
+	var info dto.WebhookIdDto
+	err := ctx.BindJSON(&info)
+	// 记录日志
+	applog.L(ctx, "重新推送事件", map[string]interface{}{
+		"id": info.Id,
+	})
+	if err != nil || info.Id <= 0 {
+		ErrIllegal(ctx, "参数非法，无法解析")
+		return
+	}
+
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	claims := claimsValue.(*jwt.Claims)
+
+	origin, ok := findDelivery(ctx, claims, info.Id)
+	if !ok {
+		return
+	}
+	hook, ok := findWebhook(ctx, claims, origin.WebhookId)
+	if !ok {
+		return
+	}
+	if hook.Enabled != 1 {
+		ErrIllegal(ctx, "订阅已停用")
+		return
+	}
+	delivery, err := webhook.Redeliver(origin)
+	if err != nil {
+		ErrSys(ctx, err)
+		return
+	}
+	ctx.JSON(200, delivery.ID)
+}
+
+// hookOwner 订阅者ID，管理员的订阅为系统订阅，订阅者ID为0
+func hookOwner(claims *jwt.Claims) int {
+	if claims.Type == UserTypeAdmin {
+		return 0
+	}
+	return claims.Sub
+}
+
+// findWebhook 查找当前用户可管理的订阅，不存在时返回错误响应
+// return: 订阅，是否存在
+func findWebhook(ctx *gin.Context, claims *jwt.Claims, id int) (*entity.Webhook, bool) {
+	var hook entity.Webhook
+	err := repo.DBDao.First(&hook, "id = ? AND user_id = ?", id, hookOwner(claims)).Error
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "订阅不存在")
+		return nil, false
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return nil, false
+	}
+	return &hook, true
+}
+
+// findDelivery 查找当前用户可管理的订阅的推送记录，不存在时返回错误响应
+// return: 推送记录，是否存在
+func findDelivery(ctx *gin.Context, claims *jwt.Claims, id int) (*entity.WebhookDelivery, bool) {
+	var delivery entity.WebhookDelivery
+	err := repo.DBDao.First(&delivery, "id = ? AND webhook_id IN (?)", id,
+		repo.DBDao.Model(&entity.Webhook{}).Select("id").Where("user_id = ?", hookOwner(claims))).Error
+	if err == gorm.ErrRecordNotFound {
+		ErrIllegal(ctx, "推送记录不存在")
+		return nil, false
+	}
+	if err != nil {
+		ErrSys(ctx, err)
+		return nil, false
+	}
+	return &delivery, true
+}
+
+// checkWebhook 检查订阅参数
+// return: 去重后以逗号分隔的订阅事件，参数非法时返回 illegalErr 错误
+func checkWebhook(claims *jwt.Claims, name, rawURL string, events []string, secret string) (string, error) {
+	if name == "" || utf8.RuneCountInString(name) > 64 {
+		return "", illegalErr("名称不可为空且不超过64个字符")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(rawURL) > 1024 {
+		return "", illegalErr("推送地址非法，仅支持http、https")
+	}
+	// 用户订阅仅可推送至公网地址，域名在推送时按解析结果校验
+	if claims.Type != UserTypeAdmin {
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") ||
+			(ip != nil && !webhook.IsPublicIP(ip)) {
+			return "", illegalErr(webhook.ErrPrivateAddress.Error())
+		}
+	}
+	if secret != "" && (len(secret) < 16 || len(secret) > 256) {
+		return "", illegalErr("签名密钥长度为16至256个字符")
+	}
+	if len(events) == 0 {
+		return "", illegalErr("请选择订阅的事件")
+	}
+	seen := map[string]bool{}
+	list := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if seen[event] {
+			continue
+		}
+		seen[event] = true
+		if !webhook.Valid(event) || strings.Contains(event, ",") {
+			return "", illegalErr("不支持的事件 " + event)
+		}
+		if claims.Type != UserTypeAdmin && event != "*" && webhook.Match(event, webhook.EventUserSynced) {
+			return "", illegalErr("仅管理员可订阅用户同步事件")
+		}
+		list = append(list, event)
+	}
+	res := strings.Join(list, ",")
+	if len(res) > 1024 {
+		return "", illegalErr("订阅的事件过多")
+	}
+	return res, nil
+}
+
+// emitEvent 发布事件推送，操作者为当前请求的用户
+func emitEvent(ctx *gin.Context, e *webhook.Event) {
+	claimsValue, _ := ctx.Get(middle.FlagClaims)
+	if claims, ok := claimsValue.(*jwt.Claims); ok {
+		e.Operator = webhook.Operator{Type: claims.Type, Id: claims.Sub, Name: operatorName(claims)}
+	}
+	webhook.Emit(e)
+}
+
+// emitUserSynced 发布用户同步事件
+// source: 同步来源 aync - 员工管理系统同步 scim - SCIM
+func emitUserSynced(user *entity.User, source string) {
+	webhook.Emit(&webhook.Event{
+		Name:     webhook.EventUserSynced,
+		Operator: webhook.Operator{Type: "sync", Name: "用户同步"},
+		Data: map[string]interface{}{
+			"id":             user.ID,
+			"username":       user.Username,
+			"name":           user.Name,
+			"openid":         user.Openid,
+			"departmentCode": user.DepartmentCode,
+			"state":          user.State,
+			"isDelete":       user.IsDelete,
+			"source":         source,
+		},
+	})
+}
@@ -0,0 +1,176 @@
+package applog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"note/appconf"
+	"note/repo"
+	"note/repo/entity"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hit 规则命中记录
+type hit struct {
+	at time.Time // 命中时间
+	n  int       // 计数
+}
+
+// alerter 审计告警引擎
+// 对写入缓冲区的操作日志逐条匹配告警规则，满足条件时写入告警收件箱并推送至告警地址。
+type alerter struct {
+	mu      sync.Mutex
+	rules   []appconf.AlertRule
+	webhook string
+	hits    map[string][]hit // 规则命中记录，key：规则名称[#操作者]
+	client  *http.Client
+}
+
+func newAlerter(cfg appconf.Alert) *alerter {
+	return &alerter{
+		rules:   cfg.Rules,
+		webhook: cfg.Webhook,
+		hits:    map[string][]hit{},
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// update 更新告警规则及推送地址，已有的命中记录将被清除
+func (a *alerter) update(cfg appconf.Alert) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.rules = cfg.Rules
+	a.webhook = cfg.Webhook
+	a.hits = map[string][]hit{}
+}
+
+// match 匹配告警规则
+// 注意该函数不应抛出任何错误，告警失败仅打印日志。
+func (a *alerter) match(record *entity.Log) {
+	if a == nil || record == nil {
+		return
+	}
+	now := time.Now()
+	var alerts []*entity.Alert
+
+	a.mu.Lock()
+	webhook := a.webhook
+	for _, rule := range a.rules {
+		if rule.OpName != record.OpName {
+			continue
+		}
+		n := countOf(rule.CountParam, record.OpParam)
+		if n <= 0 {
+			continue
+		}
+		if rule.Threshold <= 1 {
+			alerts = append(alerts, newAlert(rule, record, fmt.Sprintf("触发「%s」操作", rule.OpName)))
+			continue
+		}
+
+		key := rule.Name
+		if rule.PerUser {
+			key = fmt.Sprintf("%s#%d:%d", rule.Name, record.OpType, record.OpId)
+		}
+		// 清理时间窗口外的命中记录
+		since := now.Add(-time.Duration(rule.Window) * time.Minute)
+		kept := a.hits[key][:0]
+		total := 0
+		for _, h := range a.hits[key] {
+			if h.at.After(since) {
+				kept = append(kept, h)
+				total += h.n
+			}
+		}
+		kept = append(kept, hit{at: now, n: n})
+		total += n
+
+		if total >= rule.Threshold {
+			alerts = append(alerts, newAlert(rule, record,
+				fmt.Sprintf("%d分钟内「%s」操作计数%d，达到阈值%d", rule.Window, rule.OpName, total, rule.Threshold)))
+			// 告警后重新计数，防止重复告警
+			delete(a.hits, key)
+			continue
+		}
+		a.hits[key] = kept
+	}
+	a.mu.Unlock()
+
+	for _, alert := range alerts {
+		a.raise(alert, webhook)
+	}
+}
+
+// raise 写入告警收件箱并推送告警
+func (a *alerter) raise(alert *entity.Alert, webhook string) {
+	zap.L().Warn("审计告警", zap.String("rule", alert.RuleName), zap.String("content", alert.Content))
+	if err := repo.DBDao.Create(alert).Error; err != nil {
+		zap.L().Warn("告警写入失败", zap.Any("alert", alert), zap.Error(err))
+	}
+	if webhook == "" {
+		return
+	}
+	go func() {
+		body, _ := json.Marshal(alert)
+		resp, err := a.client.Post(webhook, "application/json", bytes.NewReader(body))
+		if err != nil {
+			zap.L().Warn("告警推送失败", zap.String("webhook", webhook), zap.Error(err))
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			zap.L().Warn("告警推送失败", zap.String("webhook", webhook), zap.Int("status", resp.StatusCode))
+		}
+	}()
+}
+
+// newAlert 根据规则与日志创建告警
+func newAlert(rule appconf.AlertRule, record *entity.Log, content string) *entity.Alert {
+	level := rule.Level
+	if level == "" {
+		level = "warn"
+	}
+	return &entity.Alert{
+		RuleName: rule.Name,
+		Level:    level,
+		OpType:   record.OpType,
+		OpId:     record.OpId,
+		OpName:   record.OpName,
+		Content:  content,
+	}
+}
+
+// countOf 计算日志的计数
+// param: 计数参数名称，为空时计数为1
+// opParam: 操作参数JSON
+func countOf(param string, opParam string) int {
+	if param == "" {
+		return 1
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(opParam), &m); err != nil {
+		return 1
+	}
+	v, ok := m[param]
+	if !ok || v == nil {
+		return 1
+	}
+	switch val := v.(type) {
+	case string:
+		n := 0
+		for _, item := range strings.Split(val, ",") {
+			if strings.TrimSpace(item) != "" {
+				n++
+			}
+		}
+		return n
+	case []interface{}:
+		return len(val)
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,295 @@
+package applog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"note/appconf/dir"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImportKeepHours 导入的归档日志保留时长（单位：小时）
+const ImportKeepHours = 24
+
+// 归档文件名称格式：oplog-YYYY-MM-DD[-N].jsonl.gz
+var archiveNameReg = regexp.MustCompile(`^oplog-\d{4}-\d{2}-\d{2}(-\d+)?\.jsonl\.gz$`)
+
+// ArchiveFile 归档文件信息
+type ArchiveFile struct {
+	Name      string `json:"name"`      // 归档文件名称
+	Day       string `json:"day"`       // 归档日期 YYYY-MM-DD
+	Size      int64  `json:"size"`      // 文件大小，单位B
+	Checksum  string `json:"checksum"`  // SM3摘要Hex
+	UpdatedAt string `json:"updatedAt"` // 归档时间 YYYY-MM-DD HH:mm:ss
+	Imported  int64  `json:"imported"`  // 已导入的日志条数，0表示未导入
+}
+
+// ValidArchiveName 判断归档文件名称是否合法
+func ValidArchiveName(name string) bool {
+	return archiveNameReg.MatchString(name)
+}
+
+// archiveExpired 归档并删除早于截止时间的操作日志
+// 按天归档，每天的日志归档成功后才会删除该天的日志。
+func archiveExpired(deadline time.Time) error {
+	var first entity.Log
+	err := repo.DBDao.Order("created_at").First(&first, "created_at < ?", deadline).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	y, m, d := first.CreatedAt.In(time.Local).Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, time.Local); day.Before(deadline); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		name, n, err := writeArchive(day, next)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			continue
+		}
+		err = repo.DBDao.Where("created_at >= ? AND created_at < ?", day, next).Delete(&entity.Log{}).Error
+		if err != nil {
+			return err
+		}
+		zap.L().Info("操作日志归档", zap.String("archive", name), zap.Int("count", n))
+	}
+	return nil
+}
+
+// writeArchive 将时间段内的日志写入归档文件，并生成SM3摘要文件
+// return: 归档文件名称, 归档条数, 错误
+func writeArchive(start, end time.Time) (string, int, error) {
+	var count int64
+	tx := repo.DBDao.Model(&entity.Log{}).Where("created_at >= ? AND created_at < ?", start, end)
+	if err := tx.Count(&count).Error; err != nil {
+		return "", 0, err
+	}
+	if count == 0 {
+		return "", 0, nil
+	}
+
+	// 同一天已存在归档文件时（如：调整了保存天数），使用序号区分
+	name := fmt.Sprintf("oplog-%s.jsonl.gz", start.Format("2006-01-02"))
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir.ArchiveDir, name)); os.IsNotExist(err) {
+			break
+		}
+		name = fmt.Sprintf("oplog-%s-%d.jsonl.gz", start.Format("2006-01-02"), i)
+	}
+	p := filepath.Join(dir.ArchiveDir, name)
+	tmp := p + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return "", 0, err
+	}
+	zw := gzip.NewWriter(file)
+	encoder := json.NewEncoder(zw)
+	n := 0
+	var records []entity.Log
+	err = repo.DBDao.Where("created_at >= ? AND created_at < ?", start, end).Order("id").
+		FindInBatches(&records, 500, func(_ *gorm.DB, _ int) error {
+			for i := range records {
+				if err := encoder.Encode(&records[i]); err != nil {
+					return err
+				}
+				n++
+			}
+			return nil
+		}).Error
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return "", 0, err
+	}
+	if err = os.Rename(tmp, p); err != nil {
+		_ = os.Remove(tmp)
+		return "", 0, err
+	}
+
+	// 生成SM3摘要文件，格式与 sm3sum 输出一致
+	sum, err := reuint.SM3File(p)
+	if err != nil {
+		return "", 0, err
+	}
+	err = os.WriteFile(p+".sm3", []byte(fmt.Sprintf("%s  %s\n", sum, name)), 0666)
+	if err != nil {
+		return "", 0, err
+	}
+	return name, n, nil
+}
+
+// ListArchives 归档文件列表，按归档日期降序排列
+func ListArchives() ([]ArchiveFile, error) {
+	entries, err := os.ReadDir(dir.ArchiveDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// 查询已导入的归档日志数量
+	var imported []struct {
+		Archive string
+		Count   int64
+	}
+	err = repo.DBDao.Model(&entity.ArchiveLog{}).Select("archive, COUNT(*) AS count").Group("archive").Find(&imported).Error
+	if err != nil {
+		return nil, err
+	}
+	importedMap := map[string]int64{}
+	for _, item := range imported {
+		importedMap[item.Archive] = item.Count
+	}
+
+	zone := time.FixedZone("CST", 8*3600)
+	res := []ArchiveFile{}
+	for _, entry := range entries {
+		if entry.IsDir() || !ValidArchiveName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		sum, _ := readChecksum(entry.Name())
+		res = append(res, ArchiveFile{
+			Name:      entry.Name(),
+			Day:       entry.Name()[len("oplog-") : len("oplog-")+len("2006-01-02")],
+			Size:      info.Size(),
+			Checksum:  sum,
+			UpdatedAt: info.ModTime().In(zone).Format("2006-01-02 15:04:05"),
+			Imported:  importedMap[entry.Name()],
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name > res[j].Name
+	})
+	return res, nil
+}
+
+// readChecksum 读取归档文件的SM3摘要
+func readChecksum(name string) (string, error) {
+	bin, err := os.ReadFile(filepath.Join(dir.ArchiveDir, name+".sm3"))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(bin))
+	if len(fields) == 0 {
+		return "", errors.New("摘要文件为空")
+	}
+	return fields[0], nil
+}
+
+// readArchive 校验并读取归档文件
+func readArchive(name string) ([]entity.Log, error) {
+	if !ValidArchiveName(name) {
+		return nil, errors.New("归档文件名称错误")
+	}
+	p := filepath.Join(dir.ArchiveDir, name)
+	expect, err := readChecksum(name)
+	if err != nil {
+		return nil, fmt.Errorf("摘要文件读取失败: %s", err.Error())
+	}
+	actual, err := reuint.SM3File(p)
+	if err != nil {
+		return nil, err
+	}
+	if expect != actual {
+		return nil, errors.New("归档文件摘要校验失败，文件可能已被篡改")
+	}
+
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	records := []entity.Log{}
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var record entity.Log
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// ImportArchive 校验归档文件并临时导入，用于检索
+// 重复导入同一归档文件时会覆盖之前的导入记录。
+// return: 导入条数, 错误
+func ImportArchive(name string) (int, error) {
+	records, err := readArchive(name)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	rows := make([]entity.ArchiveLog, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, entity.ArchiveLog{
+			CreatedAt:  record.CreatedAt,
+			LogId:      record.ID,
+			OpType:     record.OpType,
+			OpId:       record.OpId,
+			OpName:     record.OpName,
+			OpParam:    record.OpParam,
+			RequestId:  record.RequestId,
+			Archive:    name,
+			ImportedAt: now,
+		})
+	}
+	err = repo.DBDao.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("archive = ?", name).Delete(&entity.ArchiveLog{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, 500).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// ReleaseArchive 清除临时导入的归档日志
+func ReleaseArchive(name string) error {
+	return repo.DBDao.Where("archive = ?", name).Delete(&entity.ArchiveLog{}).Error
+}
+
+// cleanImported 清除超过保留时长的导入日志
+func cleanImported() {
+	deadline := time.Now().Add(-ImportKeepHours * time.Hour)
+	err := repo.DBDao.Where("imported_at < ?", deadline).Delete(&entity.ArchiveLog{}).Error
+	if err != nil {
+		zap.L().Warn("导入的归档日志清理失败", zap.Error(err))
+	}
+}
@@ -1,14 +1,18 @@
 package applog
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"note/appconf"
 	"note/controller/middle"
+	"note/metrics"
 	"note/repo"
 	"note/repo/entity"
 	"note/reuint/jwt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,19 +21,33 @@ var _globalL *Logger
 // Logger 日志模块
 type Logger struct {
 	buff        chan *entity.Log // 日志缓冲区
-	maxKeepDays int              // 日志最大存储时间（单位：天），注意若该值小于等于0则表示不删除。
+	maxKeepDays atomic.Int32     // 日志最大存储时间（单位：天），注意若该值小于等于0则表示不删除。
+	alert       *alerter         // 审计告警引擎
+
+	mu     sync.RWMutex  // 保护缓冲区关闭，防止向已关闭的缓冲区写入
+	closed bool          // 缓冲区是否已关闭
+	stop   chan struct{} // 精灵停止信号
+	done   chan struct{} // 缓冲区日志全部写入后关闭
 }
 
 // Log 写入日志
+// 日志模块关闭后写入的日志仅打印至系统日志。
 func (l *Logger) Log(record *entity.Log) {
 	if record == nil {
 		return
 	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		zap.L().Info("日志", zap.Any("record", record))
+		return
+	}
 	l.buff <- record
 }
 
 // daemon 日志精灵用于将缓存中的日志持久化
 func (l *Logger) daemon() {
+	defer close(l.done)
 	var err error
 	zap.L().Info("日志持久化存储精灵 [启动]")
 	for item := range l.buff {
@@ -38,18 +56,31 @@ func (l *Logger) daemon() {
 
 		if err != nil {
 			zap.L().Warn("日志写入失败", zap.Any("record", record), zap.Error(err))
+			metrics.OplogDropped.Inc()
 		}
+		// 匹配审计告警规则
+		l.alert.match(record)
 	}
+	zap.L().Info("日志持久化存储精灵 [停止]")
 }
 
 // 超时日志清理精灵
+// 超时日志按天归档至归档目录后再删除，同时清除超过保留时长的导入日志。
 // 注意该函数不应抛出任何错误，若有错误请手动恢复并打印，继续下一个循环。
 func (l *Logger) timeoutDeleteDaemon() {
-	if _globalL.maxKeepDays > 0 {
-		for {
-			now := time.Now().AddDate(0, 0, -_globalL.maxKeepDays).Format("2006-01-02 15:04:05")
-			repo.DBDao.Where("created_at < ?", now).Delete(&entity.Log{})
-			time.Sleep(24 * time.Hour)
+	for {
+		if days := int(l.maxKeepDays.Load()); days > 0 {
+			y, m, d := time.Now().AddDate(0, 0, -days).Date()
+			deadline := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+			if err := archiveExpired(deadline); err != nil {
+				zap.L().Warn("操作日志归档失败", zap.Error(err))
+			}
+		}
+		cleanImported()
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(24 * time.Hour):
 		}
 	}
 }
@@ -60,15 +91,56 @@ func InitLogger(cfg *appconf.Application) {
 		return
 	}
 	_globalL = &Logger{
-		buff:        make(chan *entity.Log, 32),
-		maxKeepDays: cfg.LogKeepMaxDays,
+		buff:  make(chan *entity.Log, 32),
+		alert: newAlerter(cfg.Alert),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
 	}
+	_globalL.maxKeepDays.Store(int32(cfg.LogKeepMaxDays))
+	metrics.GaugeFunc("oplog_buffer_depth", "操作日志缓冲区中待写入的日志数量", func() float64 {
+		return float64(len(_globalL.buff))
+	})
 	// 日志写入精灵
 	go _globalL.daemon()
 	// 日志超时删除精灵
 	go _globalL.timeoutDeleteDaemon()
 }
 
+// Reload 重新加载配置，包括日志保存天数及审计告警规则
+// 新的保存天数在下次清理时生效。
+func Reload(cfg *appconf.Application) {
+	if _globalL == nil {
+		return
+	}
+	_globalL.maxKeepDays.Store(int32(cfg.LogKeepMaxDays))
+	_globalL.alert.update(cfg.Alert)
+}
+
+// Shutdown 关闭日志模块
+// 停止接收新日志并等待缓冲区中的日志全部写入，超时后返回上下文的错误。
+func Shutdown(ctx context.Context) error {
+	l := _globalL
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.buff)
+	close(l.stop)
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // L 记录日志
 func L(ctx *gin.Context, name string, param interface{}) {
 	var record entity.Log
@@ -89,12 +161,24 @@ func L(ctx *gin.Context, name string, param interface{}) {
 		marshal, _ := json.Marshal(param)
 		record.OpParam = string(marshal)
 	}
+	record.RequestId = ctx.GetString(middle.FlagRequestId)
+
+	if _globalL == nil {
+		zap.L().Info("日志", zap.Any("record", record))
+		return
+	}
+	_globalL.Log(&record)
+}
 
+// Anonymous 记录匿名操作日志
+// 用于登录前等无法获取用户信息的场景，如：用户锁定。
+func Anonymous(name string, param interface{}) {
+	record := Init(entity.Log{}, "", 0, name, param)
 	if _globalL == nil {
 		zap.L().Info("日志", zap.Any("record", record))
 		return
 	}
-	_globalL.buff <- &record
+	_globalL.Log(record)
 }
 
 func Init(c entity.Log, claimsType string, id int, name string, param interface{}) *entity.Log {
@@ -6,6 +6,7 @@ import (
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 	"log"
+	"note/appconf"
 	"note/appconf/dir"
 	"os"
 	"path/filepath"
@@ -19,8 +20,9 @@ var (
 
 // InitConsole 初始化控制台日志，同时向文件和控制写入日志
 // 文件日志每天自动切分，保存180天，文件日志保存于工作目录下的 ./logs/ 目录
-func InitConsole(debug bool) *zap.Logger {
-	filename := filepath.Join(dir.LogDir, "note.log")
+// 文件日志格式由配置决定，json格式便于日志平台直接采集；日志级别可通过 Level 在运行时调整。
+func InitConsole(cfg *appconf.Application) *zap.Logger {
+	filename := filepath.Join(dir.LogDir, LogFileName)
 	// 创建文件目录
 	spliceFile := &lumberjack.Logger{
 		Filename:  filename,
@@ -44,19 +46,38 @@ func InitConsole(debug bool) *zap.Logger {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 		EncodeName:     zapcore.FullNameEncoder,
 	}
-	fileEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-	// 同时向控制台和文件写入日志
-	zapLevel := zapcore.InfoLevel
-	ginLevel := gin.ReleaseMode
+	consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
+	fileEncoder := consoleEncoder
+	if cfg.LogFormat == "json" {
+		fileEncoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
 
-	if debug {
-		zapLevel = zapcore.DebugLevel
+	ginLevel := gin.ReleaseMode
+	if cfg.Debug {
 		ginLevel = gin.DebugMode
 	}
+	SetDebug(cfg.Debug)
 
-	syncer := zapcore.NewMultiWriteSyncer(zapcore.AddSync(spliceFile), zapcore.AddSync(os.Stdout))
-	// 同时向控制台和文件写入日志
-	ZapLog = zap.New(zapcore.NewCore(fileEncoder, syncer, zapLevel), zap.AddCaller())
+	// 级别由 levelCore 统一过滤
+	all := zap.LevelEnablerFunc(func(zapcore.Level) bool { return true })
+	fileSyncer := zapcore.AddSync(spliceFile)
+	stdoutSyncer := zapcore.AddSync(os.Stdout)
+	var core zapcore.Core
+	var syncer zapcore.WriteSyncer
+	if cfg.LogFormat == "json" {
+		// 同时向控制台和文件写入日志，文件使用json格式
+		core = zapcore.NewTee(
+			zapcore.NewCore(fileEncoder, fileSyncer, all),
+			zapcore.NewCore(consoleEncoder, stdoutSyncer, all),
+		)
+		// 非结构化的日志不写入json文件，防止破坏文件格式
+		syncer = stdoutSyncer
+	} else {
+		syncer = zapcore.NewMultiWriteSyncer(fileSyncer, stdoutSyncer)
+		// 同时向控制台和文件写入日志
+		core = zapcore.NewCore(fileEncoder, syncer, all)
+	}
+	ZapLog = zap.New(&levelCore{Core: core}, zap.AddCaller())
 
 	zap.ReplaceGlobals(ZapLog)
 	gin.SetMode(ginLevel)
@@ -0,0 +1,126 @@
+package logg
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	// Level 系统日志全局级别，运行时可调整
+	Level = zap.NewAtomicLevel()
+
+	pkgMu     sync.RWMutex
+	pkgLevels = map[string]zapcore.Level{} // 包级别覆盖，key：包路径，如 note/controller
+)
+
+// SetDebug 根据调试模式调整全局级别，调试模式为debug，否则为info
+func SetDebug(debug bool) {
+	if debug {
+		Level.SetLevel(zapcore.DebugLevel)
+	} else {
+		Level.SetLevel(zapcore.InfoLevel)
+	}
+}
+
+// PackageLevel 包级别覆盖
+type PackageLevel struct {
+	Package string `json:"package"` // 包路径，覆盖该包及其子包，如 note/logg
+	Level   string `json:"level"`   // 日志级别
+}
+
+// SetPackageLevel 设置包级别覆盖
+func SetPackageLevel(pkg string, lvl zapcore.Level) {
+	pkgMu.Lock()
+	defer pkgMu.Unlock()
+	pkgLevels[strings.TrimSuffix(pkg, "/")] = lvl
+}
+
+// RemovePackageLevel 删除包级别覆盖
+func RemovePackageLevel(pkg string) {
+	pkgMu.Lock()
+	defer pkgMu.Unlock()
+	delete(pkgLevels, strings.TrimSuffix(pkg, "/"))
+}
+
+// PackageLevels 返回所有包级别覆盖，按包路径排序
+func PackageLevels() []PackageLevel {
+	pkgMu.RLock()
+	defer pkgMu.RUnlock()
+	res := []PackageLevel{}
+	for pkg, lvl := range pkgLevels {
+		res = append(res, PackageLevel{Package: pkg, Level: lvl.String()})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Package < res[j].Package
+	})
+	return res
+}
+
+// minLevel 全局级别与所有包级别覆盖中的最低级别
+func minLevel() zapcore.Level {
+	lvl := Level.Level()
+	pkgMu.RLock()
+	defer pkgMu.RUnlock()
+	for _, l := range pkgLevels {
+		if l < lvl {
+			lvl = l
+		}
+	}
+	return lvl
+}
+
+// levelOf 返回调用函数所属包的日志级别，使用最长匹配的包级别覆盖
+// function: 调用函数全名，如 note/controller.(*NoteController).list
+func levelOf(function string) zapcore.Level {
+	pkgMu.RLock()
+	defer pkgMu.RUnlock()
+	if len(pkgLevels) == 0 || function == "" {
+		return Level.Level()
+	}
+	pkg := function
+	slash := strings.LastIndex(pkg, "/")
+	if dot := strings.Index(pkg[slash+1:], "."); dot >= 0 {
+		pkg = pkg[:slash+1+dot]
+	}
+	for {
+		if lvl, ok := pkgLevels[pkg]; ok {
+			return lvl
+		}
+		i := strings.LastIndex(pkg, "/")
+		if i < 0 {
+			return Level.Level()
+		}
+		pkg = pkg[:i]
+	}
+}
+
+// levelCore 按全局级别及包级别覆盖过滤日志
+// 调用位置在 Check 之后才会填充，因此包级别过滤在 Write 时进行。
+type levelCore struct {
+	zapcore.Core
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= minLevel()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields)}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *levelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level < levelOf(ent.Caller.Function) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
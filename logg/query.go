@@ -0,0 +1,299 @@
+package logg
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"go.uber.org/zap/zapcore"
+	"io"
+	"note/appconf/dir"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LogFileName 系统日志文件名称
+const LogFileName = "note.log"
+
+// Entry 系统日志条目
+type Entry struct {
+	Time    time.Time `json:"time"`    // 日志时间
+	Level   string    `json:"level"`   // 日志级别
+	Caller  string    `json:"caller"`  // 调用位置
+	Message string    `json:"message"` // 日志消息
+	Fields  string    `json:"fields"`  // 附加字段（JSON）、堆栈等
+	File    string    `json:"file"`    // 所属日志文件
+}
+
+// Query 系统日志查询条件
+type Query struct {
+	Start   time.Time     // 开始时间，零值表示不限
+	End     time.Time     // 截止时间，零值表示不限
+	Level   zapcore.Level // 最低日志级别
+	Keyword string        // 消息或附加字段包含的内容
+	Caller  string        // 调用位置包含的内容
+	Limit   int           // 最大返回条数
+}
+
+// Match 判断日志条目是否满足查询条件
+func (q *Query) Match(e *Entry) bool {
+	if !q.Start.IsZero() && e.Time.Before(q.Start) {
+		return false
+	}
+	if !q.End.IsZero() && e.Time.After(q.End) {
+		return false
+	}
+	var lvl zapcore.Level
+	if lvl.UnmarshalText([]byte(e.Level)) == nil && lvl < q.Level {
+		return false
+	}
+	if q.Caller != "" && !strings.Contains(e.Caller, q.Caller) {
+		return false
+	}
+	if q.Keyword != "" && !strings.Contains(e.Message, q.Keyword) && !strings.Contains(e.Fields, q.Keyword) {
+		return false
+	}
+	return true
+}
+
+// ParseLine 解析一行console或json格式的日志
+// console格式：时间\t级别\t调用位置\t消息[\t附加字段]，不符合格式的行（如堆栈）返回false。
+func ParseLine(line string) (*Entry, bool) {
+	if strings.HasPrefix(line, "{") {
+		return parseJSONLine(line)
+	}
+	parts := strings.SplitN(line, "\t", 5)
+	if len(parts) < 4 {
+		return nil, false
+	}
+	t, err := time.Parse(time.RFC3339, parts[0])
+	if err != nil {
+		return nil, false
+	}
+	e := &Entry{Time: t, Level: parts[1], Caller: parts[2], Message: parts[3]}
+	if len(parts) == 5 {
+		e.Fields = parts[4]
+	}
+	return e, true
+}
+
+// parseJSONLine 解析一行json格式的日志，除时间、级别、调用位置、消息外的字段作为附加字段
+func parseJSONLine(line string) (*Entry, bool) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &m); err != nil {
+		return nil, false
+	}
+	var ts string
+	if err := json.Unmarshal(m["ts"], &ts); err != nil {
+		return nil, false
+	}
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return nil, false
+	}
+	e := &Entry{Time: t}
+	_ = json.Unmarshal(m["level"], &e.Level)
+	_ = json.Unmarshal(m["caller"], &e.Caller)
+	_ = json.Unmarshal(m["msg"], &e.Message)
+	for _, key := range []string{"ts", "level", "caller", "msg"} {
+		delete(m, key)
+	}
+	if len(m) > 0 {
+		fields, _ := json.Marshal(m)
+		e.Fields = string(fields)
+	}
+	return e, true
+}
+
+// LogFiles 返回当前及切分后的系统日志文件路径，按时间降序排列
+// since 不为零值时忽略最后修改时间早于该时间的文件。
+func LogFiles(since time.Time) ([]string, error) {
+	ext := filepath.Ext(LogFileName)
+	prefix := strings.TrimSuffix(LogFileName, ext) + "-"
+	entries, err := os.ReadDir(dir.LogDir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, item := range entries {
+		name := item.Name()
+		if item.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if !strings.HasSuffix(name, ext) && !strings.HasSuffix(name, ext+".gz") {
+			continue
+		}
+		if !since.IsZero() {
+			if info, err := item.Info(); err != nil || info.ModTime().Before(since) {
+				continue
+			}
+		}
+		backups = append(backups, filepath.Join(dir.LogDir, name))
+	}
+	// 切分文件名称包含切分时间，名称降序即时间降序
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+
+	res := []string{}
+	current := filepath.Join(dir.LogDir, LogFileName)
+	if _, err = os.Stat(current); err == nil {
+		res = append(res, current)
+	}
+	return append(res, backups...), nil
+}
+
+// Search 在系统日志文件中搜索，结果按时间降序排列
+func Search(q Query) ([]Entry, error) {
+	files, err := LogFiles(q.Start)
+	if err != nil {
+		return nil, err
+	}
+	res := []Entry{}
+	for _, filename := range files {
+		matched, err := searchFile(filename, &q)
+		if err != nil {
+			return nil, err
+		}
+		// 文件内日志按时间升序，逆序追加
+		for i := len(matched) - 1; i >= 0; i-- {
+			res = append(res, matched[i])
+			if q.Limit > 0 && len(res) >= q.Limit {
+				return res, nil
+			}
+		}
+	}
+	return res, nil
+}
+
+// searchFile 搜索单个日志文件，gz后缀的文件自动解压
+func searchFile(filename string, q *Query) ([]Entry, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(filename, ".gz") {
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	var res []Entry
+	var last *Entry
+	flush := func() {
+		if last != nil && q.Match(last) {
+			res = append(res, *last)
+		}
+		last = nil
+	}
+	name := filepath.Base(filename)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if e, ok := ParseLine(line); ok {
+			flush()
+			e.File = name
+			last = e
+			continue
+		}
+		// 堆栈等多行内容归属于上一条日志
+		if last != nil && line != "" {
+			last.Fields += "\n" + line
+		}
+	}
+	flush()
+	return res, scanner.Err()
+}
+
+// Follow 跟踪当前系统日志文件新写入的行，直到上下文结束
+// 日志文件切分后自动重新打开新文件，fn 返回错误时停止跟踪。
+func Follow(ctx context.Context, interval time.Duration, fn func(line string) error) error {
+	filename := filepath.Join(dir.LogDir, LogFileName)
+	var file *os.File
+	var reader *bufio.Reader
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+	}()
+
+	open := func(seekEnd bool) error {
+		if file != nil {
+			_ = file.Close()
+			file = nil
+		}
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		if seekEnd {
+			if _, err = f.Seek(0, io.SeekEnd); err != nil {
+				_ = f.Close()
+				return err
+			}
+		}
+		file = f
+		reader = bufio.NewReader(f)
+		return nil
+	}
+	if err := open(true); err != nil {
+		return err
+	}
+
+	pending := ""
+	drain := func() error {
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				// 不完整的行等待下次读取
+				pending += line
+				return nil
+			}
+			if err = fn(strings.TrimRight(pending+line, "\r\n")); err != nil {
+				return err
+			}
+			pending = ""
+		}
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := drain(); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		// 检查文件是否已切分
+		cur, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		latest, err := os.Stat(filename)
+		if err != nil || os.SameFile(cur, latest) {
+			// 切分过程中文件可能短暂不存在
+			continue
+		}
+		// 读取旧文件剩余内容后切换至新文件
+		if err = drain(); err != nil {
+			return err
+		}
+		if err = open(false); err != nil {
+			return err
+		}
+		pending = ""
+	}
+}
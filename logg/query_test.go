@@ -0,0 +1,54 @@
+package logg
+
+import (
+	"go.uber.org/zap/zapcore"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	e, ok := ParseLine("2024-01-01T12:00:00+08:00\twarn\tapplog/alert.go:102\t告警推送失败\t{\"status\": 500}")
+	if !ok {
+		t.Fatal("parse failed")
+	}
+	if e.Level != "warn" || e.Caller != "applog/alert.go:102" || e.Message != "告警推送失败" || e.Fields != "{\"status\": 500}" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if _, ok = ParseLine("goroutine 1 [running]:"); ok {
+		t.Fatal("stack line should not be parsed")
+	}
+
+	q := Query{Level: zapcore.ErrorLevel}
+	if q.Match(e) {
+		t.Fatal("warn should be filtered by error level")
+	}
+	q = Query{Level: zapcore.InfoLevel, Keyword: "500", Caller: "applog/"}
+	if !q.Match(e) {
+		t.Fatal("entry should match")
+	}
+}
+
+func TestParseJSONLine(t *testing.T) {
+	e, ok := ParseLine(`{"level":"error","ts":"2024-01-01T12:00:00+08:00","caller":"repo/init.go:20","msg":"连接失败","error":"timeout"}`)
+	if !ok {
+		t.Fatal("parse failed")
+	}
+	if e.Level != "error" || e.Caller != "repo/init.go:20" || e.Message != "连接失败" || e.Fields != `{"error":"timeout"}` {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+}
+
+func TestLevelOf(t *testing.T) {
+	Level.SetLevel(zapcore.InfoLevel)
+	SetPackageLevel("note/logg", zapcore.DebugLevel)
+	defer RemovePackageLevel("note/logg")
+
+	if lvl := levelOf("note/logg/applog.(*Logger).daemon"); lvl != zapcore.DebugLevel {
+		t.Fatalf("levelOf() = %s, want debug", lvl)
+	}
+	if lvl := levelOf("note/controller.(*NoteController).list"); lvl != zapcore.InfoLevel {
+		t.Fatalf("levelOf() = %s, want info", lvl)
+	}
+	if minLevel() != zapcore.DebugLevel {
+		t.Fatal("minLevel() should be debug")
+	}
+}
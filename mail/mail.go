@@ -0,0 +1,321 @@
+package mail
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"note/appconf"
+	"note/metrics"
+	"note/repo"
+	"note/repo/entity"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _globalM *Mailer
+
+// 首次重试的间隔，此后每次重试间隔加倍
+var retryDelay = time.Minute
+
+// Message 待发送的邮件
+type Message struct {
+	To      string // 收件人地址
+	Subject string // 主题
+	Body    string // HTML正文
+
+	attempts int       // 已发送次数
+	nextAt   time.Time // 下次重试时间
+
+	// 收件人用户ID，不为空时由发送精灵查询收件人邮箱及通知偏好并为每人渲染邮件，见 expand
+	recipients []int
+	ev         event // 邮件通知类型，仅 recipients 不为空时有效
+	data       *Data // 邮件模板数据，仅 recipients 不为空时有效
+}
+
+// Mailer 邮件发送模块
+// 邮件写入队列后由精灵异步发送，发送失败按配置的次数重试，重试间隔依次加倍。
+type Mailer struct {
+	cfg   atomic.Pointer[appconf.Mail] // 当前生效的邮件配置
+	queue chan *Message                // 待发送队列
+
+	mu     sync.RWMutex  // 保护队列关闭，防止向已关闭的队列写入
+	closed bool          // 队列是否已关闭
+	done   chan struct{} // 队列中的邮件全部处理后关闭
+}
+
+func newMailer(cfg appconf.Mail) *Mailer {
+	m := &Mailer{
+		queue: make(chan *Message, 128),
+		done:  make(chan struct{}),
+	}
+	m.cfg.Store(&cfg)
+	return m
+}
+
+// Enqueue 邮件写入发送队列
+// 未启用邮件通知、模块已关闭或队列已满时丢弃邮件，不阻塞业务处理。
+func (m *Mailer) Enqueue(msg *Message) {
+	if msg == nil || !m.cfg.Load().Enabled() {
+		return
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		zap.L().Warn("邮件模块已关闭，丢弃邮件", zap.String("to", msg.To), zap.Ints("recipients", msg.recipients),
+			zap.String("subject", msg.Subject))
+		return
+	}
+	select {
+	case m.queue <- msg:
+	default:
+		zap.L().Warn("邮件队列已满，丢弃邮件", zap.String("to", msg.To), zap.Ints("recipients", msg.recipients),
+			zap.String("subject", msg.Subject))
+	}
+}
+
+// daemon 邮件发送精灵
+// 队列关闭后处理完队列中剩余的邮件即退出，等待重试的邮件将被丢弃。
+func (m *Mailer) daemon() {
+	defer close(m.done)
+	zap.L().Info("邮件发送精灵 [启动]")
+	var retries []*Message
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		// 按最早的重试时间设置定时器
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		if len(retries) > 0 {
+			next := retries[0].nextAt
+			for _, msg := range retries[1:] {
+				if msg.nextAt.Before(next) {
+					next = msg.nextAt
+				}
+			}
+			timer.Reset(time.Until(next))
+		}
+
+		select {
+		case msg, ok := <-m.queue:
+			if !ok {
+				if len(retries) > 0 {
+					zap.L().Warn("邮件模块关闭，丢弃等待重试的邮件", zap.Int("count", len(retries)))
+				}
+				zap.L().Info("邮件发送精灵 [停止]")
+				return
+			}
+			for _, item := range m.expand(msg) {
+				if m.deliver(item) {
+					retries = append(retries, item)
+				}
+			}
+		case <-timer.C:
+			now := time.Now()
+			rest := retries[:0]
+			for _, msg := range retries {
+				if msg.nextAt.After(now) || m.deliver(msg) {
+					rest = append(rest, msg)
+				}
+			}
+			retries = rest
+		}
+	}
+}
+
+// deliver 发送邮件
+// return: 是否需要重试
+func (m *Mailer) deliver(msg *Message) bool {
+	cfg := m.cfg.Load()
+	if !cfg.Enabled() {
+		return false
+	}
+	msg.attempts++
+	err := send(cfg, msg)
+	if err == nil {
+		return false
+	}
+	if msg.attempts > cfg.Retries {
+		zap.L().Warn("邮件发送失败", zap.String("to", msg.To), zap.String("subject", msg.Subject),
+			zap.Int("attempts", msg.attempts), zap.Error(err))
+		return false
+	}
+	delay := retryDelay << (msg.attempts - 1)
+	msg.nextAt = time.Now().Add(delay)
+	zap.L().Info("邮件发送失败，等待重试", zap.String("to", msg.To), zap.String("subject", msg.Subject),
+		zap.Int("attempts", msg.attempts), zap.Duration("delay", delay), zap.Error(err))
+	return true
+}
+
+// shutdown 停止接收新邮件并等待队列中的邮件处理完成，超时后返回上下文的错误
+func (m *Mailer) shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	close(m.queue)
+	m.mu.Unlock()
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Init 初始化邮件发送模块
+// 未配置SMTP服务器时同样初始化，以便热加载配置后启用。
+func Init(cfg *appconf.Application) {
+	if _globalM != nil {
+		return
+	}
+	_globalM = newMailer(cfg.Mail)
+	metrics.GaugeFunc("mail_queue_depth", "邮件队列中待发送的邮件数量", func() float64 {
+		return float64(len(_globalM.queue))
+	})
+	go _globalM.daemon()
+}
+
+// Reload 重新加载邮件配置，新配置对之后发送（含重试）的邮件生效
+func Reload(cfg *appconf.Application) {
+	if _globalM == nil {
+		return
+	}
+	c := cfg.Mail
+	_globalM.cfg.Store(&c)
+}
+
+// Shutdown 关闭邮件发送模块
+// 停止接收新邮件并等待队列中的邮件发送完成，超时后返回上下文的错误。
+func Shutdown(ctx context.Context) error {
+	if _globalM == nil {
+		return nil
+	}
+	return _globalM.shutdown(ctx)
+}
+
+// Notify 将站内通知同时以邮件发送给接收者
+// 仅分享、权限申请及分享到期提醒类通知发送邮件，变更摘要邮件见 Digest，
+// 接收者未设置邮箱或在通知偏好中关闭了该类邮件时不发送。
+// 收件人在发送精灵中查询，不阻塞业务处理。
+// 注意该函数不返回错误，失败仅打印日志。
+// userIds: 接收者ID，接收者收到相同的通知
+func Notify(typ, title, content string, userIds []int) {
+	ev, ok := events[typ]
+	if !ok {
+		return
+	}
+	notifyUsers(userIds, ev, &Data{Title: title, Content: content})
+}
+
+// Digest 发送关注笔记的变更摘要邮件
+// 接收者未设置邮箱或在通知偏好中关闭了摘要邮件时不发送。
+// 注意该函数不返回错误，失败仅打印日志。
+func Digest(userId int, title, content string, items []DigestItem) {
+	notifyUsers([]int{userId}, digest, &Data{Title: title, Content: content, Items: items})
+}
+
+// notifyUsers 邮件写入发送队列，由发送精灵按接收者的邮件通知偏好渲染，见 expand
+func notifyUsers(userIds []int, ev event, data *Data) {
+	m := _globalM
+	if m == nil || len(userIds) == 0 {
+		return
+	}
+	m.Enqueue(&Message{Subject: data.Title, recipients: userIds, ev: ev, data: data})
+}
+
+// expand 查询收件人的邮箱及邮件通知偏好，为每个收件人渲染邮件
+// 在发送精灵中执行，收件人及偏好均批量查询。未指定收件人用户ID的邮件原样返回。
+func (m *Mailer) expand(msg *Message) []*Message {
+	if len(msg.recipients) == 0 {
+		return []*Message{msg}
+	}
+	var users []entity.User
+	err := repo.DBDao.Select("id, name, email").
+		Where("id IN ? AND is_delete = 0 AND email <> ''", msg.recipients).Find(&users).Error
+	if err != nil {
+		zap.L().Warn("查询邮件收件人失败", zap.Ints("userIds", msg.recipients), zap.Error(err))
+		return nil
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	var prefs []entity.MailPreference
+	if err = repo.DBDao.Where("user_id IN ?", msg.recipients).Find(&prefs).Error; err != nil {
+		zap.L().Warn("查询邮件通知偏好失败", zap.Ints("userIds", msg.recipients), zap.Error(err))
+		return nil
+	}
+	prefMap := make(map[int]entity.MailPreference, len(prefs))
+	for _, pref := range prefs {
+		prefMap[pref.UserId] = pref
+	}
+
+	href := link(m.cfg.Load())
+	res := make([]*Message, 0, len(users))
+	for _, user := range users {
+		pref, ok := prefMap[user.ID]
+		if !ok {
+			pref = entity.DefaultMailPreference(user.ID)
+		}
+		if !msg.ev.enabled(&pref) {
+			continue
+		}
+		data := *msg.data
+		data.Name = user.Name
+		data.Link = href
+		body, err := render(msg.ev.template, &data)
+		if err != nil {
+			zap.L().Warn("邮件模板渲染失败", zap.String("template", msg.ev.template), zap.Error(err))
+			return res
+		}
+		res = append(res, &Message{To: user.Email, Subject: data.Title, Body: body})
+	}
+	return res
+}
+
+// Preference 获取用户的邮件通知偏好，未设置时返回缺省偏好
+func Preference(userId int) (entity.MailPreference, error) {
+	var list []entity.MailPreference
+	err := repo.DBDao.Where("user_id = ?", userId).Limit(1).Find(&list).Error
+	if err != nil || len(list) == 0 {
+		return entity.DefaultMailPreference(userId), err
+	}
+	return list[0], nil
+}
+
+// event 发送邮件的通知类型
+type event struct {
+	template string                              // 邮件模板名称
+	enabled  func(p *entity.MailPreference) bool // 用户是否接收该类邮件
+}
+
+// 发送邮件的通知类型，key：通知类型
+var events = map[string]event{
+	"share_granted":  {"share_granted", func(p *entity.MailPreference) bool { return p.ShareGranted == 1 }},
+	"share_changed":  {"share_granted", func(p *entity.MailPreference) bool { return p.ShareGranted == 1 }},
+	"access_request": {"access_request", func(p *entity.MailPreference) bool { return p.AccessRequest == 1 }},
+	"share_expiring": {"share_expiring", func(p *entity.MailPreference) bool { return p.ShareExpiring == 1 }},
+}
+
+// 变更摘要邮件
+var digest = event{"digest", func(p *entity.MailPreference) bool { return p.Digest == 1 }}
+
+// link 邮件中前往系统的链接，未配置系统访问地址时为空
+func link(cfg *appconf.Mail) string {
+	if cfg.BaseUrl == "" {
+		return ""
+	}
+	return strings.TrimRight(cfg.BaseUrl, "/") + "/ui/#/index/noteList"
+}
+
+// Enabled 系统是否启用邮件通知
+func Enabled() bool {
+	return _globalM != nil && _globalM.cfg.Load().Enabled()
+}
@@ -0,0 +1,226 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"mime"
+	"net"
+	"net/textproto"
+	"note/appconf"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTP 本地SMTP测试服务器，仅实现发送邮件所需的命令
+type fakeSMTP struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	failures int           // 前若干次发送返回临时错误
+	received chan []string // 收到的邮件，内容为DATA的各行
+}
+
+func newFakeSMTP(t *testing.T, failures int) *fakeSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln, failures: failures, received: make(chan []string, 8)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch cmd {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			lines, err := tp.ReadDotLines()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			fail := s.failures > 0
+			s.failures--
+			s.mu.Unlock()
+			if fail {
+				_ = tp.PrintfLine("451 Try again later")
+				continue
+			}
+			s.received <- lines
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) config() appconf.Mail {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := net.LookupPort("tcp", port)
+	return appconf.Mail{Host: host, Port: p, Security: "starttls", From: "笔记系统 <note@example.com>", Retries: 2}
+}
+
+// parse 解析邮件主题及正文
+func parse(t *testing.T, lines []string) (subject, body string) {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(strings.Join(lines, "\r\n") + "\r\n")))
+	header, err := r.ReadMIMEHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err = new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded strings.Builder
+	for {
+		line, err := r.ReadLine()
+		if err != nil {
+			break
+		}
+		encoded.WriteString(line)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return subject, string(raw)
+}
+
+func TestSend(t *testing.T) {
+	s := newFakeSMTP(t, 0)
+	cfg := s.config()
+	content := strings.Repeat("王沁涛 将笔记《接口设计》分享给你，权限：可编辑 ", 10)
+	body, err := render("share_granted", &Data{Name: "张三", Title: "笔记分享", Content: content, Link: "https://note.example.com/ui/#/index/noteList"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = send(&cfg, &Message{To: "zhangsan@example.com", Subject: "笔记分享", Body: body}); err != nil {
+		t.Fatal(err)
+	}
+	subject, actual := parse(t, <-s.received)
+	if subject != "笔记分享" {
+		t.Fatalf("subject = %q, want %q", subject, "笔记分享")
+	}
+	if !strings.Contains(actual, content) || !strings.Contains(actual, "张三，你好") {
+		t.Fatalf("body not contains content: %s", actual)
+	}
+}
+
+func TestRenderEscape(t *testing.T) {
+	body, err := render("access_request", &Data{Name: "<b>张三</b>", Content: "<script>alert(1)</script>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(body, "<script>") || strings.Contains(body, "<b>") {
+		t.Fatalf("content not escaped: %s", body)
+	}
+	if strings.Contains(body, "前往笔记系统查看") {
+		t.Fatalf("link should be hidden when empty")
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	body, err := render(digest.template, &Data{
+		Name:    "张三",
+		Content: "2 篇关注的笔记有更新。",
+		Items: []DigestItem{
+			{Title: "部署手册", Editors: "李四、王五", UpdatedAt: "2026-10-19 10:00", Summary: "新增 12 行，删除 3 行"},
+			{Title: "<i>周报</i>", Editors: "李四", UpdatedAt: "2026-10-19 09:30", Summary: "新增 1 行，删除 0 行"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"部署手册", "李四、王五", "新增 12 行，删除 3 行", "2026-10-19 09:30"} {
+		if !strings.Contains(body, s) {
+			t.Fatalf("body not contains %q: %s", s, body)
+		}
+	}
+	if strings.Contains(body, "<i>") {
+		t.Fatalf("item not escaped: %s", body)
+	}
+}
+
+func TestQueueRetry(t *testing.T) {
+	old := retryDelay
+	retryDelay = 20 * time.Millisecond
+	defer func() { retryDelay = old }()
+
+	s := newFakeSMTP(t, 2)
+	m := newMailer(s.config())
+	go m.daemon()
+	m.Enqueue(&Message{To: "zhangsan@example.com", Subject: "分享即将到期", Body: "<p>test</p>"})
+
+	select {
+	case lines := <-s.received:
+		if subject, _ := parse(t, lines); subject != "分享即将到期" {
+			t.Fatalf("subject = %q", subject)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not delivered after retry")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 关闭后的邮件被丢弃
+	m.Enqueue(&Message{To: "zhangsan@example.com", Subject: "closed", Body: "closed"})
+}
+
+func TestQueueGiveUp(t *testing.T) {
+	old := retryDelay
+	retryDelay = 10 * time.Millisecond
+	defer func() { retryDelay = old }()
+
+	s := newFakeSMTP(t, 10)
+	cfg := s.config()
+	cfg.Retries = 1
+	m := newMailer(cfg)
+	go m.daemon()
+	msg := &Message{To: "zhangsan@example.com", Subject: "test", Body: "test"}
+	m.Enqueue(msg)
+
+	time.Sleep(200 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := m.shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if msg.attempts != cfg.Retries+1 {
+		t.Fatalf("attempts = %d, want %d", msg.attempts, cfg.Retries+1)
+	}
+	select {
+	case <-s.received:
+		t.Fatal("message should not be delivered")
+	default:
+	}
+}
@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// 邮件模板，每个模板由公共布局 layout.html 及同名的内容模板组成，key：模板名称
+var templates = map[string]*template.Template{}
+
+func init() {
+	for _, name := range []string{"share_granted", "access_request", "share_expiring", "digest"} {
+		templates[name] = template.Must(template.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+	}
+}
+
+// Data 邮件模板数据
+type Data struct {
+	Name    string       // 收件人姓名
+	Title   string       // 标题
+	Content string       // 内容
+	Link    string       // 前往系统的链接，为空时不显示
+	Items   []DigestItem // 变更摘要条目，仅用于摘要邮件
+}
+
+// DigestItem 变更摘要条目
+type DigestItem struct {
+	Title     string // 笔记名称
+	Editors   string // 更新者姓名，多个以顿号分隔
+	UpdatedAt string // 最后更新时间
+	Summary   string // 变更内容摘要
+}
+
+// render 渲染邮件正文
+func render(name string, data *Data) (string, error) {
+	t, ok := templates[name]
+	if !ok {
+		return "", fmt.Errorf("邮件模板 %s 不存在", name)
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -1,64 +1,221 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"log"
+	"net"
 	"net/http"
 	"note/appconf"
 	"note/appconf/dir"
 	"note/controller"
+	"note/controller/middle"
 	"note/logg"
 	"note/logg/applog"
+	"note/mail"
+	"note/metrics"
 	"note/noteDaemon"
 	"note/repo"
+	"note/webhook"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
-	// 初始化各级目录
-	dir.Init()
 	// 加载配置文件配置
-	appcfg := appconf.Load()
+	appcfg, err := appconf.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// 初始化各级目录
+	if err = dir.Init(appcfg.DataDir); err != nil {
+		log.Fatalln("目录初始化失败", err)
+	}
 	// 初始化日志
-	logg.InitConsole(appcfg.Debug)
+	logg.InitConsole(appcfg)
+	for _, warning := range appcfg.Warnings() {
+		zap.L().Warn(warning)
+	}
 	// 数据库初始化
-	err := repo.Init(appcfg)
+	err = repo.Init(appcfg)
 	if err != nil {
 		zap.L().Fatal("持久层初始化失败", zap.Error(err))
 	}
+	if err = repo.DBDao.Use(&metrics.GormPlugin{}); err != nil {
+		zap.L().Fatal("数据库监控插件注册失败", zap.Error(err))
+	}
 	// 初始化操作日志模块
 	applog.InitLogger(appcfg)
 	// 初始化笔记定时清除模块
 	noteDaemon.InitNote(appcfg)
+	// 初始化邮件发送模块
+	mail.Init(appcfg)
+	// 初始化事件推送模块
+	webhook.Init()
 
-	// 启动用户同步服务
-	go bootUserSyncSever(appcfg)
+	// 请求的根上下文，关闭时取消以结束SSE等长连接
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 
-	// 启动Web服务器
+	// Web服务器、用户同步服务、监控指标服务
 	server := NewHttpServer(appcfg)
-	if err = server.ListenAndServe(); err != nil {
-		zap.L().Fatal("服务启动失败", zap.Error(err))
+	syncServer, err := newUserSyncServer(appcfg)
+	if err != nil {
+		zap.L().Fatal("用户同步服务配置错误", zap.Error(err))
 	}
+	if server.TLSConfig, err = newTLSConfig(appcfg, false); err != nil {
+		zap.L().Fatal("HTTPS配置错误", zap.Error(err))
+	}
+	if syncServer.TLSConfig, err = newTLSConfig(appcfg, true); err != nil {
+		zap.L().Fatal("HTTPS配置错误", zap.Error(err))
+	}
+	tlcpServer, tlcpListener, err := newTLCPServer(appcfg, server.Handler)
+	if err != nil {
+		zap.L().Fatal("TLCP配置错误", zap.Error(err))
+	}
+	servers := []*http.Server{server, syncServer}
+	if appcfg.MetricsPort > 0 {
+		metrics.Init()
+		servers = append(servers, newMetricsServer(appcfg))
+	}
+	for _, server := range servers {
+		server.BaseContext = func(net.Listener) context.Context { return baseCtx }
+		go serve(server)
+	}
+	// TLCP服务与Web服务共用路由，监听已在创建时完成握手配置
+	if tlcpServer != nil {
+		tlcpServer.BaseContext = func(net.Listener) context.Context { return baseCtx }
+		servers = append(servers, tlcpServer)
+		go serveListener(tlcpServer, tlcpListener)
+	}
+
+	// 等待退出信号，期间监听配置文件变化
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go watchConfig(ctx, appcfg, os.Args[1:])
+	<-ctx.Done()
+	stop()
+	shutdown(appcfg, servers, cancelBase)
 }
 
-// bootUserSyncSever 启动用户同步服务
-func bootUserSyncSever(config *appconf.Application) {
+// serve 启动服务，服务关闭以外的错误直接退出
+// 设置了HTTPS配置的服务使用HTTPS，证书已在配置中加载。
+func serve(server *http.Server) {
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		zap.L().Fatal("服务启动失败", zap.String("addr", server.Addr), zap.Error(err))
+	}
+}
+
+// serveListener 在给定监听上启动服务，服务关闭以外的错误直接退出
+func serveListener(server *http.Server, ln net.Listener) {
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		zap.L().Fatal("服务启动失败", zap.String("addr", server.Addr), zap.Error(err))
+	}
+}
+
+// shutdown 优雅关闭
+// 停止接收新请求并等待处理中的请求完成，随后写入缓冲区中的操作日志、停止各精灵并关闭数据库连接。
+func shutdown(config *appconf.Application, servers []*http.Server, cancelBase context.CancelFunc) {
+	timeout := time.Duration(config.ShutdownTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	zap.L().Info("系统关闭 [开始]", zap.Duration("timeout", timeout))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				zap.L().Warn("服务关闭超时", zap.String("addr", server.Addr), zap.Error(err))
+			}
+		}(server)
+	}
+	// 结束长连接，否则等待至超时
+	cancelBase()
+	wg.Wait()
+
+	noteDaemon.Shutdown()
+	if err := webhook.Shutdown(ctx); err != nil {
+		zap.L().Warn("事件分发超时，部分事件可能丢失", zap.Error(err))
+	}
+	if err := mail.Shutdown(ctx); err != nil {
+		zap.L().Warn("邮件发送超时，部分邮件可能丢失", zap.Error(err))
+	}
+	if err := applog.Shutdown(ctx); err != nil {
+		zap.L().Warn("操作日志写入超时，部分日志可能丢失", zap.Error(err))
+	}
+	if err := repo.Close(); err != nil {
+		zap.L().Warn("数据库连接关闭失败", zap.Error(err))
+	}
+	zap.L().Info("系统关闭 [完成]")
+	_ = zap.L().Sync()
+}
+
+// newUserSyncServer 创建用户同步服务
+// 用户同步接口需通过签名认证，见 controller.NewSyncAuth，SCIM接口使用令牌认证，见 controller.NewScimAuth。
+func newUserSyncServer(config *appconf.Application) (*http.Server, error) {
+	auth, err := controller.NewSyncAuth(config.SyncAuth)
+	if err != nil {
+		return nil, err
+	}
+	ipFilter, err := controller.NewSyncIPFilter(config.SyncAuth.AllowIPs)
+	if err != nil {
+		return nil, err
+	}
 	var r *gin.Engine
 	if config.Debug {
 		r = gin.Default()
 	} else {
 		r = gin.New()
 	}
+	// 来源IP过滤依赖 ClientIP，仅信任配置的反向代理设置的 X-Forwarded-For，防止伪造请求头绕过
+	if err = r.SetTrustedProxies(config.SyncAuth.TrustedProxies); err != nil {
+		return nil, err
+	}
 
-	route := r.Group("/api")
-	controller.NewAyncController(route)
-	zap.L().Info("系统启动", zap.Int("syncPort", config.SyncPort))
-	server := http.Server{
+	r.Use(middle.RequestId, middle.Recovery(), metrics.Middleware, ipFilter)
+	route := r.Group("/api", auth)
+	controller.NewAyncController(route, config.StatePolicies)
+	// SCIM 2.0 用户供应，配置令牌后启用
+	if config.Scim.Token != "" {
+		controller.NewScimController(r.Group("/scim/v2", controller.NewScimAuth(config.Scim.Token)))
+	}
+	zap.L().Info("系统启动", zap.Int("syncPort", config.SyncPort),
+		zap.Bool("https", config.TLS.Enabled()), zap.Bool("clientAuth", config.TLS.ClientCAFile != ""),
+		zap.Int("allowIPs", len(config.SyncAuth.AllowIPs)), zap.Bool("scim", config.Scim.Token != ""))
+	return &http.Server{
 		Addr:    fmt.Sprintf(":%d", config.SyncPort),
 		Handler: r,
-	}
-	err := server.ListenAndServe()
-	if err != nil {
-		zap.L().Fatal("同步服务启动失败", zap.Error(err))
+	}, nil
+}
+
+// newMetricsServer 创建监控指标服务
+func newMetricsServer(config *appconf.Application) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	zap.L().Info("系统启动", zap.Int("metricsPort", config.MetricsPort))
+	return &http.Server{
+		Addr:    fmt.Sprintf(":%d", config.MetricsPort),
+		Handler: mux,
 	}
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"note/appconf"
+	"testing"
+)
+
+func TestUserSyncServerClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		want           int
+	}{
+		// 伪造的 X-Forwarded-For 不可绕过来源IP限制
+		{"伪造请求头", nil, "203.0.113.9:40000", http.StatusForbidden},
+		{"非可信代理", []string{"192.168.1.1"}, "203.0.113.9:40000", http.StatusForbidden},
+		// 来源IP通过后进入签名认证
+		{"可信代理", []string{"192.168.1.1"}, "192.168.1.1:40000", http.StatusUnauthorized},
+		{"直连", nil, "10.1.2.3:40000", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &appconf.Application{SyncPort: 9001}
+			cfg.SyncAuth.Secret = "0123456789abcdef"
+			cfg.SyncAuth.AllowIPs = []string{"10.0.0.0/8"}
+			cfg.SyncAuth.TrustedProxies = tt.trustedProxies
+			server, err := newUserSyncServer(cfg)
+			if err != nil {
+				t.Fatalf("newUserSyncServer() error = %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/user/aync", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "10.1.2.3")
+			w := httptest.NewRecorder()
+			server.Handler.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
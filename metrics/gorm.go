@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+const startKey = "metrics:start"
+
+// GormPlugin 数据库操作耗时统计插件
+type GormPlugin struct {
+}
+
+// Name 插件名称
+func (p *GormPlugin) Name() string {
+	return "metrics"
+}
+
+// Initialize 注册各类操作前后的回调
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	registers := []struct {
+		operation string
+		before    func(name string, fn func(*gorm.DB)) error
+		after     func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register},
+		{"query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register},
+		{"update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register},
+		{"delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register},
+		{"row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register},
+		{"raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register},
+	}
+	for _, item := range registers {
+		if err := item.before("metrics:before_"+item.operation, before); err != nil {
+			return err
+		}
+		if err := item.after("metrics:after_"+item.operation, after(item.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func before(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+func after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		v, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := v.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		dbLatency.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"note/appconf/dir"
+	"note/reuint"
+	"strconv"
+	"time"
+)
+
+const namespace = "note"
+
+var (
+	// httpRequests HTTP请求计数
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP请求总数",
+	}, []string{"method", "route", "status"})
+
+	// httpLatency HTTP请求耗时
+	httpLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP请求耗时（单位：秒）",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// dbLatency 数据库操作耗时
+	dbLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "数据库操作耗时（单位：秒）",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+
+	// noteStorage 笔记存储区大小
+	noteStorage = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "note_storage_bytes",
+		Help:      "笔记存储区大小（单位：B）",
+	})
+
+	// OplogDropped 写入失败而丢弃的操作日志数量
+	OplogDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "oplog_dropped_total",
+		Help:      "写入失败而丢弃的操作日志数量",
+	})
+
+	// NotePurgeRuns 过期笔记清理执行次数
+	NotePurgeRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "note_purge_runs_total",
+		Help:      "过期笔记清理执行次数",
+	}, []string{"result"})
+
+	// NotePurged 清理的过期笔记数量
+	NotePurged = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "note_purged_total",
+		Help:      "清理的过期笔记数量",
+	})
+
+	// NotePurgeLastRun 最近一次过期笔记清理的时间（Unix秒）
+	NotePurgeLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "note_purge_last_run_timestamp_seconds",
+		Help:      "最近一次过期笔记清理的时间（Unix秒）",
+	})
+)
+
+// GaugeFunc 注册由函数取值的指标，如：编辑锁数量、操作日志缓冲区深度
+// 该函数在控制器等构造函数中调用，可能重复调用（如多次注册路由），同名指标已注册时沿用已注册的指标，
+// 因此取值函数应读取包级变量而非构造时的局部对象。
+func GaugeFunc(name, help string, fn func() float64) {
+	err := prometheus.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn))
+	var registered prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &registered) {
+		zap.L().Warn("指标注册失败", zap.String("name", name), zap.Error(err))
+	}
+}
+
+// Middleware HTTP请求指标拦截器
+// 以路由模板作为标签，防止路径参数导致标签数量膨胀。
+func Middleware(ctx *gin.Context) {
+	start := time.Now()
+	ctx.Next()
+
+	route := ctx.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	method := ctx.Request.Method
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(ctx.Writer.Status())).Inc()
+	httpLatency.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+}
+
+// storageDaemon 定时统计笔记存储区大小
+func storageDaemon(interval time.Duration) {
+	for {
+		size, err := reuint.DirSize(dir.NoteDir)
+		if err != nil {
+			zap.L().Warn("笔记存储区大小统计失败", zap.Error(err))
+		} else {
+			noteStorage.Set(float64(size))
+		}
+		time.Sleep(interval)
+	}
+}
+
+// Init 初始化指标模块，启动笔记存储区大小统计精灵
+func Init() {
+	go storageDaemon(10 * time.Minute)
+}
@@ -0,0 +1,292 @@
+package noteDaemon
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"note/mail"
+	"note/notify"
+	"note/repo"
+	"note/repo/entity"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	digestCheckInterval = 5 * time.Minute // 变更摘要检查间隔
+	digestDailyHour     = 9               // 每天摘要的发送时间（时）
+	digestMaxNotes      = 50              // 摘要包含的最大笔记数量
+	digestContentMaxLen = 500             // 站内通知摘要内容的最大长度（字符）
+	changeKeepDays      = 30              // 笔记变更记录保存天数
+)
+
+// noteDigest 关注笔记在摘要周期内的变更
+type noteDigest struct {
+	NoteId    int
+	Title     string    // 笔记名称
+	Editors   []string  // 更新者姓名，按首次更新排列
+	Added     int       // 新增行数
+	Removed   int       // 删除行数
+	UpdatedAt time.Time // 最后更新时间
+}
+
+// noteChange 笔记变更记录及保存者姓名
+type noteChange struct {
+	NoteId    int
+	Name      string
+	Added     int
+	Removed   int
+	CreatedAt time.Time
+}
+
+// 变更摘要精灵，按用户设置的频率发送关注笔记的变更摘要，并清理过期的变更记录
+// 注意该函数不应抛出任何错误，若有错误请打印，继续下一个循环。
+func (l *Note) digestDaemon() {
+	var cleaned time.Time
+	for {
+		l.sendDigests()
+		if time.Since(cleaned) > 24*time.Hour {
+			cleaned = time.Now()
+			deadline := cleaned.AddDate(0, 0, -changeKeepDays)
+			if err := repo.DBDao.Where("created_at < ?", deadline).Delete(&entity.NoteChange{}).Error; err != nil {
+				zap.L().Warn("清理笔记变更记录失败", zap.Error(err))
+			}
+		}
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(digestCheckInterval):
+		}
+	}
+}
+
+// sendDigests 向到达摘要周期的关注者发送变更摘要
+func (l *Note) sendDigests() {
+	// 变更记录按秒保存，摘要区间取整秒防止遗漏或重复
+	now := time.Now().Truncate(time.Second)
+	var users []int
+	err := repo.DBDao.Table("watches").
+		Joins("INNER JOIN users ON users.id = watches.user_id AND users.is_delete = 0").
+		Distinct("watches.user_id").Pluck("watches.user_id", &users).Error
+	if err != nil {
+		zap.L().Warn("查询关注者失败", zap.Error(err))
+		return
+	}
+	for _, userId := range users {
+		select {
+		case <-l.stop:
+			return
+		default:
+		}
+		if err = digestUser(userId, now); err != nil {
+			zap.L().Warn("发送变更摘要失败", zap.Int("userId", userId), zap.Error(err))
+		}
+	}
+}
+
+// digestPeriod 获取当前摘要周期的开始时间
+// 每小时摘要于整点发送，每天摘要于 digestDailyHour 时发送。
+func digestPeriod(frequency string, now time.Time) time.Time {
+	if frequency == entity.DigestHourly {
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), digestDailyHour, 0, 0, 0, now.Location())
+	if now.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// digestUser 用户本周期未发送摘要时，汇总上次摘要以来关注笔记的变更并发送
+// 无变更时不发送，但同样记录摘要时间。
+func digestUser(userId int, now time.Time) error {
+	setting := entity.DefaultWatchSetting(userId)
+	var list []entity.WatchSetting
+	if err := repo.DBDao.Where("user_id = ?", userId).Limit(1).Find(&list).Error; err != nil {
+		return err
+	}
+	if len(list) > 0 {
+		setting = list[0]
+	}
+	if setting.LastDigestAt != nil && !setting.LastDigestAt.Before(digestPeriod(setting.Frequency, now)) {
+		return nil
+	}
+	since := now.AddDate(0, 0, -changeKeepDays)
+	if setting.LastDigestAt != nil {
+		since = *setting.LastDigestAt
+	}
+
+	notes, err := collectChanges(userId, since, now)
+	if err != nil {
+		return err
+	}
+	if len(notes) > 0 {
+		sendDigest(userId, &setting, since, notes)
+	}
+	return repo.DBDao.Where("user_id = ?", userId).
+		Assign(map[string]interface{}{"last_digest_at": now}).
+		FirstOrCreate(&setting).Error
+}
+
+// watchedNotes 获取用户关注的笔记及开始关注的时间，关注文件夹时包含其子文件夹中的笔记
+// return: 笔记ID - 开始关注的时间（重复关注时取最早的时间）
+func watchedNotes(userId int) (map[int]time.Time, error) {
+	var watches []entity.Watch
+	if err := repo.DBDao.Where("user_id = ?", userId).Find(&watches).Error; err != nil {
+		return nil, err
+	}
+	res := map[int]time.Time{}
+	watch := func(noteId int, at time.Time) {
+		if t, ok := res[noteId]; !ok || at.Before(t) {
+			res[noteId] = at
+		}
+	}
+	for _, w := range watches {
+		if w.NoteId > 0 {
+			watch(w.NoteId, w.CreatedAt)
+			continue
+		}
+		folders := []int{w.FolderId}
+		for _, sub := range repo.FolderRepo.GetSubFolders(w.FolderId) {
+			folders = append(folders, sub.ID)
+		}
+		var noteIds []int
+		err := repo.DBDao.Model(&entity.NoteMember{}).Distinct("note_id").
+			Where("folder_id IN ? AND role = 0", folders).Pluck("note_id", &noteIds).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range noteIds {
+			watch(id, w.CreatedAt)
+		}
+	}
+	return res, nil
+}
+
+// collectChanges 汇总关注笔记在 [since, now) 内由他人保存的变更
+// 仅包含未删除且关注者仍可访问的笔记，开始关注前的变更不计入，按最后更新时间倒序。
+func collectChanges(userId int, since, now time.Time) ([]*noteDigest, error) {
+	watched, err := watchedNotes(userId)
+	if err != nil || len(watched) == 0 {
+		return nil, err
+	}
+	ids := make([]int, 0, len(watched))
+	for id := range watched {
+		ids = append(ids, id)
+	}
+	// 笔记手动保存时更新修改时间，先以修改时间筛选出有变更的笔记
+	var notes []entity.Note
+	err = repo.DBDao.Select("id, title").
+		Where("id IN ? AND is_delete = 0 AND updated_at >= ?", ids, since).Find(&notes).Error
+	if err != nil || len(notes) == 0 {
+		return nil, err
+	}
+	titles := map[int]string{}
+	ids = ids[:0]
+	for _, n := range notes {
+		titles[n.ID] = n.Title
+		ids = append(ids, n.ID)
+	}
+
+	var changes []noteChange
+	err = repo.DBDao.Table("note_changes").
+		Select("note_changes.note_id, note_changes.added, note_changes.removed, note_changes.created_at, users.`name`").
+		Joins("INNER JOIN users ON users.id = note_changes.user_id").
+		Where("note_changes.note_id IN ? AND note_changes.user_id != ?", ids, userId).
+		Where("note_changes.created_at >= ? AND note_changes.created_at < ?", since, now).
+		Order("note_changes.id").Find(&changes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	digests := map[int]*noteDigest{}
+	var res []*noteDigest
+	for _, c := range changes {
+		if c.CreatedAt.Before(watched[c.NoteId]) {
+			continue
+		}
+		d, ok := digests[c.NoteId]
+		if !ok {
+			role, err := repo.NoteMemberRepo.Check(userId, c.NoteId)
+			if err != nil {
+				return nil, err
+			}
+			if role == -1 {
+				digests[c.NoteId] = nil
+				continue
+			}
+			d = &noteDigest{NoteId: c.NoteId, Title: titles[c.NoteId]}
+			digests[c.NoteId] = d
+			res = append(res, d)
+		}
+		if d == nil {
+			continue
+		}
+		if !contains(d.Editors, c.Name) {
+			d.Editors = append(d.Editors, c.Name)
+		}
+		d.Added += c.Added
+		d.Removed += c.Removed
+		d.UpdatedAt = c.CreatedAt
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].UpdatedAt.After(res[j].UpdatedAt)
+	})
+	if len(res) > digestMaxNotes {
+		res = res[:digestMaxNotes]
+	}
+	return res, nil
+}
+
+// sendDigest 发送变更摘要，站内通知按用户设置发送，邮件按用户的邮件通知偏好发送
+func sendDigest(userId int, setting *entity.WatchSetting, since time.Time, notes []*noteDigest) {
+	title := "关注的笔记有更新"
+	noteId := 0
+	var content string
+	if len(notes) == 1 {
+		n := notes[0]
+		noteId = n.NoteId
+		content = fmt.Sprintf("笔记《%s》由 %s 更新，%s", n.Title, strings.Join(n.Editors, "、"), changeSummary(n.Added, n.Removed))
+	} else {
+		parts := make([]string, len(notes))
+		for i, n := range notes {
+			parts[i] = fmt.Sprintf("《%s》（%s，+%d/-%d）", n.Title, strings.Join(n.Editors, "、"), n.Added, n.Removed)
+		}
+		content = fmt.Sprintf("%d 篇关注的笔记有更新：%s", len(notes), strings.Join(parts, "、"))
+	}
+	if r := []rune(content); len(r) > digestContentMaxLen {
+		content = string(r[:digestContentMaxLen-1]) + "…"
+	}
+	if setting.InApp == 1 {
+		if err := notify.Send(userId, notify.TypeDigest, title, content, noteId); err != nil {
+			zap.L().Warn("发送变更摘要通知失败", zap.Int("userId", userId), zap.Error(err))
+		}
+	}
+
+	items := make([]mail.DigestItem, len(notes))
+	for i, n := range notes {
+		items[i] = mail.DigestItem{
+			Title:     n.Title,
+			Editors:   strings.Join(n.Editors, "、"),
+			UpdatedAt: n.UpdatedAt.Format("2006-01-02 15:04"),
+			Summary:   changeSummary(n.Added, n.Removed),
+		}
+	}
+	mail.Digest(userId, title,
+		fmt.Sprintf("自 %s 以来，%d 篇关注的笔记有更新。", since.Format("2006-01-02 15:04"), len(notes)), items)
+}
+
+// changeSummary 变更内容摘要
+func changeSummary(added, removed int) string {
+	return fmt.Sprintf("新增 %d 行，删除 %d 行", added, removed)
+}
+
+// contains 判断字符串是否在列表中
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,15 @@
 package noteDaemon
 
 import (
+	"go.uber.org/zap"
 	"note/appconf"
 	"note/appconf/dir"
+	"note/metrics"
 	"note/repo"
 	"note/repo/entity"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 )
 
@@ -14,7 +17,8 @@ var _globalL *Note
 
 // Note 笔记模块
 type Note struct {
-	maxKeepDays int // 日志最大存储时间（单位：天），注意若该值小于等于0则表示不删除。
+	maxKeepDays atomic.Int32  // 日志最大存储时间（单位：天），注意若该值小于等于0则表示不删除。
+	stop        chan struct{} // 精灵停止信号
 }
 
 func InitNote(cfg *appconf.Application) {
@@ -22,31 +26,89 @@ func InitNote(cfg *appconf.Application) {
 		return
 	}
 	_globalL = &Note{
-		maxKeepDays: cfg.NoteKeepMaxDays,
+		stop: make(chan struct{}),
 	}
+	_globalL.maxKeepDays.Store(int32(cfg.NoteKeepMaxDays))
 	// 日志超时删除精灵
 	go _globalL.timeoutDeleteDaemon()
+	// 分享到期精灵
+	go _globalL.shareExpireDaemon()
+	// 变更摘要精灵
+	go _globalL.digestDaemon()
+}
+
+// Reload 重新加载配置，新的保存天数在下次清理时生效
+func Reload(cfg *appconf.Application) {
+	if _globalL == nil {
+		return
+	}
+	_globalL.maxKeepDays.Store(int32(cfg.NoteKeepMaxDays))
+}
+
+// Shutdown 停止笔记定时清除精灵、分享到期精灵及变更摘要精灵
+func Shutdown() {
+	if _globalL == nil {
+		return
+	}
+	select {
+	case <-_globalL.stop:
+	default:
+		close(_globalL.stop)
+	}
 }
 
 // 超时删除笔记清理精灵
 // 注意该函数不应抛出任何错误，若有错误请手动恢复并打印，继续下一个循环。
 func (l *Note) timeoutDeleteDaemon() {
-	if _globalL.maxKeepDays > 0 {
-		var notes []string
-		for {
-			now := time.Now().AddDate(0, 0, -_globalL.maxKeepDays).Format("2006-01-02 15:04:05")
-			repo.DBDao.Model(&entity.Note{}).Select("id").Where("updated_at < ? AND is_delete = 1", now).Find(&notes)
-
-			// 删除文件夹
-			for _, note := range notes {
-				noteDir := filepath.Join(dir.NoteDir, note)
-				_ = os.RemoveAll(noteDir)
-				// 删除笔记成员表内相关记录
-				repo.DBDao.Where("note_id = ?", note).Delete(&entity.NoteMember{})
-			}
-
-			repo.DBDao.Where("updated_at < ? AND is_delete = 1", now).Delete(&entity.Note{})
-			time.Sleep(24 * time.Hour)
+	for {
+		// 保存天数可能在运行时调整，小于等于0时跳过本次清理
+		if l.maxKeepDays.Load() > 0 {
+			l.purge()
+		}
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(24 * time.Hour):
+		}
+	}
+}
+
+// purge 清理超时删除的笔记，并记录清理结果指标
+func (l *Note) purge() {
+	var notes []string
+	now := time.Now().AddDate(0, 0, -int(l.maxKeepDays.Load())).Format("2006-01-02 15:04:05")
+	err := repo.DBDao.Model(&entity.Note{}).Select("id").Where("updated_at < ? AND is_delete = 1", now).Find(&notes).Error
+
+	// 删除文件夹
+	for _, note := range notes {
+		if err != nil {
+			break
+		}
+		noteDir := filepath.Join(dir.NoteDir, note)
+		_ = os.RemoveAll(noteDir)
+		// 删除笔记成员表内相关记录
+		err = repo.DBDao.Where("note_id = ?", note).Delete(&entity.NoteMember{}).Error
+		if err == nil {
+			err = repo.DBDao.Where("note_id = ?", note).Delete(&entity.NoteGroupGrant{}).Error
+		}
+		// 删除关注及变更记录
+		if err == nil {
+			err = repo.DBDao.Where("note_id = ?", note).Delete(&entity.Watch{}).Error
 		}
+		if err == nil {
+			err = repo.DBDao.Where("note_id = ?", note).Delete(&entity.NoteChange{}).Error
+		}
+	}
+
+	if err == nil {
+		err = repo.DBDao.Where("updated_at < ? AND is_delete = 1", now).Delete(&entity.Note{}).Error
+	}
+	metrics.NotePurgeLastRun.SetToCurrentTime()
+	if err != nil {
+		zap.L().Warn("过期笔记清理失败", zap.Error(err))
+		metrics.NotePurgeRuns.WithLabelValues("failure").Inc()
+		return
 	}
+	metrics.NotePurgeRuns.WithLabelValues("success").Inc()
+	metrics.NotePurged.Add(float64(len(notes)))
 }
@@ -0,0 +1,135 @@
+package noteDaemon
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"note/logg/applog"
+	"note/notify"
+	"note/repo"
+	"note/repo/entity"
+	"time"
+)
+
+const (
+	shareCheckInterval = 10 * time.Minute // 分享到期检查间隔
+	shareRemindBefore  = 24 * time.Hour   // 分享到期前提醒笔记拥有者的时间
+)
+
+// expiringShare 到期或即将到期的分享
+type expiringShare struct {
+	ID        int
+	NoteId    int
+	TargetId  int       // 被分享的用户ID或用户组ID
+	Name      string    // 被分享的用户姓名或用户组名称
+	Title     string    // 笔记名称
+	ExpiresAt time.Time // 到期时间
+}
+
+// 分享到期精灵，提醒笔记拥有者即将到期的分享并取消已到期的分享
+// 注意该函数不应抛出任何错误，若有错误请打印，继续下一个循环。
+func (l *Note) shareExpireDaemon() {
+	for {
+		l.remindShares()
+		l.revokeShares()
+		select {
+		case <-l.stop:
+			return
+		case <-time.After(shareCheckInterval):
+		}
+	}
+}
+
+// revokeShares 取消已到期的用户及用户组分享
+func (l *Note) revokeShares() {
+	now := time.Now()
+	var members []expiringShare
+	err := repo.DBDao.Model(&entity.NoteMember{}).
+		Select("id, note_id, user_id AS target_id").
+		Where("role != 0 AND expires_at <= ?", now).Find(&members).Error
+	if err != nil {
+		zap.L().Warn("查询到期分享失败", zap.Error(err))
+		return
+	}
+	for _, m := range members {
+		if err = repo.DBDao.Delete(&entity.NoteMember{}, m.ID).Error; err != nil {
+			zap.L().Warn("取消到期分享失败", zap.Int("id", m.ID), zap.Error(err))
+			continue
+		}
+		applog.Anonymous("分享到期取消", map[string]interface{}{"id": m.TargetId, "noteId": m.NoteId, "type": "user"})
+	}
+
+	var grants []expiringShare
+	err = repo.DBDao.Model(&entity.NoteGroupGrant{}).
+		Select("id, note_id, group_id AS target_id").
+		Where("expires_at <= ?", now).Find(&grants).Error
+	if err != nil {
+		zap.L().Warn("查询到期分享失败", zap.Error(err))
+		return
+	}
+	for _, g := range grants {
+		if err = repo.DBDao.Delete(&entity.NoteGroupGrant{}, g.ID).Error; err != nil {
+			zap.L().Warn("取消到期分享失败", zap.Int("id", g.ID), zap.Error(err))
+			continue
+		}
+		applog.Anonymous("分享到期取消", map[string]interface{}{"id": g.TargetId, "noteId": g.NoteId, "type": "group"})
+	}
+}
+
+// remindShares 提醒笔记拥有者即将到期的分享，每个分享仅提醒一次
+func (l *Note) remindShares() {
+	now := time.Now()
+	var members []expiringShare
+	err := repo.DBDao.Table("note_members").
+		Select("note_members.id, note_members.note_id, note_members.expires_at, users.`name`, notes.title").
+		Joins("INNER JOIN users ON users.id = note_members.user_id").
+		Joins("INNER JOIN notes ON notes.id = note_members.note_id").
+		Where("note_members.role != 0 AND note_members.reminded = 0").
+		Where("note_members.expires_at > ? AND note_members.expires_at <= ?", now, now.Add(shareRemindBefore)).
+		Find(&members).Error
+	if err != nil {
+		zap.L().Warn("查询即将到期分享失败", zap.Error(err))
+		return
+	}
+	for _, m := range members {
+		content := fmt.Sprintf("笔记《%s》分享给用户 %s 的权限将于 %s 到期", m.Title, m.Name, m.ExpiresAt.Format("2006-01-02 15:04"))
+		if remind(m.NoteId, content) {
+			repo.DBDao.Model(&entity.NoteMember{}).Where("id = ?", m.ID).Update("reminded", 1)
+		}
+	}
+
+	var grants []expiringShare
+	err = repo.DBDao.Table("note_group_grants").
+		Select("note_group_grants.id, note_group_grants.note_id, note_group_grants.expires_at, user_groups.`name`, notes.title").
+		Joins("INNER JOIN user_groups ON user_groups.id = note_group_grants.group_id").
+		Joins("INNER JOIN notes ON notes.id = note_group_grants.note_id").
+		Where("note_group_grants.reminded = 0").
+		Where("note_group_grants.expires_at > ? AND note_group_grants.expires_at <= ?", now, now.Add(shareRemindBefore)).
+		Find(&grants).Error
+	if err != nil {
+		zap.L().Warn("查询即将到期分享失败", zap.Error(err))
+		return
+	}
+	for _, g := range grants {
+		content := fmt.Sprintf("笔记《%s》分享给用户组 %s 的权限将于 %s 到期", g.Title, g.Name, g.ExpiresAt.Format("2006-01-02 15:04"))
+		if remind(g.NoteId, content) {
+			repo.DBDao.Model(&entity.NoteGroupGrant{}).Where("id = ?", g.ID).Update("reminded", 1)
+		}
+	}
+}
+
+// remind 向笔记拥有者发送分享即将到期的通知
+// return: 是否发送成功，失败时下次检查重新发送
+func remind(noteId int, content string) bool {
+	owners, err := repo.NoteRepo.Owners(noteId)
+	if err != nil {
+		zap.L().Warn("查询笔记拥有者失败", zap.Int("noteId", noteId), zap.Error(err))
+		return false
+	}
+	for _, owner := range owners {
+		if err = notify.Send(owner, notify.TypeShareExpiring, "分享即将到期", content, noteId); err != nil {
+			zap.L().Warn("发送分享到期提醒失败", zap.Int("noteId", noteId), zap.Error(err))
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"note/repo/entity"
+	"sync"
+)
+
+// 每个订阅的通知缓冲数量，缓冲已满时丢弃，客户端可通过通知列表补全
+const subscriberBuffer = 16
+
+var _hub = &hub{subs: map[int]map[chan *entity.Notification]struct{}{}}
+
+// hub 通知订阅中心
+// 同一用户可在多处订阅，通知推送至该用户的所有订阅。
+type hub struct {
+	mu   sync.Mutex
+	subs map[int]map[chan *entity.Notification]struct{} // 用户ID - 订阅通道
+}
+
+// Subscribe 订阅用户的通知
+// return: 通知通道，取消订阅函数（取消后通道关闭）
+func Subscribe(userId int) (<-chan *entity.Notification, func()) {
+	ch := make(chan *entity.Notification, subscriberBuffer)
+	_hub.mu.Lock()
+	if _hub.subs[userId] == nil {
+		_hub.subs[userId] = map[chan *entity.Notification]struct{}{}
+	}
+	_hub.subs[userId][ch] = struct{}{}
+	_hub.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			_hub.mu.Lock()
+			delete(_hub.subs[userId], ch)
+			if len(_hub.subs[userId]) == 0 {
+				delete(_hub.subs, userId)
+			}
+			_hub.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Subscribers 当前订阅数量
+func Subscribers() int {
+	_hub.mu.Lock()
+	defer _hub.mu.Unlock()
+	n := 0
+	for _, s := range _hub.subs {
+		n += len(s)
+	}
+	return n
+}
+
+// publish 推送通知至接收者的所有订阅
+func (h *hub) publish(n *entity.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[n.UserId] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
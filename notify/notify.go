@@ -0,0 +1,64 @@
+package notify
+
+import (
+	"note/mail"
+	"note/repo"
+	"note/repo/entity"
+)
+
+// 通知类型
+const (
+	TypeShareGranted     = "share_granted"      // 笔记分享给用户或所在用户组
+	TypeShareChanged     = "share_changed"      // 笔记分享的权限被修改
+	TypeShareExpiring    = "share_expiring"     // 分享即将到期
+	TypeAccessRequest    = "access_request"     // 收到笔记权限申请
+	TypeAccessApproved   = "access_approved"    // 权限申请已同意
+	TypeAccessDenied     = "access_denied"      // 权限申请已拒绝
+	TypeGroupAdded       = "group_added"        // 被加入用户组
+	TypeGroupRoleChanged = "group_role_changed" // 用户组中的角色被修改
+	TypeGroupRemoved     = "group_removed"      // 被移出用户组
+	TypeNoteDeleted      = "note_deleted"       // 可编辑的笔记被删除
+	TypeNoteRestored     = "note_restored"      // 可编辑的笔记被恢复
+	TypeLockTaken        = "lock_taken"         // 笔记编辑锁被接管
+	TypeDigest           = "digest"             // 关注的笔记或文件夹的变更摘要
+)
+
+// Send 发送站内通知，保存后实时推送至接收者订阅的连接，分享等重要通知同时发送邮件
+// userId: 接收者ID
+// typ: 通知类型
+// noteId: 关联笔记ID，0表示无
+func Send(userId int, typ, title, content string, noteId int) error {
+	return SendAll([]int{userId}, typ, title, content, noteId)
+}
+
+// SendAll 向多个接收者发送相同的站内通知，通知批量保存，其余同 Send
+// 邮件收件人由邮件发送精灵查询，接收者较多（如分享给用户组）时不阻塞业务处理。
+// userIds: 接收者ID
+func SendAll(userIds []int, typ, title, content string, noteId int) error {
+	list := make([]*entity.Notification, 0, len(userIds))
+	ids := make([]int, 0, len(userIds))
+	for _, userId := range userIds {
+		if userId <= 0 {
+			continue
+		}
+		list = append(list, &entity.Notification{
+			UserId:  userId,
+			Type:    typ,
+			Title:   title,
+			Content: content,
+			NoteId:  noteId,
+		})
+		ids = append(ids, userId)
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	if err := repo.DBDao.CreateInBatches(list, 500).Error; err != nil {
+		return err
+	}
+	for _, n := range list {
+		_hub.publish(n)
+	}
+	mail.Notify(typ, title, content, ids)
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"note/appconf"
+	"note/controller"
+	"note/logg"
+	"note/logg/applog"
+	"note/mail"
+	"note/noteDaemon"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// configReloader 配置热加载
+// 定时检查配置文件的修改时间，或收到SIGHUP信号时重新加载配置，仅应用支持热加载的配置项。
+type configReloader struct {
+	args    []string             // 启动时的命令行参数，重新加载时保持相同的覆盖
+	current *appconf.Application // 当前生效的配置
+	modTime time.Time            // 配置文件最后修改时间
+}
+
+// watchConfig 监听配置文件变化，直到上下文结束
+func watchConfig(ctx context.Context, cfg *appconf.Application, args []string) {
+	r := &configReloader{args: args, current: cfg}
+	if info, err := os.Stat(cfg.File); err == nil {
+		r.modTime = info.ModTime()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload("SIGHUP")
+		case <-ticker.C:
+			info, err := os.Stat(r.current.File)
+			if err != nil || info.ModTime().Equal(r.modTime) {
+				continue
+			}
+			r.modTime = info.ModTime()
+			r.reload("文件变化")
+		}
+	}
+}
+
+// reload 重新加载配置并应用支持热加载的配置项
+// 配置错误时保持当前配置不变。
+func (r *configReloader) reload(trigger string) {
+	cfg, err := appconf.Load(r.args)
+	if err != nil {
+		zap.L().Warn("配置重新加载失败，保持当前配置", zap.String("trigger", trigger), zap.Error(err))
+		applog.Anonymous("重新加载配置", map[string]interface{}{
+			"trigger": trigger,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	var applied, ignored []string
+	for _, path := range appconf.Diff(r.current, cfg) {
+		if appconf.IsReloadable(path) {
+			applied = append(applied, path)
+		} else {
+			ignored = append(ignored, path)
+		}
+	}
+	if len(applied) == 0 && len(ignored) == 0 {
+		return
+	}
+	if len(ignored) > 0 {
+		zap.L().Warn("以下配置项修改后需重启程序才能生效", zap.Strings("fields", ignored))
+	}
+
+	// 不支持热加载的配置项保持启动时的值
+	next := *r.current
+	next.LogKeepMaxDays = cfg.LogKeepMaxDays
+	next.NoteKeepMaxDays = cfg.NoteKeepMaxDays
+	next.Debug = cfg.Debug
+	next.SSOBaseUrl = cfg.SSOBaseUrl
+	next.SSOClientId = cfg.SSOClientId
+	next.SSOClientSecret = cfg.SSOClientSecret
+	next.Alert = cfg.Alert
+	next.Successor = cfg.Successor
+	next.Mail = cfg.Mail
+
+	if next.Debug != r.current.Debug {
+		logg.SetDebug(next.Debug)
+	}
+	applog.Reload(&next)
+	noteDaemon.Reload(&next)
+	mail.Reload(&next)
+	controller.Reload(&next)
+	r.current = &next
+
+	zap.L().Info("配置重新加载", zap.String("trigger", trigger), zap.Strings("applied", applied))
+	applog.Anonymous("重新加载配置", map[string]interface{}{
+		"trigger": trigger,
+		"applied": applied,
+		"ignored": ignored,
+	})
+}
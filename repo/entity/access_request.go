@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AccessRequest 笔记权限申请
+// 无权限或仅可查看的用户向笔记拥有者申请权限，拥有者同意后按分享笔记处理。
+type AccessRequest struct {
+	ID        int        `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	NoteId    int        `json:"noteId"`    // 笔记ID
+	UserId    int        `json:"userId"`    // 申请者ID
+	Role      int        `json:"role"`      // 申请的权限 1 - 可查看 2 - 可编辑
+	Message   string     `json:"message"`   // 申请说明
+	Status    int        `json:"status"`    // 状态 0 - 待处理 1 - 已同意 2 - 已拒绝
+	HandlerId int        `json:"handlerId"` // 处理者ID
+	HandledAt *time.Time `json:"handledAt"` // 处理时间
+	Reply     string     `json:"reply"`     // 处理意见
+}
+
+func (c *AccessRequest) MarshalJSON() ([]byte, error) {
+	type Alias AccessRequest
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime  `json:"createdAt"`
+		HandledAt *DateTime `json:"handledAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+		(*DateTime)(c.HandledAt),
+	})
+}
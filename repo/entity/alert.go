@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Alert 审计告警
+type Alert struct {
+	ID        int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	RuleName  string    `json:"ruleName"` // 触发的规则名称
+	Level     string    `json:"level"`    // 告警级别：info、warn、critical
+	OpType    int       `json:"opType"`   // 最后一次触发的操作者类型 0 - 匿名 1 - 管理员 2 - 用户
+	OpId      int       `json:"opId"`     // 最后一次触发的操作者记录ID
+	OpName    string    `json:"opName"`   // 操作名称
+	Content   string    `json:"content"`  // 告警内容
+	IsRead    int       `json:"isRead"`   // 是否已读 0 - 未读（默认值） 1 - 已读
+}
+
+func (c *Alert) MarshalJSON() ([]byte, error) {
+	type Alias Alert
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime `json:"createdAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+	})
+}
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+)
+
+// ArchiveLog 临时导入的归档操作日志
+// 归档文件重新导入后用于检索，超过保留时长后自动清除。
+type ArchiveLog struct {
+	ID         int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`  // 原日志创建时间
+	LogId      int       `json:"logId"`      // 原日志ID
+	OpType     int       `json:"opType"`     // 操作者类型 0 - 匿名 1 - 管理员 2 - 用户
+	OpId       int       `json:"opId"`       // 操作者记录ID
+	OpName     string    `json:"opName"`     // 操作名称
+	OpParam    string    `json:"opParam"`    // 操作的关键参数
+	RequestId  string    `json:"requestId"`  // 请求ID
+	Archive    string    `json:"archive"`    // 归档文件名称
+	ImportedAt time.Time `json:"importedAt"` // 导入时间
+}
@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Department 部门，由员工管理系统同步
+type Department struct {
+	ID         int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	Code       string    `json:"code"`       // 部门编号【唯一】
+	Name       string    `json:"name"`       // 部门名称
+	ParentCode string    `json:"parentCode"` // 上级部门编号，为空表示顶级部门
+}
+
+func (c *Department) MarshalJSON() ([]byte, error) {
+	type Alias Department
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime `json:"createdAt"`
+		UpdatedAt DateTime `json:"updatedAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+		DateTime(c.UpdatedAt),
+	})
+}
@@ -6,10 +6,12 @@ import (
 )
 
 // Folder 文件夹
+// 用户组的根文件夹即该用户组的团队空间，团队空间中的文件夹及笔记属于用户组。
 type Folder struct {
 	ID        int       `gorm:"autoIncrement" json:"id"`
 	CreatedAt time.Time `json:"createdAt"`
-	UserId    int       `json:"userId"`   // 用户ID
+	UserId    int       `json:"userId"`   // 用户ID，团队空间的文件夹为0
+	GroupId   int       `json:"groupId"`  // 团队空间所属用户组ID，0表示个人文件夹
 	Name      string    `json:"name"`     // 文件夹名称
 	ParentId  int       `json:"parentId"` // 父文件夹ID
 }
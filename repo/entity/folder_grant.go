@@ -0,0 +1,28 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// FolderGrant 文件夹分享授权
+// 授权对象为用户或用户组，文件夹下的笔记及子文件夹继承该权限。
+type FolderGrant struct {
+	ID        int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	FolderId  int       `json:"folderId"` // 文件夹ID
+	UserId    int       `json:"userId"`   // 被分享的用户ID，分享给用户组时为0
+	GroupId   int       `json:"groupId"`  // 被分享的用户组ID，分享给用户时为0
+	Role      int       `json:"role"`     // 权限 1 - 可查看 2 - 可编辑
+}
+
+func (c *FolderGrant) MarshalJSON() ([]byte, error) {
+	type Alias FolderGrant
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime `json:"createdAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+	})
+}
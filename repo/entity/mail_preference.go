@@ -0,0 +1,17 @@
+package entity
+
+// MailPreference 用户邮件通知偏好
+// 无记录时表示全部事件均发送邮件。
+type MailPreference struct {
+	ID            int `gorm:"autoIncrement" json:"-"`
+	UserId        int `json:"-"`             // 用户ID【唯一】
+	ShareGranted  int `json:"shareGranted"`  // 笔记分享给我时是否发送邮件 0 - 不发送 1 - 发送
+	AccessRequest int `json:"accessRequest"` // 收到笔记权限申请时是否发送邮件 0 - 不发送 1 - 发送
+	ShareExpiring int `json:"shareExpiring"` // 分享即将到期时是否发送邮件 0 - 不发送 1 - 发送
+	Digest        int `json:"digest"`        // 是否发送关注笔记的变更摘要邮件 0 - 不发送 1 - 发送
+}
+
+// DefaultMailPreference 缺省的邮件通知偏好，全部事件均发送邮件
+func DefaultMailPreference(userId int) MailPreference {
+	return MailPreference{UserId: userId, ShareGranted: 1, AccessRequest: 1, ShareExpiring: 1, Digest: 1}
+}
@@ -7,7 +7,8 @@ type Note struct {
 	ID        int       `gorm:"autoIncrement" json:"id"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
-	UserId    int       `json:"userId"`   // 所属用户ID
+	UserId    int       `json:"userId"`   // 所属用户ID，团队空间的笔记为0
+	GroupId   int       `json:"groupId"`  // 团队空间所属用户组ID，0表示个人笔记
 	Title     string    `json:"title"`    // 文档名
 	TitlePy   string    `json:"titlePy"`  // 文档名称缩写
 	Priority  int       `json:"priority"` // 优先级 默认为0，越大优先级越高，用于文档排序，非特殊情况保持0即可。
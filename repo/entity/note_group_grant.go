@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NoteGroupGrant 笔记用户组授权
+// 笔记分享给用户组时仅记录一条授权，用户组成员的权限在访问时根据所属用户组计算。
+type NoteGroupGrant struct {
+	ID        int        `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	NoteId    int        `json:"noteId"`    // 笔记ID
+	GroupId   int        `json:"groupId"`   // 用户组ID
+	Role      int        `json:"role"`      // 用户组成员权限 1 - 可查看 2 - 可编辑
+	ExpiresAt *time.Time `json:"expiresAt"` // 分享到期时间，为空表示不过期
+	Reminded  int        `json:"reminded"`  // 是否已发送到期提醒 0 - 未提醒 1 - 已提醒
+}
+
+func (c *NoteGroupGrant) MarshalJSON() ([]byte, error) {
+	type Alias NoteGroupGrant
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime  `json:"createdAt"`
+		ExpiresAt *DateTime `json:"expiresAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+		(*DateTime)(c.ExpiresAt),
+	})
+}
@@ -6,24 +6,33 @@ import (
 )
 
 type NoteMember struct {
-	ID        int       `gorm:"autoIncrement" json:"id"`
-	CreatedAt time.Time `json:"createdAt"`
-	UserId    int       `json:"userId"`    // 用户ID
-	NoteId    int       `json:"noteId"`    // 笔记ID
-	Role      int       `json:"role"`      // 用户权限 0 - 笔记拥有者/管理者 1 - 可查看 2 - 可编辑
-	Remark    string    `json:"remark"`    // 备注
-	NoteGroup string    `json:"noteGroup"` // 笔记分组（兼容，最新版已采用文件夹Id）
-	GroupId   int       `json:"groupId"`   // 用户组ID
-	FolderId  int       `json:"folderId"`  // 文件夹Id
+	ID        int        `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UserId    int        `json:"userId"`    // 用户ID
+	NoteId    int        `json:"noteId"`    // 笔记ID
+	Role      int        `json:"role"`      // 用户权限 0 - 笔记拥有者/管理者 1 - 可查看 2 - 可编辑
+	Remark    string     `json:"remark"`    // 备注
+	NoteGroup string     `json:"noteGroup"` // 笔记分组（兼容，最新版已采用文件夹Id）
+	GroupId   int        `json:"groupId"`   // （已弃用）用户组ID，用户组分享见 NoteGroupGrant
+	FolderId  int        `json:"folderId"`  // 文件夹Id
+	ExpiresAt *time.Time `json:"expiresAt"` // 分享到期时间，为空表示不过期，拥有者记录无到期时间
+	Reminded  int        `json:"reminded"`  // 是否已发送到期提醒 0 - 未提醒 1 - 已提醒
+}
+
+// Expired 分享是否已到期
+func (c *NoteMember) Expired() bool {
+	return c.ExpiresAt != nil && time.Now().After(*c.ExpiresAt)
 }
 
 func (c *NoteMember) MarshalJSON() ([]byte, error) {
 	type Alias NoteMember
 	return json.Marshal(&struct {
 		*Alias
-		CreatedAt DateTime `json:"createdAt"`
+		CreatedAt DateTime  `json:"createdAt"`
+		ExpiresAt *DateTime `json:"expiresAt"`
 	}{
 		(*Alias)(c),
 		DateTime(c.CreatedAt),
+		(*DateTime)(c.ExpiresAt),
 	})
 }
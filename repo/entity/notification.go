@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID        int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UserId    int       `json:"userId"`  // 接收者ID
+	Type      string    `json:"type"`    // 通知类型，如：share_expiring
+	Title     string    `json:"title"`   // 标题
+	Content   string    `json:"content"` // 内容
+	NoteId    int       `json:"noteId"`  // 关联笔记ID，0表示无
+	IsRead    int       `json:"isRead"`  // 是否已读 0 - 未读 1 - 已读
+}
+
+func (c *Notification) MarshalJSON() ([]byte, error) {
+	type Alias Notification
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime `json:"createdAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+	})
+}
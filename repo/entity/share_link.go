@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ShareLink 笔记公开分享链接
+// 持有链接的匿名访问者可只读查看笔记，链接可设置有效期、访问密码及最大访问次数。
+type ShareLink struct {
+	ID          int        `gorm:"autoIncrement" json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	NoteId      int        `json:"noteId"`      // 笔记ID
+	UserId      int        `json:"userId"`      // 创建者ID
+	Token       string     `json:"token"`       // 链接令牌【唯一】
+	ExpiredAt   *time.Time `json:"expiredAt"`   // 过期时间，为空表示不过期
+	Password    Pwd        `json:"-"`           // 访问密码加盐摘要Hex，为空表示无需密码
+	Salt        string     `json:"-"`           // 盐值Hex
+	MaxViews    int        `json:"maxViews"`    // 最大访问次数，0表示不限制
+	Views       int        `json:"views"`       // 已访问次数
+	AllowAssets int        `json:"allowAssets"` // 是否允许下载笔记资源 0 - 不允许 1 - 允许
+	Revoked     int        `json:"revoked"`     // 是否已撤销 0 - 有效 1 - 已撤销
+}
+
+// Expired 链接是否已过期
+func (c *ShareLink) Expired() bool {
+	return c.ExpiredAt != nil && time.Now().After(*c.ExpiredAt)
+}
+
+func (c *ShareLink) MarshalJSON() ([]byte, error) {
+	type Alias ShareLink
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt   DateTime  `json:"createdAt"`
+		ExpiredAt   *DateTime `json:"expiredAt"`
+		HasPassword bool      `json:"hasPassword"` // 是否需要访问密码
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+		(*DateTime)(c.ExpiredAt),
+		c.Password != "",
+	})
+}
@@ -20,16 +20,30 @@ type User struct {
 	Sn        string    `json:"sn"`        // 身份证
 	NoteTags  string    `json:"noteTags"`  // 笔记标签 - 已弃用
 	GroupTags string    `json:"groupTags"` // 用户组标签 - 已弃用
-	IsDelete  int       `json:"isDelete"`  // 是否删除 0 - 未删除（默认值） 1 - 删除
+
+	DepartmentCode string     `json:"departmentCode"` // 所属部门编号
+	Title          string     `json:"title"`          // 职务
+	Manager        string     `json:"manager"`        // 直属上级工号
+	State          int        `json:"state"`          // 员工状态 0 - 未同步 1 - 正式 2 - 试用 3 - 实习 4 - 外聘 5 - 劳务派遣 6 - 离职 7 - 返聘
+	ExpiredAt      *time.Time `json:"expiredAt"`      // 账号过期时间，为空表示不过期
+
+	IsDelete int `json:"isDelete"` // 是否删除 0 - 未删除（默认值） 1 - 删除
+}
+
+// Expired 账号是否已过期
+func (c *User) Expired() bool {
+	return c.ExpiredAt != nil && time.Now().After(*c.ExpiredAt)
 }
 
 func (c *User) MarshalJSON() ([]byte, error) {
 	type Alias User
 	return json.Marshal(&struct {
 		*Alias
-		CreatedAt DateTime `json:"createdAt"`
+		CreatedAt DateTime  `json:"createdAt"`
+		ExpiredAt *DateTime `json:"expiredAt"`
 	}{
 		(*Alias)(c),
 		DateTime(c.CreatedAt),
+		(*DateTime)(c.ExpiredAt),
 	})
 }
@@ -0,0 +1,58 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Watch 关注的笔记或文件夹
+// 关注文件夹时包含其子文件夹中的笔记，关注者失去访问权限的笔记不计入变更摘要。
+type Watch struct {
+	ID        int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UserId    int       `json:"userId"`   // 关注者ID
+	NoteId    int       `json:"noteId"`   // 关注的笔记ID，0表示关注文件夹
+	FolderId  int       `json:"folderId"` // 关注的文件夹ID，0表示关注笔记
+}
+
+func (c *Watch) MarshalJSON() ([]byte, error) {
+	type Alias Watch
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime `json:"createdAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+	})
+}
+
+// WatchSetting 用户的变更摘要设置
+// 无记录时按天发送站内通知摘要。
+type WatchSetting struct {
+	ID           int        `gorm:"autoIncrement" json:"-"`
+	UserId       int        `json:"-"`         // 用户ID【唯一】
+	Frequency    string     `json:"frequency"` // 摘要频率 hourly - 每小时 daily - 每天
+	InApp        int        `json:"inApp"`     // 是否发送站内通知摘要 0 - 不发送 1 - 发送
+	LastDigestAt *time.Time `json:"-"`         // 上次生成摘要的时间，摘要包含此后的变更
+}
+
+// 变更摘要频率
+const (
+	DigestHourly = "hourly" // 每小时
+	DigestDaily  = "daily"  // 每天
+)
+
+// DefaultWatchSetting 缺省的变更摘要设置
+func DefaultWatchSetting(userId int) WatchSetting {
+	return WatchSetting{UserId: userId, Frequency: DigestDaily, InApp: 1}
+}
+
+// NoteChange 笔记变更记录，手动保存笔记内容时生成，用于关注者的变更摘要
+type NoteChange struct {
+	ID        int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	NoteId    int       `json:"noteId"`  // 笔记ID
+	UserId    int       `json:"userId"`  // 保存者ID
+	Added     int       `json:"added"`   // 新增行数
+	Removed   int       `json:"removed"` // 删除行数
+}
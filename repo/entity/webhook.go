@@ -0,0 +1,77 @@
+package entity
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Webhook 事件推送订阅
+// 订阅的事件发生时以HTTP POST推送至订阅地址，请求使用订阅密钥进行HMAC-SM3签名。
+type Webhook struct {
+	ID        int       `gorm:"autoIncrement" json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UserId    int       `json:"userId"`  // 订阅者ID，0表示管理员订阅（接收全部事件）
+	Name      string    `json:"name"`    // 名称
+	Url       string    `json:"url"`     // 推送地址
+	Secret    string    `json:"-"`       // 签名密钥
+	Events    string    `json:"events"`  // 订阅的事件，多个以逗号分隔，支持通配符，如：note.*，* 表示全部事件
+	Enabled   int       `json:"enabled"` // 是否启用 0 - 停用 1 - 启用
+}
+
+// EventList 订阅的事件列表
+func (c *Webhook) EventList() []string {
+	if c.Events == "" {
+		return []string{}
+	}
+	return strings.Split(c.Events, ",")
+}
+
+func (c *Webhook) MarshalJSON() ([]byte, error) {
+	type Alias Webhook
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt DateTime `json:"createdAt"`
+		UpdatedAt DateTime `json:"updatedAt"`
+		Events    []string `json:"events"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+		DateTime(c.UpdatedAt),
+		c.EventList(),
+	})
+}
+
+// WebhookDelivery 事件推送记录
+// 每次事件推送（含重新推送）一条记录，失败时按退避间隔重试，重试次数记录于 Attempts。
+type WebhookDelivery struct {
+	ID          int        `gorm:"autoIncrement" json:"id"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	WebhookId   int        `json:"webhookId"`            // 订阅ID
+	Event       string     `json:"event"`                // 事件名称，如：note.updated
+	Payload     string     `json:"payload,omitempty"`    // 推送的请求体
+	Status      int        `json:"status"`               // 状态 0 - 待推送 1 - 成功 2 - 失败
+	Attempts    int        `json:"attempts"`             // 已推送次数
+	StatusCode  int        `json:"statusCode"`           // 最后一次推送的响应状态码，0表示未收到响应
+	Response    string     `json:"response,omitempty"`   // 最后一次推送的响应体（截断）
+	Error       string     `json:"error"`                // 最后一次推送的错误信息
+	NextAt      *time.Time `json:"nextAt"`               // 下次推送时间
+	DeliveredAt *time.Time `json:"deliveredAt"`          // 最后一次推送时间
+	Redelivery  int        `json:"redelivery,omitempty"` // 重新推送的原推送记录ID，0表示非重新推送
+}
+
+func (c *WebhookDelivery) MarshalJSON() ([]byte, error) {
+	type Alias WebhookDelivery
+	return json.Marshal(&struct {
+		*Alias
+		CreatedAt   DateTime  `json:"createdAt"`
+		NextAt      *DateTime `json:"nextAt"`
+		DeliveredAt *DateTime `json:"deliveredAt"`
+	}{
+		(*Alias)(c),
+		DateTime(c.CreatedAt),
+		(*DateTime)(c.NextAt),
+		(*DateTime)(c.DeliveredAt),
+	})
+}
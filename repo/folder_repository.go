@@ -1,6 +1,7 @@
 package repo
 
 import (
+	"database/sql"
 	"errors"
 	"gorm.io/gorm"
 	"note/repo/entity"
@@ -11,13 +12,19 @@ type FolderRepository struct {
 }
 
 // Exist 判断文件夹是否已存在
+// 父文件夹不为根时仅判断父文件夹下的同名文件夹，父文件夹可能属于团队空间。
 func (f *FolderRepository) Exist(id int, name string, parentId int) (bool, error) {
 	if id <= 0 || name == "" {
 		return false, errors.New("参数错误")
 	}
 	res := &entity.Folder{}
 
-	err := DBDao.First(res, "user_id = ? AND name = ? AND parent_id = ?", id, name, parentId).Error
+	var err error
+	if parentId > 0 {
+		err = DBDao.First(res, "name = ? AND parent_id = ?", name, parentId).Error
+	} else {
+		err = DBDao.First(res, "user_id = ? AND name = ? AND parent_id = ?", id, name, parentId).Error
+	}
 	if err == gorm.ErrRecordNotFound {
 		return false, nil
 	}
@@ -29,6 +36,7 @@ func (f *FolderRepository) Exist(id int, name string, parentId int) (bool, error
 }
 
 // GetFolder 获取当前文件夹
+// 文件夹属于该用户，或属于该用户管理（用户组拥有者）的团队空间，否则返回 gorm.ErrRecordNotFound。
 func (f *FolderRepository) GetFolder(id int, userId int) (*entity.Folder, error) {
 	if id <= 0 || userId <= 0 {
 		return nil, errors.New("参数错误")
@@ -36,11 +44,33 @@ func (f *FolderRepository) GetFolder(id int, userId int) (*entity.Folder, error)
 
 	res := &entity.Folder{}
 
-	err := DBDao.First(res, "id = ? AND user_id = ?  ", id, userId).Error
+	err := DBDao.First(res, "id = ? ", id).Error
 	if err != nil {
 		return nil, err
 	}
+	if res.UserId == userId {
+		return res, nil
+	}
+	if res.GroupId != 0 {
+		role, err := spaceRole(userId, res.GroupId)
+		if err != nil {
+			return nil, err
+		}
+		if role == 0 {
+			return res, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
 
+// GetById 根据ID获取文件夹，不限制文件夹拥有者
+func (f *FolderRepository) GetById(id int) (*entity.Folder, error) {
+	res := &entity.Folder{}
+	err := DBDao.First(res, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
@@ -89,6 +119,115 @@ func (f *FolderRepository) GetFolderFullPath(id int, userId int, name string) (s
 
 }
 
+// Ancestors 获取文件夹及其所有父文件夹，由当前文件夹至根文件夹排列
+// 文件夹不存在时返回空列表。
+func (f *FolderRepository) Ancestors(id int) ([]entity.Folder, error) {
+	var res []entity.Folder
+	// 防止异常数据中父文件夹循环引用
+	visited := map[int]bool{}
+	for id > 0 && !visited[id] {
+		visited[id] = true
+		var folder entity.Folder
+		err := DBDao.First(&folder, "id = ?", id).Error
+		if err == gorm.ErrRecordNotFound {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, folder)
+		id = folder.ParentId
+	}
+	return res, nil
+}
+
+// Role 获取用户对文件夹的权限 -1 - 无权限 0 - 文件夹拥有者（团队空间管理者） 1 - 可查看 2 - 可编辑
+// 团队空间的文件夹按用户在用户组中的角色确定权限，
+// 文件夹继承所有父文件夹的分享授权，取用户及所在用户组授权中的最高权限。
+func (f *FolderRepository) Role(userId int, folderId int) (int, error) {
+	folders, err := f.Ancestors(folderId)
+	if err != nil || len(folders) == 0 {
+		return -1, err
+	}
+	res := -1
+	if space := folders[len(folders)-1].GroupId; space != 0 {
+		res, err = spaceRole(userId, space)
+		if err != nil || res == 0 {
+			return res, err
+		}
+	} else if folders[0].UserId == userId {
+		return 0, nil
+	}
+	ids := make([]int, len(folders))
+	for i := range folders {
+		ids[i] = folders[i].ID
+	}
+	var role sql.NullInt64
+	err = DBDao.Model(&entity.FolderGrant{}).Select("MAX(role)").
+		Where("folder_id IN ?", ids).
+		Where("user_id = ? OR group_id IN (?)", userId, userGroups(userId)).
+		Row().Scan(&role)
+	if err != nil {
+		return -1, err
+	}
+	if role.Valid && int(role.Int64) > res {
+		res = int(role.Int64)
+	}
+	return res, nil
+}
+
+// Inherited 获取用户通过文件夹获得笔记权限的所有文件夹
+// 包含用户自己的文件夹及分享给用户或所在用户组的文件夹（含子文件夹）。
+// return: 文件夹ID - 文件夹下笔记继承的权限，用户自己的文件夹为可编辑
+func (f *FolderRepository) Inherited(userId int) (map[int]int, error) {
+	res := map[int]int{}
+	var own []int
+	err := DBDao.Model(&entity.Folder{}).Where("user_id = ?", userId).Pluck("id", &own).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range own {
+		res[id] = 2
+	}
+
+	var grants []entity.FolderGrant
+	err = DBDao.Where("user_id = ? OR group_id IN (?)", userId, userGroups(userId)).Find(&grants).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range grants {
+		ids := []int{grant.FolderId}
+		for _, sub := range f.GetSubFolders(grant.FolderId) {
+			ids = append(ids, sub.ID)
+		}
+		for _, id := range ids {
+			if res[id] < grant.Role {
+				res[id] = grant.Role
+			}
+		}
+	}
+	return res, nil
+}
+
+// spaceRole 获取用户在团队空间中的权限 -1 - 非用户组成员 0 - 管理 1 - 可查看 2 - 可编辑
+// 由用户在用户组中的角色确定：拥有者管理团队空间，维护可编辑，普通用户可查看。
+func spaceRole(userId int, groupId int) (int, error) {
+	var member entity.GroupMember
+	err := DBDao.First(&member, "user_id = ? AND belong = ?", userId, groupId).Error
+	if err == gorm.ErrRecordNotFound {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return member.Role, nil
+}
+
+// userGroups 用户所在用户组ID的子查询
+func userGroups(userId int) *gorm.DB {
+	return DBDao.Model(&entity.GroupMember{}).Select("belong").Where("user_id = ?", userId)
+}
+
 func NewFolderRepository() *FolderRepository {
 	return &FolderRepository{}
 }
@@ -2,6 +2,7 @@ package repo
 
 import (
 	"fmt"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"go.uber.org/zap"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
@@ -27,7 +28,7 @@ var (
 func Init(config *appconf.Application) error {
 	var err error
 	zap.L().Info("连接数据库",
-		zap.String("dsn", config.Database.DSN),
+		zap.String("dsn", maskDSN(config.Database.DSN)),
 		zap.String("type", config.Database.Type))
 	config.Database.Type = strings.ToLower(config.Database.Type)
 
@@ -66,3 +67,27 @@ func Init(config *appconf.Application) error {
 	NoteRepo = NewNoteRepository()
 	return nil
 }
+
+// Close 关闭数据库连接
+func Close() error {
+	if DBDao == nil {
+		return nil
+	}
+	db, err := DBDao.DB()
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// maskDSN 隐藏连接地址中的密码，用于打印日志
+func maskDSN(dsn string) string {
+	cfg, err := mysqlDriver.ParseDSN(dsn)
+	if err != nil {
+		return "******"
+	}
+	if cfg.Passwd != "" {
+		cfg.Passwd = "******"
+	}
+	return cfg.FormatDSN()
+}
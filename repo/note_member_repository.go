@@ -1,27 +1,150 @@
 package repo
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
 	"note/repo/entity"
+	"strings"
+	"time"
 )
 
 // NoteMemberRepository 笔记成员支持层
 type NoteMemberRepository struct {
 }
 
-// Check 检查用户权限 -1 - 无权限 1 - 可查看 2 - 可编辑
+// Check 检查用户权限 -1 - 无权限 0 - 笔记拥有者（团队空间管理者） 1 - 可查看 2 - 可编辑
+// 笔记直接分享给用户或所在用户组、团队空间成员的权限取其中的最高权限，
+// 否则继承笔记所在文件夹的权限，文件夹拥有者对其中他人的笔记可编辑。
+// 已到期的分享视为不存在，到期记录由定时任务清理。
 func (r *NoteMemberRepository) Check(userId int, noteId int) (int, error) {
 
+	role := -1
+	now := time.Now()
 	var res entity.NoteMember
 	err := DBDao.First(&res, "user_id = ? AND note_id = ?", userId, noteId).Error
-	if err == gorm.ErrRecordNotFound {
-		return -1, nil
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return -1, err
+	}
+	if err == nil && !res.Expired() {
+		if res.Role == 0 {
+			return 0, nil
+		}
+		role = res.Role
 	}
+
+	var groupRole sql.NullInt64
+	err = DBDao.Model(&entity.NoteGroupGrant{}).Select("MAX(note_group_grants.role)").
+		Joins("INNER JOIN group_members ON group_members.belong = note_group_grants.group_id").
+		Where("note_group_grants.note_id = ? AND group_members.user_id = ?", noteId, userId).
+		Where("note_group_grants.expires_at IS NULL OR note_group_grants.expires_at > ?", now).
+		Row().Scan(&groupRole)
 	if err != nil {
 		return -1, err
 	}
-	return res.Role, nil
+	if groupRole.Valid && int(groupRole.Int64) > role {
+		role = int(groupRole.Int64)
+	}
+
+	// 团队空间的笔记按用户在用户组中的角色确定权限
+	var note entity.Note
+	err = DBDao.Select("group_id").Where("id = ?", noteId).Limit(1).Find(&note).Error
+	if err != nil {
+		return -1, err
+	}
+	if note.GroupId != 0 {
+		space, err := spaceRole(userId, note.GroupId)
+		if err != nil || space == 0 {
+			return space, err
+		}
+		if space > role {
+			role = space
+		}
+	}
+	// 笔记单独分享的权限优先于文件夹继承的权限
+	if role != -1 {
+		return role, nil
+	}
+
+	var owner entity.NoteMember
+	err = DBDao.Where("note_id = ? AND role = 0", noteId).Limit(1).Find(&owner).Error
+	if err != nil || owner.FolderId <= 0 {
+		return -1, err
+	}
+	role, err = FolderRepo.Role(userId, owner.FolderId)
+	if role == 0 {
+		role = 2
+	}
+	return role, err
+}
+
+// Accessible 用户可访问笔记的子查询
+// 合并用户的笔记成员记录、所在用户组的授权、团队空间的笔记以及文件夹继承的权限（不含已到期的分享），每篇笔记一行，
+// 字段：note_id、role（规则同 Check）、remark、folder_id（非笔记成员记录时为空）。
+// db: 数据库连接
+func (r *NoteMemberRepository) Accessible(db *gorm.DB, userId int) (*gorm.DB, error) {
+	now := time.Now()
+	members := db.Model(&entity.NoteMember{}).
+		Select("note_id, role, remark, folder_id, 0 AS inherited").
+		Where("user_id = ?", userId).
+		Where("expires_at IS NULL OR expires_at > ?", now)
+	grants := db.Model(&entity.NoteGroupGrant{}).
+		Select("note_group_grants.note_id, note_group_grants.role, '' AS remark, 0 AS folder_id, 0 AS inherited").
+		Joins("INNER JOIN group_members ON group_members.belong = note_group_grants.group_id").
+		Where("group_members.user_id = ?", userId).
+		Where("note_group_grants.expires_at IS NULL OR note_group_grants.expires_at > ?", now)
+	spaces := db.Model(&entity.Note{}).
+		Select("notes.id AS note_id, group_members.role, '' AS remark, 0 AS folder_id, 0 AS inherited").
+		Joins("INNER JOIN group_members ON group_members.belong = notes.group_id").
+		Where("group_members.user_id = ?", userId)
+	parts := []interface{}{members, grants, spaces}
+
+	folders, err := FolderRepo.Inherited(userId)
+	if err != nil {
+		return nil, err
+	}
+	byRole := map[int][]int{}
+	for id, role := range folders {
+		byRole[role] = append(byRole[role], id)
+	}
+	for _, role := range []int{1, 2} {
+		if len(byRole[role]) == 0 {
+			continue
+		}
+		parts = append(parts, db.Model(&entity.NoteMember{}).
+			Select(fmt.Sprintf("note_id, %d AS role, '' AS remark, 0 AS folder_id, 1 AS inherited", role)).
+			Where("role = 0 AND folder_id IN ?", byRole[role]))
+	}
+
+	union := strings.TrimSuffix(strings.Repeat("? UNION ALL ", len(parts)), " UNION ALL ")
+	return db.Table("("+union+") AS t", parts...).
+		Select("note_id, " +
+			"CASE WHEN MIN(role) = 0 THEN 0 " +
+			"WHEN MIN(inherited) = 0 THEN MAX(CASE WHEN inherited = 0 THEN role END) " +
+			"ELSE MAX(role) END AS role, " +
+			"MAX(remark) AS remark, MAX(folder_id) AS folder_id").
+		Group("note_id"), nil
+}
+
+// Editors 获取可编辑笔记的用户
+// 包括直接分享、用户组授权（不含已到期的分享）及团队空间的维护者，不含文件夹继承的权限。
+func (r *NoteMemberRepository) Editors(noteId int) ([]int, error) {
+	now := time.Now()
+	members := DBDao.Model(&entity.NoteMember{}).Select("user_id").
+		Where("note_id = ? AND role = 2", noteId).
+		Where("expires_at IS NULL OR expires_at > ?", now)
+	grants := DBDao.Model(&entity.NoteGroupGrant{}).Select("group_members.user_id").
+		Joins("INNER JOIN group_members ON group_members.belong = note_group_grants.group_id").
+		Where("note_group_grants.note_id = ? AND note_group_grants.role = 2", noteId).
+		Where("note_group_grants.expires_at IS NULL OR note_group_grants.expires_at > ?", now)
+	spaces := DBDao.Model(&entity.Note{}).Select("group_members.user_id").
+		Joins("INNER JOIN group_members ON group_members.belong = notes.group_id").
+		Where("notes.id = ? AND group_members.role = 2", noteId)
+
+	var ids []int
+	err := DBDao.Raw("? UNION ? UNION ?", members, grants, spaces).Scan(&ids).Error
+	return ids, err
 }
 
 // Exist 判断用户是否已被分享
@@ -39,7 +162,7 @@ func (r *NoteMemberRepository) Exist(id int, noteId int, shareType string) (bool
 			return true, err
 		}
 	} else if shareType == "group" {
-		err := DBDao.First(res, "group_id = ? AND note_id = ?", id, noteId).Error
+		err := DBDao.First(&entity.NoteGroupGrant{}, "group_id = ? AND note_id = ?", id, noteId).Error
 		if err == gorm.ErrRecordNotFound {
 			return false, nil
 		}
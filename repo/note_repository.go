@@ -27,6 +27,24 @@ func (n *NoteRepository) GetNoteById(id int, userId int) (*entity.Note, error) {
 	return note, nil
 }
 
+// Owners 获取笔记拥有者，团队空间的笔记为用户组拥有者
+// return: 用户ID列表，笔记不存在时为空
+func (n *NoteRepository) Owners(noteId int) ([]int, error) {
+	var note entity.Note
+	err := DBDao.Select("user_id, group_id").Where("id = ?", noteId).Limit(1).Find(&note).Error
+	if err != nil {
+		return nil, err
+	}
+	if note.UserId > 0 {
+		return []int{note.UserId}, nil
+	}
+	var owners []int
+	if note.GroupId > 0 {
+		err = DBDao.Model(&entity.GroupMember{}).Where("belong = ? AND role = 0", note.GroupId).Pluck("user_id", &owners).Error
+	}
+	return owners, err
+}
+
 func NewNoteRepository() *NoteRepository {
 	return &NoteRepository{}
 }
@@ -70,6 +70,31 @@ func (r *UserGroupRepository) Role(userId int, groupId int) (int, error) {
 	return res.Role, nil
 }
 
+// AddMember 添加用户组成员，用户已在用户组内时不做处理
+// tx: 事务
+// role: 成员角色 0 - 用户组拥有者/管理者 1 - 普通用户 2 - 维护
+func (r *UserGroupRepository) AddMember(tx *gorm.DB, groupId int, userId int, role int) error {
+	var count int64
+	err := tx.Model(&entity.GroupMember{}).Where("user_id = ? AND belong = ?", userId, groupId).Count(&count).Error
+	if err != nil || count > 0 {
+		return err
+	}
+	return tx.Create(&entity.GroupMember{UserId: userId, Belong: groupId, Role: role}).Error
+}
+
+// RemoveMember 移除用户组成员
+// tx: 事务
+func (r *UserGroupRepository) RemoveMember(tx *gorm.DB, groupId int, userId int) error {
+	return tx.Where("user_id = ? AND belong = ?", userId, groupId).Delete(&entity.GroupMember{}).Error
+}
+
+// MemberIds 获取用户组成员的用户ID
+func (r *UserGroupRepository) MemberIds(groupId int) ([]int, error) {
+	var ids []int
+	err := DBDao.Model(&entity.GroupMember{}).Where("belong = ?", groupId).Pluck("user_id", &ids).Error
+	return ids, err
+}
+
 func NewUserGroupRepository() *UserGroupRepository {
 	return &UserGroupRepository{}
 }
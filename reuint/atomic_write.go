@@ -0,0 +1,32 @@
+package reuint
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic 原子写入文件
+// 先写入同目录下的临时文件并落盘，再重命名覆盖目标文件，防止写入中断导致文件内容被截断。
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpName, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpName, filename)
+	}
+	if err != nil {
+		_ = os.Remove(tmpName)
+	}
+	return err
+}
@@ -0,0 +1,25 @@
+package reuint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	root := t.TempDir()
+	filename := filepath.Join(root, "note.md")
+	_ = os.WriteFile(filename, []byte("old content"), 0666)
+
+	if err := WriteFileAtomic(filename, []byte("new"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	actual, _ := os.ReadFile(filename)
+	if string(actual) != "new" {
+		t.Fatalf("content = %s, want new", actual)
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 1 {
+		t.Fatalf("temp file not removed, entries = %d", len(entries))
+	}
+}
@@ -0,0 +1,29 @@
+package reuint
+
+import (
+	"encoding/hex"
+	"github.com/emmansun/gmsm/sm3"
+	"io"
+	"os"
+)
+
+// SM3Sum 计算数据流的SM3摘要
+// return: 摘要Hex, 错误
+func SM3Sum(r io.Reader) (string, error) {
+	hash := sm3.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SM3File 计算文件的SM3摘要
+// return: 摘要Hex, 错误
+func SM3File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return SM3Sum(file)
+}
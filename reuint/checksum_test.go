@@ -0,0 +1,17 @@
+package reuint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM3Sum(t *testing.T) {
+	actual, err := SM3Sum(strings.NewReader("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	if actual != expect {
+		t.Fatalf("SM3Sum() = %s, want %s", actual, expect)
+	}
+}
@@ -0,0 +1,28 @@
+package reuint
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// DirSize 计算目录下所有文件的大小之和（单位：B）
+// 遍历过程中被删除的文件将被忽略。
+func DirSize(root string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,24 @@
+package reuint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirSize(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "sub"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(root, "a.txt"), make([]byte, 10), 0666)
+	_ = os.WriteFile(filepath.Join(root, "sub", "b.txt"), make([]byte, 20), 0666)
+
+	size, err := DirSize(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 30 {
+		t.Fatalf("DirSize() = %d, want 30", size)
+	}
+}
@@ -0,0 +1,8 @@
+package reuint
+
+// DiskUsage 磁盘使用情况（单位：B）
+type DiskUsage struct {
+	Total uint64 `json:"total"` // 总容量
+	Free  uint64 `json:"free"`  // 可用容量
+	Used  uint64 `json:"used"`  // 已用容量
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package reuint
+
+import "syscall"
+
+// GetDiskUsage 获取路径所在磁盘的使用情况
+func GetDiskUsage(path string) (*DiskUsage, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+	bsize := uint64(stat.Bsize)
+	res := &DiskUsage{
+		Total: uint64(stat.Blocks) * bsize,
+		Free:  uint64(stat.Bavail) * bsize,
+	}
+	res.Used = res.Total - uint64(stat.Bfree)*bsize
+	return res, nil
+}
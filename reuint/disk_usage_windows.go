@@ -0,0 +1,18 @@
+//go:build windows
+
+package reuint
+
+import "golang.org/x/sys/windows"
+
+// GetDiskUsage 获取路径所在磁盘的使用情况
+func GetDiskUsage(path string) (*DiskUsage, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var free, total, totalFree uint64
+	if err = windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return nil, err
+	}
+	return &DiskUsage{Total: total, Free: free, Used: total - totalFree}, nil
+}
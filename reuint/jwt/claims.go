@@ -6,4 +6,5 @@ type Claims struct {
 	Sub  int    `json:"sub"`  // 用户ID或管理员ID
 	Exp  int64  `json:"exp"`  // 过期时间，Unix 毫秒数
 	Role int    `json:"role"` // 角色
+	Iat  int64  `json:"iat"`  // 签发时间，Unix 毫秒数，用于吊销用户此前签发的token
 }
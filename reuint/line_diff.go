@@ -0,0 +1,36 @@
+package reuint
+
+import "strings"
+
+// LineDiff 统计两段文本间新增及删除的行数
+// 按行的出现次数比较，不考虑行的顺序，移动行不计入变更，修改一行计为新增、删除各一行。
+// return: 新增行数, 删除行数
+func LineDiff(old, new string) (added int, removed int) {
+	counts := map[string]int{}
+	for _, line := range splitLines(old) {
+		counts[line]++
+	}
+	for _, line := range splitLines(new) {
+		counts[line]--
+	}
+	for _, n := range counts {
+		if n > 0 {
+			removed += n
+		} else {
+			added -= n
+		}
+	}
+	return added, removed
+}
+
+// splitLines 按行分割文本，忽略行尾的回车符，空文本无任何行
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	return lines
+}
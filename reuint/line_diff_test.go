@@ -0,0 +1,25 @@
+package reuint
+
+import "testing"
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		old, new       string
+		added, removed int
+	}{
+		{"", "", 0, 0},
+		{"", "a\nb\n", 2, 0},
+		{"a\nb\n", "", 0, 2},
+		{"a\nb\nc", "a\nb\nc\n", 0, 0},
+		{"a\r\nb\r\n", "a\nb\n", 0, 0},
+		{"a\nb\nc", "c\na\nb", 0, 0},
+		{"a\nb\nc", "a\nB\nc\nd", 2, 1},
+		{"a\na\nb", "a\nb\nb", 1, 1},
+	}
+	for _, tt := range tests {
+		added, removed := LineDiff(tt.old, tt.new)
+		if added != tt.added || removed != tt.removed {
+			t.Errorf("LineDiff(%q, %q) = %d, %d, want %d, %d", tt.old, tt.new, added, removed, tt.added, tt.removed)
+		}
+	}
+}
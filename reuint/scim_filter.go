@@ -0,0 +1,92 @@
+package reuint
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ScimFilter SCIM过滤条件，如：userName eq "zhangsan"
+type ScimFilter struct {
+	Attr  string // 属性路径，如 userName、emails.value
+	Op    string // 比较运算符（小写）：eq、ne、co、sw、ew、pr
+	Value string // 比较值，运算符为 pr 时为空
+}
+
+// ParseScimFilter 解析SCIM过滤表达式（RFC 7644 3.4.2.2）
+// 仅支持以 and 连接的简单比较表达式，不支持 or、not、括号及复杂属性过滤，
+// 如：userName eq "zhangsan" and active eq true
+// return: 过滤条件，表达式为空时返回空
+func ParseScimFilter(s string) ([]ScimFilter, error) {
+	tokens, err := scimTokens(s)
+	if err != nil {
+		return nil, err
+	}
+	var res []ScimFilter
+	for i := 0; i < len(tokens); {
+		if len(res) > 0 {
+			if !strings.EqualFold(tokens[i], "and") {
+				return nil, fmt.Errorf("不支持的逻辑运算 %q，仅支持 and", tokens[i])
+			}
+			i++
+		}
+		if i+1 >= len(tokens) {
+			return nil, fmt.Errorf("过滤表达式不完整")
+		}
+		f := ScimFilter{Attr: tokens[i], Op: strings.ToLower(tokens[i+1])}
+		if strings.ContainsAny(f.Attr, "[]\"") {
+			return nil, fmt.Errorf("不支持的属性 %q", f.Attr)
+		}
+		i += 2
+		switch f.Op {
+		case "pr":
+		case "eq", "ne", "co", "sw", "ew":
+			if i >= len(tokens) {
+				return nil, fmt.Errorf("缺少比较值")
+			}
+			f.Value = tokens[i]
+			i++
+		default:
+			return nil, fmt.Errorf("不支持的运算符 %q", f.Op)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// scimTokens 拆分过滤表达式，字符串按JSON格式解码，其余按空白分隔
+func scimTokens(s string) ([]string, error) {
+	var res []string
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')':
+			return nil, fmt.Errorf("不支持括号")
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("字符串未结束")
+			}
+			var str string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &str); err != nil {
+				return nil, fmt.Errorf("字符串格式错误 %s", s[i:j+1])
+			}
+			res = append(res, str)
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && s[j] != ' ' && s[j] != '\t' {
+				j++
+			}
+			res = append(res, s[i:j])
+			i = j
+		}
+	}
+	return res, nil
+}
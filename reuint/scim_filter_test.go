@@ -0,0 +1,41 @@
+package reuint
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScimFilter(t *testing.T) {
+	cases := map[string][]ScimFilter{
+		``:                         nil,
+		`userName eq "zhangsan"`:   {{Attr: "userName", Op: "eq", Value: "zhangsan"}},
+		`displayName Co "研发 \"一部"`: {{Attr: "displayName", Op: "co", Value: `研发 "一部`}},
+		`externalId sw "21" and active eq true`: {
+			{Attr: "externalId", Op: "sw", Value: "21"},
+			{Attr: "active", Op: "eq", Value: "true"},
+		},
+		`emails pr`: {{Attr: "emails", Op: "pr"}},
+	}
+	for in, want := range cases {
+		got, err := ParseScimFilter(in)
+		if err != nil {
+			t.Fatalf("ParseScimFilter(%q) error: %v", in, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("ParseScimFilter(%q) = %+v, want %+v", in, got, want)
+		}
+	}
+
+	for _, in := range []string{
+		`userName eq`,
+		`userName gt "a"`,
+		`userName eq "a" or userName eq "b"`,
+		`(userName eq "a")`,
+		`emails[type eq "work"].value eq "a"`,
+		`userName eq "a`,
+	} {
+		if _, err := ParseScimFilter(in); err == nil {
+			t.Fatalf("ParseScimFilter(%q) should fail", in)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package reuint
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"github.com/emmansun/gmsm/sm3"
+	"strings"
+)
+
+// SignRequest 计算请求的HMAC-SM3签名
+// 待签名字符串为：请求方法\n请求路径\n时间戳\n随机数\nHex(SM3(请求体))
+// return: 签名Hex
+func SignRequest(secret []byte, method, path, timestamp, nonce string, body []byte) string {
+	bodyHash := sm3.Sum(body)
+	payload := strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	mac := hmac.New(sm3.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequest 验证请求的HMAC-SM3签名，使用常量时间比较
+func VerifyRequest(secret []byte, method, path, timestamp, nonce string, body []byte, signature string) bool {
+	expect := SignRequest(secret, method, path, timestamp, nonce, body)
+	return hmac.Equal([]byte(expect), []byte(strings.ToLower(signature)))
+}
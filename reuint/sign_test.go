@@ -0,0 +1,26 @@
+package reuint
+
+import (
+	"testing"
+)
+
+func TestSignRequest(t *testing.T) {
+	secret := []byte("0123456789abcdef")
+	body := []byte(`{"jobNumber":21011,"state":1}`)
+	sign := SignRequest(secret, "post", "/api/user/aync", "1700000000", "n1", body)
+	if len(sign) != 64 {
+		t.Fatalf("signature length = %d, want 64", len(sign))
+	}
+	if !VerifyRequest(secret, "POST", "/api/user/aync", "1700000000", "n1", body, sign) {
+		t.Fatal("signature should be valid")
+	}
+	if VerifyRequest(secret, "POST", "/api/user/aync", "1700000000", "n1", []byte(`{"jobNumber":21012,"state":1}`), sign) {
+		t.Fatal("signature should be invalid when body changed")
+	}
+	if VerifyRequest(secret, "POST", "/api/user/aync", "1700000000", "n2", body, sign) {
+		t.Fatal("signature should be invalid when nonce changed")
+	}
+	if VerifyRequest([]byte("other"), "POST", "/api/user/aync", "1700000000", "n1", body, sign) {
+		t.Fatal("signature should be invalid with other secret")
+	}
+}
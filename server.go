@@ -1,12 +1,18 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/tjfoc/gmsm/gmtls"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
 	"note/appconf"
 	"note/controller"
+	"note/controller/middle"
+	"os"
 )
 
 func NewHttpServer(config *appconf.Application) *http.Server {
@@ -18,9 +24,70 @@ func NewHttpServer(config *appconf.Application) *http.Server {
 	}
 	// 注册路由
 	controller.RouteMapping(r, config)
-	zap.L().Info("系统启动", zap.Int("port", config.Port))
+	zap.L().Info("系统启动", zap.Int("port", config.Port), zap.Bool("https", config.TLS.Enabled()))
 	return &http.Server{
 		Addr:    fmt.Sprintf(":%d", config.Port),
 		Handler: r,
 	}
 }
+
+// newTLSConfig 创建HTTPS配置，未启用HTTPS时返回nil
+// clientAuth: 是否要求并验证客户端证书，仅在配置了客户端CA证书时生效
+func newTLSConfig(config *appconf.Application, clientAuth bool) (*tls.Config, error) {
+	if !config.TLS.Enabled() {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(config.TLS.CertFile, config.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("证书或私钥加载失败: %s", err.Error())
+	}
+	res := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if clientAuth && config.TLS.ClientCAFile != "" {
+		bin, err := os.ReadFile(config.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("客户端CA证书读取失败: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bin) {
+			return nil, fmt.Errorf("客户端CA证书解析失败: %s", config.TLS.ClientCAFile)
+		}
+		res.ClientCAs = pool
+		res.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return res, nil
+}
+
+// newTLCPServer 创建国密TLCP服务及其监听，未启用TLCP时返回nil
+// 握手使用SM2双证书：签名证书用于身份认证，加密证书用于密钥交换。
+// 注意TLCP连接的 Request.TLS 为空，需使用 middle.IsSecure 判断是否为加密连接，且仅支持HTTP/1.1。
+// TLCP协议由 github.com/tjfoc/gmsm 的 gmtls 实现：项目使用的 github.com/emmansun/gmsm 仅提供算法，
+// 基于其实现的 gotlcp 未发布至Go模块代理，无法获取，gotlcp 可获取后应替换，届时移除 tjfoc/gmsm 依赖。
+func newTLCPServer(config *appconf.Application, handler http.Handler) (*http.Server, net.Listener, error) {
+	tlcp := config.TLS.TLCP
+	if !tlcp.Enabled() {
+		return nil, nil, nil
+	}
+	sig, err := gmtls.LoadX509KeyPair(tlcp.SignCertFile, tlcp.SignKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("TLCP签名证书或私钥加载失败: %s", err.Error())
+	}
+	enc, err := gmtls.LoadX509KeyPair(tlcp.EncCertFile, tlcp.EncKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("TLCP加密证书或私钥加载失败: %s", err.Error())
+	}
+	addr := fmt.Sprintf(":%d", tlcp.Port)
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	zap.L().Info("系统启动", zap.Int("tlcpPort", tlcp.Port))
+	ln = gmtls.NewListener(ln, &gmtls.Config{
+		GMSupport:    gmtls.NewGMSupport(),
+		Certificates: []gmtls.Certificate{sig, enc},
+	})
+	// TLCP连接的 Request.TLS 为空，标记连接已加密，Cookie据此设置Secure标志
+	return &http.Server{Addr: addr, Handler: handler, ConnContext: middle.SecureConn}, ln, nil
+}
@@ -37,6 +37,11 @@ CREATE TABLE users
     sn          VARCHAR(512),                       -- 身份证
     note_tags   VARCHAR(1024),                      -- （已弃用）文件夹标签列表 "多个标签使用“,”分隔。例如： “运维,常见问题”"
     group_tags  VARCHAR(1024),                      -- （已弃用）用户组标签列表 "多个标签使用“,”分隔。例如： “运维,常见问题”"
+    department_code VARCHAR(128) DEFAULT '',        -- 所属部门编号
+    title       VARCHAR(256) DEFAULT '',            -- 职务
+    manager     VARCHAR(200) DEFAULT '',            -- 直属上级工号
+    state       TINYINT DEFAULT 0,                  -- 员工状态 0 - 未同步 1 - 正式 2 - 试用 3 - 实习 4 - 外聘 5 - 劳务派遣 6 - 离职 7 - 返聘
+    expired_at  DATETIME NULL,                      -- 账号过期时间，为空表示不过期
     is_delete   TINYINT								-- 是否删除 0 - 未删除（默认值） 1 - 删除
 );
 
@@ -67,7 +72,8 @@ CREATE TABLE notes
     id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
     created_at  DATETIME,                           -- 创建时间
     updated_at  DATETIME,                           -- 更新时间
-    user_id     INTEGER,                            -- 所属用户ID
+    user_id     INTEGER,                            -- 所属用户ID，团队空间的笔记为0
+    group_id    INTEGER DEFAULT 0,                  -- 团队空间所属用户组ID，0表示个人笔记
     title       VARCHAR(512) NOT NULL,              -- 文档名
     title_py    VARCHAR(255),                       -- 文档名拼音缩写
     priority    INTEGER,                            -- 优先级 默认为0，越大优先级越高，用于文档排序，非特殊情况保持0即可。
@@ -80,7 +86,8 @@ CREATE TABLE folders
 (
     id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
     created_at  DATETIME,                           -- 创建时间
-    user_id 	INTEGER,                            -- 所属用户ID
+    user_id 	INTEGER,                            -- 所属用户ID，团队空间的文件夹为0
+    group_id    INTEGER DEFAULT 0,                  -- 团队空间所属用户组ID，0表示个人文件夹
     name        VARCHAR(256),                       -- 文件夹名称
     parent_id   INTEGER                             -- 父文件夹ID，若为0，则为根文件夹。
 );
@@ -95,8 +102,10 @@ CREATE TABLE note_members
     role        TINYINT,                            -- 用户类型 枚举值：0 - 笔记拥有者/管理者 ， 1 - 可查看 ， 2 - 可编辑
     note_group  VARCHAR(1024),                      -- （已弃用）笔记分组列表 "多个标签使用“,”分隔。例如： “运维,常见问题”"
     remark 		VARCHAR(512),                       -- 备注
-    group_id    INTEGER,                            -- 用户组ID
-    folder_id   INTEGER                             -- 文件夹ID
+    group_id    INTEGER,                            -- （已弃用）用户组ID，用户组分享见 note_group_grants
+    folder_id   INTEGER,                            -- 文件夹ID
+    expires_at  DATETIME NULL,                      -- 分享到期时间，为空表示不过期
+    reminded    TINYINT DEFAULT 0                   -- 是否已发送到期提醒 0 - 未提醒 1 - 已提醒
 );
 
 
@@ -108,7 +117,204 @@ CREATE TABLE logs
     op_type    TINYINT,                            -- 操作者类型 类型如下包括：0 - 匿名，1 - 管理员，2 - 用户 若不知道用户或没有用户信息，则使用匿名。
     op_id      INTEGER,                            -- 操作者记录ID 0 表示匿名
     op_name    VARCHAR(512) NOT NULL,              -- 操作名称
-    op_param   TEXT NULL                           -- 操作的关键参数 可选参数，例如删除用户时，删除的用户ID，复杂参数请使用JSON对象字符串，如{id: 1}
+    op_param   TEXT NULL,                          -- 操作的关键参数 可选参数，例如删除用户时，删除的用户ID，复杂参数请使用JSON对象字符串，如{id: 1}
+    request_id VARCHAR(64) DEFAULT ''              -- 请求ID，用于关联系统日志
+);
+
+
+-- 创建审计告警表
+CREATE TABLE alerts
+(
+    id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at  DATETIME,                           -- 告警时间
+    rule_name   VARCHAR(256),                       -- 触发的规则名称
+    level       VARCHAR(32),                        -- 告警级别：info、warn、critical
+    op_type     TINYINT,                            -- 操作者类型 0 - 匿名，1 - 管理员，2 - 用户
+    op_id       INTEGER,                            -- 操作者记录ID
+    op_name     VARCHAR(512),                       -- 操作名称
+    content     VARCHAR(1024),                      -- 告警内容
+    is_read     TINYINT DEFAULT 0                   -- 是否已读 0 - 未读（默认值） 1 - 已读
+);
+
+
+-- 创建归档日志导入表
+CREATE TABLE archive_logs
+(
+    id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at  DATETIME,                           -- 原日志创建时间
+    log_id      INTEGER,                            -- 原日志ID
+    op_type     TINYINT,                            -- 操作者类型 0 - 匿名，1 - 管理员，2 - 用户
+    op_id       INTEGER,                            -- 操作者记录ID
+    op_name     VARCHAR(512),                       -- 操作名称
+    op_param    TEXT,                               -- 操作的关键参数
+    request_id  VARCHAR(64) DEFAULT '',             -- 请求ID
+    archive     VARCHAR(128),                       -- 归档文件名称
+    imported_at DATETIME                            -- 导入时间，超过24小时后自动清除
+);
+
+
+-- 创建部门表
+CREATE TABLE departments
+(
+    id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at  DATETIME,                           -- 创建时间
+    updated_at  DATETIME,                           -- 更新时间
+    code        VARCHAR(128) UNIQUE,                -- 部门编号，由员工管理系统同步
+    name        VARCHAR(256),                       -- 部门名称
+    parent_code VARCHAR(128) DEFAULT ''             -- 上级部门编号，为空表示顶级部门
+);
+
+
+-- 创建笔记用户组授权表
+CREATE TABLE note_group_grants
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    note_id    INTEGER,                            -- 笔记ID
+    group_id   INTEGER,                            -- 用户组ID
+    role       TINYINT,                            -- 用户组成员权限 枚举值：1 - 可查看 ， 2 - 可编辑
+    expires_at DATETIME NULL,                      -- 分享到期时间，为空表示不过期
+    reminded   TINYINT DEFAULT 0,                  -- 是否已发送到期提醒 0 - 未提醒 1 - 已提醒
+    UNIQUE (note_id, group_id)
+);
+
+
+-- 创建文件夹分享授权表
+CREATE TABLE folder_grants
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    folder_id  INTEGER,                            -- 文件夹ID
+    user_id    INTEGER DEFAULT 0,                  -- 被分享的用户ID，分享给用户组时为0
+    group_id   INTEGER DEFAULT 0,                  -- 被分享的用户组ID，分享给用户时为0
+    role       TINYINT,                            -- 权限 枚举值：1 - 可查看 ， 2 - 可编辑
+    UNIQUE (folder_id, user_id, group_id)
+);
+
+
+-- 创建笔记公开分享链接表
+CREATE TABLE share_links
+(
+    id           INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at   DATETIME,                           -- 创建时间
+    note_id      INTEGER,                            -- 笔记ID
+    user_id      INTEGER,                            -- 创建者ID
+    token        VARCHAR(64) UNIQUE,                 -- 链接令牌
+    expired_at   DATETIME NULL,                      -- 过期时间，为空表示不过期
+    password     VARCHAR(256) DEFAULT '',            -- 访问密码加盐摘要Hex，为空表示无需密码
+    salt         VARCHAR(256) DEFAULT '',            -- 盐值Hex
+    max_views    INTEGER DEFAULT 0,                  -- 最大访问次数，0表示不限制
+    views        INTEGER DEFAULT 0,                  -- 已访问次数
+    allow_assets TINYINT DEFAULT 0,                  -- 是否允许下载笔记资源 0 - 不允许 1 - 允许
+    revoked      TINYINT DEFAULT 0                   -- 是否已撤销 0 - 有效 1 - 已撤销
+);
+
+
+-- 创建站内通知表
+CREATE TABLE notifications
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    user_id    INTEGER,                            -- 接收者ID
+    type       VARCHAR(64),                        -- 通知类型，如：share_expiring
+    title      VARCHAR(256),                       -- 标题
+    content    VARCHAR(1024),                      -- 内容
+    note_id    INTEGER DEFAULT 0,                  -- 关联笔记ID，0表示无
+    is_read    TINYINT DEFAULT 0                   -- 是否已读 0 - 未读 1 - 已读
+);
+
+
+-- 创建笔记权限申请表
+CREATE TABLE access_requests
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    note_id    INTEGER,                            -- 笔记ID
+    user_id    INTEGER,                            -- 申请者ID
+    role       TINYINT,                            -- 申请的权限 枚举值：1 - 可查看 ， 2 - 可编辑
+    message    VARCHAR(512) DEFAULT '',            -- 申请说明
+    status     TINYINT DEFAULT 0,                  -- 状态 枚举值：0 - 待处理 ， 1 - 已同意 ， 2 - 已拒绝
+    handler_id INTEGER DEFAULT 0,                  -- 处理者ID
+    handled_at DATETIME NULL,                      -- 处理时间
+    reply      VARCHAR(512) DEFAULT ''             -- 处理意见
+);
+
+
+-- 创建邮件通知偏好表
+CREATE TABLE mail_preferences
+(
+    id             INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    user_id        INTEGER UNIQUE,                     -- 用户ID
+    share_granted  TINYINT DEFAULT 1,                  -- 笔记分享给我时是否发送邮件 0 - 不发送 1 - 发送
+    access_request TINYINT DEFAULT 1,                  -- 收到笔记权限申请时是否发送邮件 0 - 不发送 1 - 发送
+    share_expiring TINYINT DEFAULT 1,                  -- 分享即将到期时是否发送邮件 0 - 不发送 1 - 发送
+    digest         TINYINT DEFAULT 1                   -- 是否发送关注笔记的变更摘要邮件 0 - 不发送 1 - 发送
+);
+
+
+-- 创建事件推送订阅表
+CREATE TABLE webhooks
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    updated_at DATETIME,                           -- 更新时间
+    user_id    INTEGER DEFAULT 0,                  -- 订阅者ID，0表示管理员订阅
+    name       VARCHAR(64),                        -- 名称
+    url        VARCHAR(1024),                      -- 推送地址
+    secret     VARCHAR(256),                       -- 签名密钥
+    events     VARCHAR(1024),                      -- 订阅的事件，多个以逗号分隔
+    enabled    TINYINT DEFAULT 1                   -- 是否启用 0 - 停用 1 - 启用
+);
+
+
+-- 创建事件推送记录表
+CREATE TABLE webhook_deliveries
+(
+    id           INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at   DATETIME,                           -- 创建时间
+    webhook_id   INTEGER,                            -- 订阅ID
+    event        VARCHAR(64),                        -- 事件名称
+    payload      MEDIUMTEXT,                         -- 推送的请求体
+    status       TINYINT DEFAULT 0,                  -- 状态 0 - 待推送 1 - 成功 2 - 失败
+    attempts     INTEGER DEFAULT 0,                  -- 已推送次数
+    status_code  INTEGER DEFAULT 0,                  -- 最后一次推送的响应状态码
+    response     VARCHAR(1024) DEFAULT '',           -- 最后一次推送的响应体（截断）
+    error        VARCHAR(512) DEFAULT '',            -- 最后一次推送的错误信息
+    next_at      DATETIME NULL,                      -- 下次推送时间
+    delivered_at DATETIME NULL,                      -- 最后一次推送时间
+    redelivery   INTEGER DEFAULT 0                   -- 重新推送的原推送记录ID
+);
+
+
+-- 创建关注表
+CREATE TABLE watches
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    user_id    INTEGER,                            -- 关注者ID
+    note_id    INTEGER DEFAULT 0,                  -- 关注的笔记ID，0表示关注文件夹
+    folder_id  INTEGER DEFAULT 0                   -- 关注的文件夹ID，0表示关注笔记
+);
+
+-- 创建变更摘要设置表
+CREATE TABLE watch_settings
+(
+    id             INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    user_id        INTEGER UNIQUE,                     -- 用户ID
+    frequency      VARCHAR(16) DEFAULT 'daily',        -- 摘要频率 hourly - 每小时 daily - 每天
+    in_app         TINYINT DEFAULT 1,                  -- 是否发送站内通知摘要 0 - 不发送 1 - 发送
+    last_digest_at DATETIME                            -- 上次生成摘要的时间
+);
+
+-- 创建笔记变更记录表
+CREATE TABLE note_changes
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 保存时间
+    note_id    INTEGER,                            -- 笔记ID
+    user_id    INTEGER,                            -- 保存者ID
+    added      INTEGER DEFAULT 0,                  -- 新增行数
+    removed    INTEGER DEFAULT 0                   -- 删除行数
 );
 
 
@@ -122,4 +328,4 @@ CREATE TABLE configs
 
 -- 创建版本号记录
 INSERT INTO configs(item_name, content)
-VALUES ("db_version", "2023031401");
\ No newline at end of file
+VALUES ("db_version", "2026101913");
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- 创建审计告警表
+CREATE TABLE alerts
+(
+    id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at  DATETIME,                           -- 告警时间
+    rule_name   VARCHAR(256),                       -- 触发的规则名称
+    level       VARCHAR(32),                        -- 告警级别：info、warn、critical
+    op_type     TINYINT,                            -- 操作者类型 0 - 匿名，1 - 管理员，2 - 用户
+    op_id       INTEGER,                            -- 操作者记录ID
+    op_name     VARCHAR(512),                       -- 操作名称
+    content     VARCHAR(1024),                      -- 告警内容
+    is_read     TINYINT DEFAULT 0                   -- 是否已读 0 - 未读（默认值） 1 - 已读
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101901 WHERE item_name = "db_version";
@@ -0,0 +1,16 @@
+-- 创建归档日志导入表
+CREATE TABLE archive_logs
+(
+    id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at  DATETIME,                           -- 原日志创建时间
+    log_id      INTEGER,                            -- 原日志ID
+    op_type     TINYINT,                            -- 操作者类型 0 - 匿名，1 - 管理员，2 - 用户
+    op_id       INTEGER,                            -- 操作者记录ID
+    op_name     VARCHAR(512),                       -- 操作名称
+    op_param    TEXT,                               -- 操作的关键参数
+    archive     VARCHAR(128),                       -- 归档文件名称
+    imported_at DATETIME                            -- 导入时间，超过24小时后自动清除
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101902 WHERE item_name = "db_version";
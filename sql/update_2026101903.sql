@@ -0,0 +1,10 @@
+-- 操作日志表增加请求ID字段，用于关联系统日志
+ALTER TABLE logs
+    ADD request_id VARCHAR(64) DEFAULT '';
+
+-- 归档日志导入表增加请求ID字段
+ALTER TABLE archive_logs
+    ADD request_id VARCHAR(64) DEFAULT '';
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101903 WHERE item_name = "db_version";
@@ -0,0 +1,25 @@
+-- 创建部门表
+CREATE TABLE departments
+(
+    id          INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at  DATETIME,                           -- 创建时间
+    updated_at  DATETIME,                           -- 更新时间
+    code        VARCHAR(128) UNIQUE,                -- 部门编号，由员工管理系统同步
+    name        VARCHAR(256),                       -- 部门名称
+    parent_code VARCHAR(128) DEFAULT ''             -- 上级部门编号，为空表示顶级部门
+);
+
+-- 用户表增加同步的部门、职务、直属上级、员工状态及账号过期时间字段
+ALTER TABLE users
+    ADD department_code VARCHAR(128) DEFAULT '';
+ALTER TABLE users
+    ADD title VARCHAR(256) DEFAULT '';
+ALTER TABLE users
+    ADD manager VARCHAR(200) DEFAULT '';
+ALTER TABLE users
+    ADD state TINYINT DEFAULT 0;
+ALTER TABLE users
+    ADD expired_at DATETIME NULL;
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101904 WHERE item_name = "db_version";
@@ -0,0 +1,37 @@
+-- 创建笔记用户组授权表
+CREATE TABLE note_group_grants
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    note_id    INTEGER,                            -- 笔记ID
+    group_id   INTEGER,                            -- 用户组ID
+    role       TINYINT,                            -- 用户组成员权限 枚举值：1 - 可查看 ， 2 - 可编辑
+    UNIQUE (note_id, group_id)
+);
+
+-- 由笔记成员表中用户组分享的记录生成用户组授权，取用户组成员的最低权限
+INSERT INTO note_group_grants (created_at, note_id, group_id, role)
+SELECT MIN(created_at), note_id, group_id, MIN(role)
+FROM note_members
+WHERE group_id <> 0
+  AND role <> 0
+GROUP BY note_id, group_id;
+
+-- 删除已由用户组授权覆盖的成员记录，权限高于授权或已设置文件夹、备注的记录保留为用户分享
+DELETE
+FROM note_members
+WHERE id IN (SELECT id
+             FROM (SELECT m.id
+                   FROM note_members m
+                            INNER JOIN note_group_grants g ON g.note_id = m.note_id AND g.group_id = m.group_id
+                            INNER JOIN group_members gm ON gm.belong = m.group_id AND gm.user_id = m.user_id
+                   WHERE m.role <> 0
+                     AND m.role <= g.role
+                     AND m.folder_id = 0
+                     AND (m.remark IS NULL OR m.remark = '')) t);
+
+-- 笔记成员表仅保存用户分享，清空用户组字段
+UPDATE note_members SET group_id = 0 WHERE group_id <> 0;
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101905 WHERE item_name = "db_version";
@@ -0,0 +1,14 @@
+-- 创建文件夹分享授权表
+CREATE TABLE folder_grants
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    folder_id  INTEGER,                            -- 文件夹ID
+    user_id    INTEGER DEFAULT 0,                  -- 被分享的用户ID，分享给用户组时为0
+    group_id   INTEGER DEFAULT 0,                  -- 被分享的用户组ID，分享给用户时为0
+    role       TINYINT,                            -- 权限 枚举值：1 - 可查看 ， 2 - 可编辑
+    UNIQUE (folder_id, user_id, group_id)
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101906 WHERE item_name = "db_version";
@@ -0,0 +1,12 @@
+-- 文件夹表增加团队空间所属用户组字段，团队空间的根文件夹即团队空间
+ALTER TABLE folders
+    ADD group_id INTEGER DEFAULT 0;
+UPDATE folders SET group_id = 0;
+
+-- 笔记表增加团队空间所属用户组字段
+ALTER TABLE notes
+    ADD group_id INTEGER DEFAULT 0;
+UPDATE notes SET group_id = 0;
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101907 WHERE item_name = "db_version";
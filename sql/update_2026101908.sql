@@ -0,0 +1,19 @@
+-- 创建笔记公开分享链接表
+CREATE TABLE share_links
+(
+    id           INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at   DATETIME,                           -- 创建时间
+    note_id      INTEGER,                            -- 笔记ID
+    user_id      INTEGER,                            -- 创建者ID
+    token        VARCHAR(64) UNIQUE,                 -- 链接令牌
+    expired_at   DATETIME NULL,                      -- 过期时间，为空表示不过期
+    password     VARCHAR(256) DEFAULT '',            -- 访问密码加盐摘要Hex，为空表示无需密码
+    salt         VARCHAR(256) DEFAULT '',            -- 盐值Hex
+    max_views    INTEGER DEFAULT 0,                  -- 最大访问次数，0表示不限制
+    views        INTEGER DEFAULT 0,                  -- 已访问次数
+    allow_assets TINYINT DEFAULT 0,                  -- 是否允许下载笔记资源 0 - 不允许 1 - 允许
+    revoked      TINYINT DEFAULT 0                   -- 是否已撤销 0 - 有效 1 - 已撤销
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101908 WHERE item_name = "db_version";
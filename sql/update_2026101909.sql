@@ -0,0 +1,27 @@
+-- 笔记成员表增加分享到期时间，到期后由定时任务取消分享
+ALTER TABLE note_members
+    ADD expires_at DATETIME NULL,
+    ADD reminded TINYINT DEFAULT 0;
+UPDATE note_members SET reminded = 0;
+
+-- 笔记用户组授权表增加分享到期时间
+ALTER TABLE note_group_grants
+    ADD expires_at DATETIME NULL,
+    ADD reminded TINYINT DEFAULT 0;
+UPDATE note_group_grants SET reminded = 0;
+
+-- 创建站内通知表
+CREATE TABLE notifications
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    user_id    INTEGER,                            -- 接收者ID
+    type       VARCHAR(64),                        -- 通知类型，如：share_expiring
+    title      VARCHAR(256),                       -- 标题
+    content    VARCHAR(1024),                      -- 内容
+    note_id    INTEGER DEFAULT 0,                  -- 关联笔记ID，0表示无
+    is_read    TINYINT DEFAULT 0                   -- 是否已读 0 - 未读 1 - 已读
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101909 WHERE item_name = "db_version";
@@ -0,0 +1,17 @@
+-- 创建笔记权限申请表
+CREATE TABLE access_requests
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    note_id    INTEGER,                            -- 笔记ID
+    user_id    INTEGER,                            -- 申请者ID
+    role       TINYINT,                            -- 申请的权限 枚举值：1 - 可查看 ， 2 - 可编辑
+    message    VARCHAR(512) DEFAULT '',            -- 申请说明
+    status     TINYINT DEFAULT 0,                  -- 状态 枚举值：0 - 待处理 ， 1 - 已同意 ， 2 - 已拒绝
+    handler_id INTEGER DEFAULT 0,                  -- 处理者ID
+    handled_at DATETIME NULL,                      -- 处理时间
+    reply      VARCHAR(512) DEFAULT ''             -- 处理意见
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101910 WHERE item_name = "db_version";
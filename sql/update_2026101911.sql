@@ -0,0 +1,13 @@
+-- 创建邮件通知偏好表
+CREATE TABLE mail_preferences
+(
+    id             INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    user_id        INTEGER UNIQUE,                     -- 用户ID
+    share_granted  TINYINT DEFAULT 1,                  -- 笔记分享给我时是否发送邮件 0 - 不发送 1 - 发送
+    access_request TINYINT DEFAULT 1,                  -- 收到笔记权限申请时是否发送邮件 0 - 不发送 1 - 发送
+    share_expiring TINYINT DEFAULT 1,                  -- 分享即将到期时是否发送邮件 0 - 不发送 1 - 发送
+    digest         TINYINT DEFAULT 1                   -- 是否发送关注笔记的变更摘要邮件 0 - 不发送 1 - 发送
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101911 WHERE item_name = "db_version";
@@ -0,0 +1,34 @@
+-- 创建事件推送订阅表
+CREATE TABLE webhooks
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    updated_at DATETIME,                           -- 更新时间
+    user_id    INTEGER DEFAULT 0,                  -- 订阅者ID，0表示管理员订阅
+    name       VARCHAR(64),                        -- 名称
+    url        VARCHAR(1024),                      -- 推送地址
+    secret     VARCHAR(256),                       -- 签名密钥
+    events     VARCHAR(1024),                      -- 订阅的事件，多个以逗号分隔
+    enabled    TINYINT DEFAULT 1                   -- 是否启用 0 - 停用 1 - 启用
+);
+
+-- 创建事件推送记录表
+CREATE TABLE webhook_deliveries
+(
+    id           INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at   DATETIME,                           -- 创建时间
+    webhook_id   INTEGER,                            -- 订阅ID
+    event        VARCHAR(64),                        -- 事件名称
+    payload      MEDIUMTEXT,                         -- 推送的请求体
+    status       TINYINT DEFAULT 0,                  -- 状态 0 - 待推送 1 - 成功 2 - 失败
+    attempts     INTEGER DEFAULT 0,                  -- 已推送次数
+    status_code  INTEGER DEFAULT 0,                  -- 最后一次推送的响应状态码
+    response     VARCHAR(1024) DEFAULT '',           -- 最后一次推送的响应体（截断）
+    error        VARCHAR(512) DEFAULT '',            -- 最后一次推送的错误信息
+    next_at      DATETIME NULL,                      -- 下次推送时间
+    delivered_at DATETIME NULL,                      -- 最后一次推送时间
+    redelivery   INTEGER DEFAULT 0                   -- 重新推送的原推送记录ID
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101912 WHERE item_name = "db_version";
@@ -0,0 +1,33 @@
+-- 创建关注表
+CREATE TABLE watches
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 创建时间
+    user_id    INTEGER,                            -- 关注者ID
+    note_id    INTEGER DEFAULT 0,                  -- 关注的笔记ID，0表示关注文件夹
+    folder_id  INTEGER DEFAULT 0                   -- 关注的文件夹ID，0表示关注笔记
+);
+
+-- 创建变更摘要设置表
+CREATE TABLE watch_settings
+(
+    id             INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    user_id        INTEGER UNIQUE,                     -- 用户ID
+    frequency      VARCHAR(16) DEFAULT 'daily',        -- 摘要频率 hourly - 每小时 daily - 每天
+    in_app         TINYINT DEFAULT 1,                  -- 是否发送站内通知摘要 0 - 不发送 1 - 发送
+    last_digest_at DATETIME                            -- 上次生成摘要的时间
+);
+
+-- 创建笔记变更记录表
+CREATE TABLE note_changes
+(
+    id         INTEGER PRIMARY KEY AUTO_INCREMENT, -- 自增主键
+    created_at DATETIME,                           -- 保存时间
+    note_id    INTEGER,                            -- 笔记ID
+    user_id    INTEGER,                            -- 保存者ID
+    added      INTEGER DEFAULT 0,                  -- 新增行数
+    removed    INTEGER DEFAULT 0                   -- 删除行数
+);
+
+-- 更新版本号记录
+UPDATE configs SET content = 2026101913 WHERE item_name = "db_version";
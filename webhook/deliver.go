@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"note/repo"
+	"note/repo/entity"
+	"note/reuint"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"     // 事件名称
+	HeaderDelivery  = "X-Webhook-Delivery"  // 推送记录ID，重新推送时不同
+	HeaderTimestamp = "X-Webhook-Timestamp" // 请求时间戳（Unix秒）
+	HeaderNonce     = "X-Webhook-Nonce"     // 请求随机数
+	HeaderSignature = "X-Webhook-Signature" // 请求签名Hex，算法见 reuint.SignRequest，请求路径为推送地址的路径
+
+	responseMaxLen = 1024 // 推送记录保存的响应体最大长度（字符）
+	errorMaxLen    = 512  // 推送记录保存的错误信息最大长度（字符）
+)
+
+var (
+	// client 管理员订阅使用的客户端，可推送至内网地址
+	client = &http.Client{Timeout: 10 * time.Second, CheckRedirect: noRedirect}
+	// userClient 用户订阅使用的客户端，连接时校验解析后的IP，仅允许推送至公网地址，防止借推送访问内网服务
+	userClient = &http.Client{
+		Timeout:       10 * time.Second,
+		CheckRedirect: noRedirect,
+		Transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: publicOnly}).DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+	// 不允许推送的地址段，回环、私有、链路本地等地址见 IsPublicIP
+	reservedNets = []*net.IPNet{
+		mustCIDR("0.0.0.0/8"),
+		mustCIDR("100.64.0.0/10"),
+		mustCIDR("192.0.0.0/24"),
+		mustCIDR("198.18.0.0/15"),
+		mustCIDR("64:ff9b::/96"),
+	}
+)
+
+// ErrPrivateAddress 用户订阅的推送地址为内网地址
+var ErrPrivateAddress = errors.New("推送地址不可为内网地址")
+
+// deliver 推送记录，更新推送结果
+// 注意该函数不应抛出任何错误，若有错误请打印。
+func deliver(id int) {
+	var delivery entity.WebhookDelivery
+	err := repo.DBDao.First(&delivery, "id = ? AND status = 0", id).Error
+	if err != nil {
+		zap.L().Warn("查询推送记录失败", zap.Int("id", id), zap.Error(err))
+		return
+	}
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts":     delivery.Attempts + 1,
+		"delivered_at": now,
+	}
+
+	var hook entity.Webhook
+	err = repo.DBDao.Where("id = ?", delivery.WebhookId).Limit(1).Find(&hook).Error
+	if err != nil {
+		zap.L().Warn("查询事件推送订阅失败", zap.Int("webhookId", delivery.WebhookId), zap.Error(err))
+		return
+	}
+	if hook.ID == 0 || hook.Enabled != 1 {
+		updates["status"] = 2
+		updates["error"] = "订阅已删除或停用"
+		updates["next_at"] = nil
+	} else {
+		code, resp, err := post(&hook, &delivery)
+		updates["status_code"] = code
+		// 用户订阅不保存响应体，防止借推送读取其他服务的响应
+		if hook.UserId == 0 {
+			updates["response"] = truncate(resp, responseMaxLen)
+		}
+		updates["error"] = ""
+		switch {
+		case err == nil:
+			updates["status"] = 1
+			updates["next_at"] = nil
+		case delivery.Attempts < len(retryDelays):
+			updates["error"] = truncate(err.Error(), errorMaxLen)
+			updates["next_at"] = now.Add(retryDelays[delivery.Attempts])
+		default:
+			updates["error"] = truncate(err.Error(), errorMaxLen)
+			updates["status"] = 2
+			updates["next_at"] = nil
+			zap.L().Warn("事件推送失败", zap.Int("id", id), zap.Int("webhookId", hook.ID),
+				zap.String("event", delivery.Event), zap.Error(err))
+		}
+	}
+	if err = repo.DBDao.Model(&entity.WebhookDelivery{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		zap.L().Warn("更新推送记录失败", zap.Int("id", id), zap.Error(err))
+	}
+}
+
+// post 推送请求，响应状态码非2xx时返回错误
+// return: 响应状态码（未收到响应时为0）、响应体
+func post(hook *entity.Webhook, delivery *entity.WebhookDelivery) (int, string, error) {
+	u, err := url.Parse(hook.Url)
+	if err != nil {
+		return 0, "", err
+	}
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, hook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	nonce := make([]byte, 16)
+	if _, err = rand.Read(nonce); err != nil {
+		return 0, "", err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderNonce, hex.EncodeToString(nonce))
+	req.Header.Set(HeaderSignature, reuint.SignRequest([]byte(hook.Secret), http.MethodPost, u.Path,
+		timestamp, req.Header.Get(HeaderNonce), body))
+
+	c := client
+	if hook.UserId != 0 {
+		c = userClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, responseMaxLen*4))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(data), fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+// noRedirect 不跟随重定向，重定向响应按非2xx响应处理
+func noRedirect(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// publicOnly 连接前校验目标IP，非公网地址时拒绝连接
+func publicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// IsPublicIP 是否为公网地址，回环、私有、链路本地、未指定、组播及保留地址均不是公网地址
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	for _, item := range reservedNets {
+		if item.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// mustCIDR 解析网段，非法时panic，仅用于常量
+func mustCIDR(s string) *net.IPNet {
+	_, res, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
+
+// truncate 按字符截断字符串，非法的UTF-8字符被替换
+func truncate(s string, n int) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"note/repo"
+	"strings"
+	"time"
+)
+
+// 事件名称
+const (
+	EventPing               = "ping"                 // 测试推送，仅推送至被测试的订阅
+	EventNoteCreated        = "note.created"         // 创建笔记
+	EventNoteUpdated        = "note.updated"         // 手动保存笔记内容
+	EventNoteDeleted        = "note.deleted"         // 删除笔记（移入回收站）
+	EventNoteRestored       = "note.restored"        // 恢复笔记
+	EventNoteShared         = "note.shared"          // 笔记分享给用户或用户组，含修改分享权限
+	EventFolderCreated      = "folder.created"       // 创建文件夹
+	EventFolderRenamed      = "folder.renamed"       // 文件夹重命名
+	EventFolderMoved        = "folder.moved"         // 文件夹移动
+	EventFolderDeleted      = "folder.deleted"       // 删除文件夹
+	EventFolderShared       = "folder.shared"        // 文件夹分享给用户或用户组，含修改分享权限
+	EventFolderUnshared     = "folder.unshared"      // 取消分享文件夹
+	EventGroupMemberAdded   = "group.member.added"   // 用户组添加成员
+	EventGroupMemberUpdated = "group.member.updated" // 修改用户组成员的角色
+	EventGroupMemberRemoved = "group.member.removed" // 移出用户组成员
+	EventUserSynced         = "user.synced"          // 用户同步（员工管理系统同步、SCIM），仅管理员可订阅
+)
+
+// Events 可订阅的事件
+var Events = []string{
+	EventNoteCreated, EventNoteUpdated, EventNoteDeleted, EventNoteRestored, EventNoteShared,
+	EventFolderCreated, EventFolderRenamed, EventFolderMoved, EventFolderDeleted, EventFolderShared, EventFolderUnshared,
+	EventGroupMemberAdded, EventGroupMemberUpdated, EventGroupMemberRemoved,
+	EventUserSynced,
+}
+
+// Operator 事件操作者
+type Operator struct {
+	Type string `json:"type"` // 操作者类型 user - 用户 admin - 管理员 sync - 用户同步
+	Id   int    `json:"id"`   // 操作者ID，用户同步时为0
+	Name string `json:"name"` // 操作者名称
+}
+
+// Event 事件
+// 管理员订阅接收全部事件，用户订阅仅接收有权限访问的笔记、文件夹、所在用户组的事件以及与用户直接相关的事件。
+type Event struct {
+	Name     string      // 事件名称
+	Operator Operator    // 操作者
+	Data     interface{} // 事件数据
+	NoteId   int         // 关联笔记ID，可访问该笔记的用户可接收
+	FolderId int         // 关联文件夹ID，可访问该文件夹的用户可接收
+	GroupId  int         // 关联用户组ID，用户组成员可接收
+	Users    []int       // 直接相关的用户，如被分享者、被移出用户组的用户
+
+	at time.Time // 事件发生时间
+}
+
+// payload 推送的请求体
+type payload struct {
+	Event     string      `json:"event"`     // 事件名称
+	CreatedAt string      `json:"createdAt"` // 事件发生时间
+	Operator  Operator    `json:"operator"`  // 操作者
+	Data      interface{} `json:"data"`      // 事件数据
+}
+
+// Match 判断事件是否匹配订阅的事件
+// pattern: 订阅的事件，支持以 .* 结尾的前缀通配，* 匹配全部事件
+func Match(pattern, event string) bool {
+	if pattern == "*" || pattern == event {
+		return true
+	}
+	if strings.HasSuffix(pattern, ".*") {
+		return strings.HasPrefix(event, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+// Valid 判断订阅的事件是否合法，即至少匹配一个可订阅的事件
+func Valid(pattern string) bool {
+	for _, event := range Events {
+		if Match(pattern, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// visible 用户订阅是否可接收该事件
+func (e *Event) visible(userId int) (bool, error) {
+	for _, id := range e.Users {
+		if id == userId {
+			return true, nil
+		}
+	}
+	if e.NoteId > 0 {
+		role, err := repo.NoteMemberRepo.Check(userId, e.NoteId)
+		if err != nil || role != -1 {
+			return role != -1, err
+		}
+	}
+	if e.FolderId > 0 {
+		role, err := repo.FolderRepo.Role(userId, e.FolderId)
+		if err != nil || role != -1 {
+			return role != -1, err
+		}
+	}
+	if e.GroupId > 0 {
+		return repo.UserGroupRepo.ExistUser(userId, e.GroupId)
+	}
+	return false, nil
+}
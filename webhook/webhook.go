@@ -0,0 +1,263 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"go.uber.org/zap"
+	"note/metrics"
+	"note/repo"
+	"note/repo/entity"
+	"sync"
+	"time"
+)
+
+var _globalD *Dispatcher
+
+const (
+	deliveryWorkers  = 4                // 并发推送数量
+	deliveryScan     = 30 * time.Second // 待推送记录的检查间隔
+	deliveryKeepDays = 30               // 推送记录保存天数
+)
+
+// 推送失败后的重试间隔，重试次数为间隔数量，全部重试失败后推送记录标记为失败
+var retryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 6 * time.Hour}
+
+// Dispatcher 事件推送模块
+// 事件写入队列后由分发精灵匹配订阅并生成推送记录，推送精灵按推送记录的下次推送时间推送，
+// 推送记录持久化于数据库，程序重启后继续推送未完成的记录。
+type Dispatcher struct {
+	events chan *Event   // 待分发的事件
+	wake   chan struct{} // 新增推送记录时唤醒推送精灵
+	jobs   chan int      // 待推送的记录ID
+
+	inflight sync.Map // 推送中的记录ID，防止重复推送
+
+	mu      sync.RWMutex   // 保护事件队列关闭，防止向已关闭的队列写入
+	closed  bool           // 事件队列是否已关闭
+	stop    chan struct{}  // 精灵停止信号
+	workers sync.WaitGroup // 分发及推送精灵
+}
+
+// Emit 发布事件
+// 模块未初始化、已关闭或队列已满时丢弃事件，不阻塞业务处理。
+func Emit(e *Event) {
+	d := _globalD
+	if d == nil || e == nil {
+		return
+	}
+	e.at = time.Now()
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		zap.L().Warn("事件推送模块已关闭，丢弃事件", zap.String("event", e.Name))
+		return
+	}
+	select {
+	case d.events <- e:
+	default:
+		zap.L().Warn("事件队列已满，丢弃事件", zap.String("event", e.Name))
+	}
+}
+
+// dispatch 分发精灵，为订阅了事件的订阅生成推送记录
+// 事件队列关闭后处理完剩余的事件即退出。
+func (d *Dispatcher) dispatch() {
+	defer d.workers.Done()
+	for e := range d.events {
+		var hooks []entity.Webhook
+		if err := repo.DBDao.Where("enabled = 1").Find(&hooks).Error; err != nil {
+			zap.L().Warn("查询事件推送订阅失败", zap.String("event", e.Name), zap.Error(err))
+			continue
+		}
+		body, err := json.Marshal(&payload{
+			Event:     e.Name,
+			CreatedAt: e.at.Format("2006-01-02 15:04:05"),
+			Operator:  e.Operator,
+			Data:      e.Data,
+		})
+		if err != nil {
+			zap.L().Warn("事件序列化失败", zap.String("event", e.Name), zap.Error(err))
+			continue
+		}
+		for i := range hooks {
+			if !subscribed(&hooks[i], e) {
+				continue
+			}
+			if _, err = enqueue(hooks[i].ID, e.Name, string(body), 0); err != nil {
+				zap.L().Warn("创建推送记录失败", zap.Int("webhookId", hooks[i].ID), zap.String("event", e.Name), zap.Error(err))
+			}
+		}
+	}
+}
+
+// subscribed 判断订阅是否接收该事件
+func subscribed(hook *entity.Webhook, e *Event) bool {
+	matched := false
+	for _, pattern := range hook.EventList() {
+		if Match(pattern, e.Name) {
+			matched = true
+			break
+		}
+	}
+	if !matched || hook.UserId == 0 {
+		return matched
+	}
+	ok, err := e.visible(hook.UserId)
+	if err != nil {
+		zap.L().Warn("查询订阅者权限失败", zap.Int("webhookId", hook.ID), zap.String("event", e.Name), zap.Error(err))
+	}
+	return ok
+}
+
+// schedule 推送调度精灵，定时检查到达推送时间的记录交由推送精灵推送，并清理过期的推送记录
+func (d *Dispatcher) schedule() {
+	defer d.workers.Done()
+	defer close(d.jobs)
+	ticker := time.NewTicker(deliveryScan)
+	defer ticker.Stop()
+	var cleaned time.Time
+	for {
+		var due []int
+		err := repo.DBDao.Model(&entity.WebhookDelivery{}).
+			Where("status = 0 AND next_at <= ?", time.Now()).
+			Order("next_at").Limit(100).Pluck("id", &due).Error
+		if err != nil {
+			zap.L().Warn("查询待推送记录失败", zap.Error(err))
+		}
+		for _, id := range due {
+			if _, loaded := d.inflight.LoadOrStore(id, true); loaded {
+				continue
+			}
+			select {
+			case d.jobs <- id:
+			case <-d.stop:
+				return
+			}
+		}
+		if time.Since(cleaned) > 24*time.Hour {
+			cleaned = time.Now()
+			deadline := cleaned.AddDate(0, 0, -deliveryKeepDays)
+			if err = repo.DBDao.Where("created_at < ? AND status != 0", deadline).Delete(&entity.WebhookDelivery{}).Error; err != nil {
+				zap.L().Warn("清理推送记录失败", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-d.stop:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// work 推送精灵
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+	for id := range d.jobs {
+		deliver(id)
+		d.inflight.Delete(id)
+	}
+}
+
+// notify 唤醒推送调度精灵
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue 创建推送记录并唤醒推送精灵
+// redelivery: 重新推送的原推送记录ID，0表示非重新推送
+func enqueue(webhookId int, event, body string, redelivery int) (*entity.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := &entity.WebhookDelivery{
+		WebhookId:  webhookId,
+		Event:      event,
+		Payload:    body,
+		NextAt:     &now,
+		Redelivery: redelivery,
+	}
+	if err := repo.DBDao.Create(delivery).Error; err != nil {
+		return nil, err
+	}
+	if _globalD != nil {
+		_globalD.notify()
+	}
+	return delivery, nil
+}
+
+// Ping 向订阅推送测试事件
+func Ping(hook *entity.Webhook) (*entity.WebhookDelivery, error) {
+	body, err := json.Marshal(&payload{
+		Event:     EventPing,
+		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Operator:  Operator{},
+		Data:      map[string]interface{}{"webhookId": hook.ID, "name": hook.Name, "events": hook.EventList()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enqueue(hook.ID, EventPing, string(body), 0)
+}
+
+// Redeliver 以相同的请求体重新推送，生成新的推送记录
+func Redeliver(origin *entity.WebhookDelivery) (*entity.WebhookDelivery, error) {
+	return enqueue(origin.WebhookId, origin.Event, origin.Payload, origin.ID)
+}
+
+// Init 初始化事件推送模块
+func Init() {
+	if _globalD != nil {
+		return
+	}
+	_globalD = &Dispatcher{
+		events: make(chan *Event, 256),
+		wake:   make(chan struct{}, 1),
+		jobs:   make(chan int),
+		stop:   make(chan struct{}),
+	}
+	metrics.GaugeFunc("webhook_event_queue_depth", "事件队列中待分发的事件数量", func() float64 {
+		return float64(len(_globalD.events))
+	})
+	_globalD.workers.Add(2 + deliveryWorkers)
+	go _globalD.dispatch()
+	go _globalD.schedule()
+	for i := 0; i < deliveryWorkers; i++ {
+		go _globalD.work()
+	}
+	zap.L().Info("事件推送精灵 [启动]")
+}
+
+// Shutdown 关闭事件推送模块
+// 停止接收新事件，等待队列中的事件分发完成及推送中的请求结束，未完成的推送在程序重启后继续，
+// 超时后返回上下文的错误。
+func Shutdown(ctx context.Context) error {
+	d := _globalD
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	close(d.events)
+	close(d.stop)
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		zap.L().Info("事件推送精灵 [停止]")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,132 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"note/repo/entity"
+	"note/reuint"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		event   string
+		want    bool
+	}{
+		{"*", EventNoteUpdated, true},
+		{"note.updated", EventNoteUpdated, true},
+		{"note.*", EventNoteUpdated, true},
+		{"note.*", EventFolderCreated, false},
+		{"group.*", EventGroupMemberAdded, true},
+		{"group.member.*", EventGroupMemberRemoved, true},
+		{"note", EventNoteUpdated, false},
+		{"notes.*", EventNoteUpdated, false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.event); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.event, got, tt.want)
+		}
+	}
+	if !Valid("folder.*") || !Valid(EventUserSynced) || Valid("folder.archived") || Valid("") {
+		t.Errorf("Valid() not match expect")
+	}
+}
+
+func TestPost(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef"
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get(HeaderEvent) != EventNoteUpdated || r.Header.Get(HeaderDelivery) != "7" {
+			t.Errorf("header not match expect: %v", r.Header)
+		}
+		if !reuint.VerifyRequest([]byte(secret), r.Method, r.URL.Path, r.Header.Get(HeaderTimestamp),
+			r.Header.Get(HeaderNonce), body, r.Header.Get(HeaderSignature)) {
+			t.Errorf("signature verify failed")
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("received"))
+	}))
+	defer server.Close()
+
+	hook := &entity.Webhook{ID: 1, Url: server.URL + "/hooks/note", Secret: secret, Enabled: 1}
+	delivery := &entity.WebhookDelivery{ID: 7, WebhookId: 1, Event: EventNoteUpdated, Payload: `{"event":"note.updated"}`}
+	code, resp, err := post(hook, delivery)
+	if err != nil || code != http.StatusOK || resp != "received" {
+		t.Fatalf("post() = %d, %q, %v", code, resp, err)
+	}
+
+	status = http.StatusBadGateway
+	code, _, err = post(hook, delivery)
+	if err == nil || code != http.StatusBadGateway {
+		t.Fatalf("post() = %d, %v, want error", code, err)
+	}
+}
+
+func TestPostRestricted(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/hooks/note", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("received"))
+	}))
+	defer server.Close()
+	delivery := &entity.WebhookDelivery{ID: 7, WebhookId: 1, Event: EventNoteUpdated, Payload: `{"event":"note.updated"}`}
+
+	// 不跟随重定向
+	hook := &entity.Webhook{ID: 1, Url: server.URL + "/redirect", Enabled: 1}
+	code, _, err := post(hook, delivery)
+	if err == nil || code != http.StatusFound || hits != 1 {
+		t.Fatalf("post() = %d, %v, hits %d, want redirect not followed", code, err, hits)
+	}
+
+	// 用户订阅不可推送至内网地址
+	hook = &entity.Webhook{ID: 2, UserId: 31, Url: server.URL + "/hooks/note", Enabled: 1}
+	code, _, err = post(hook, delivery)
+	if !errors.Is(err, ErrPrivateAddress) || code != 0 || hits != 1 {
+		t.Fatalf("post() = %d, %v, hits %d, want %v", code, err, hits, ErrPrivateAddress)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"2001:4860:4860::8888", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"100.64.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("IsPublicIP(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("事件推送记录", 4); got != "事件推送" {
+		t.Fatalf("truncate() = %q", got)
+	}
+	if got := truncate("abc\xff", 10); got != "abc�" {
+		t.Fatalf("truncate() = %q", got)
+	}
+}